@@ -28,6 +28,7 @@ type ItemAttributes struct {
 	IsBlocking     bool   `json:"is_blocking,omitempty"`      // Solids (Walls)
 	IsMissileBlock bool   `json:"is_missile_block,omitempty"` // Blocks Projectiles
 	IsPathBlock    bool   `json:"is_path_block,omitempty"`    // Unpassable (Magic Walls)
+	TopOrder       uint8  `json:"top_order,omitempty"`        // Stack order: 1 clip, 2 bottom, 3 top
 
 	IsContainer  bool `json:"is_container,omitempty"`
 	IsStackable  bool `json:"is_stackable,omitempty"`
@@ -35,6 +36,7 @@ type ItemAttributes struct {
 	IsMultiUse   bool `json:"is_multi_use,omitempty"` // Runes, fluids
 	IsPickupable bool `json:"is_pickupable,omitempty"`
 	IsRotatable  bool `json:"is_rotatable,omitempty"`
+	IsCorpse     bool `json:"is_corpse,omitempty"` // Lying corpse, looted when it is a container
 
 	// --- Visuals (Useful for bot context) ---
 	LightLevel   uint16 `json:"light_level,omitempty"`
@@ -122,11 +124,11 @@ func parseItem(r *bytes.Reader, id uint16) ItemAttributes {
 			item.IsGround = true
 			item.Speed = readUint16(r)
 		case 0x01: // Clip
-			// Rendering Flag
+			item.TopOrder = 1 // Ground borders
 		case 0x02: // Bottom
-			// Rendering Flag
+			item.TopOrder = 2 // Walls, below creatures
 		case 0x03: // Top
-			// Decorative
+			item.TopOrder = 3 // Always on top
 		case 0x04: // Container
 			item.IsContainer = true
 		case 0x05: // Stackable
@@ -177,7 +179,7 @@ func parseItem(r *bytes.Reader, id uint16) ItemAttributes {
 		case 0x19: // Elevation
 			item.Elevation = readUint16(r)
 		case 0x1A: // Lying Corpse
-			// No data
+			item.IsCorpse = true
 		case 0x1B: // Animate Always
 			// No data
 		case 0x1C: // Minimap Color
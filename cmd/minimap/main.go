@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"z07/internal/minimap"
+)
+
+// Draws every floor of an explored map as a PNG, one pixel per tile, to browse the map offline.
+func main() {
+	mapPath := flag.String("map", "", "Map file written by the proxy, e.g. maps/world.fibula.app_7172.z07map")
+	outDir := flag.String("out", "minimap", "Directory for the floor images")
+	flag.Parse()
+
+	if *mapPath == "" {
+		flag.Usage()
+		log.Fatal("-map is required")
+	}
+
+	m, err := minimap.ReadFile(*mapPath)
+	if err != nil {
+		log.Fatalf("Error reading map: %v", err)
+	}
+	if m.Len() == 0 {
+		log.Fatalf("%s holds no explored tiles", *mapPath)
+	}
+
+	floors, err := m.ExportPNG(*outDir)
+	if err != nil {
+		log.Fatalf("Error writing images: %v", err)
+	}
+
+	fmt.Printf("%d tiles on %d floors\n", m.Len(), len(floors))
+	for _, f := range floors {
+		size := f.Image.Bounds().Size()
+		fmt.Printf("  %s  %dx%d, top left tile at %d,%d\n",
+			filepath.Join(*outDir, minimap.FloorFile(f.Z)), size.X, size.Y, f.Origin.X, f.Origin.Y)
+	}
+}
@@ -1,22 +1,23 @@
 package main
 
 import (
-	"sync"
+	"context"
+	"log"
 	"z07/internal/bot"
+	"z07/internal/dashboard"
 	"z07/internal/game/domain"
 	"z07/internal/game/state"
 )
 
 func main() {
-	var wg sync.WaitGroup
-	wg.Add(1)
-
 	gs := state.New()
 	gs.SetPlayerName("JohnDoe")
 	gs.SetPlayerPos(domain.Position{X: 5, Y: 6, Z: 7})
 
-	b := bot.NewBot(gs, nil, nil)
-	b.StartUIOnly()
+	// Only the dashboard runs, no module touches the (missing) connections.
+	registry := dashboard.NewRegistry()
+	registry.Register(dashboard.Session{ID: "debug", Character: "JohnDoe", Bot: bot.NewBot(gs, nil, nil)})
 
-	wg.Wait()
+	srv := &dashboard.Server{Addr: ":8080", Registry: registry}
+	log.Fatal(srv.Run(context.Background()))
 }
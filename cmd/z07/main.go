@@ -20,47 +20,119 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"os"
 	"sync"
+	"time"
 	"z07/internal/assets"
+	"z07/internal/capture"
+	"z07/internal/config"
+	"z07/internal/dashboard"
 	"z07/internal/game"
 	"z07/internal/login"
+	"z07/internal/minimap"
+	"z07/internal/protocol"
+	"z07/internal/protocol/crypto"
 	"z07/internal/proxy"
+	"z07/internal/rules"
 )
 
 func main() {
-	if err := assets.LoadItemsJson("data/772/items.json"); err != nil {
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	// Validate already built both of these once, they can not fail here.
+	crypto.RSA, _ = cfg.RSA.Keys()
+	gameworldIP, _ := protocol.StringToIP(cfg.Advertise.IP)
+
+	if err := assets.LoadItemsJson(cfg.Items); err != nil {
 		log.Fatalf("Critical Error: %v", err)
 	}
+	if cfg.ItemNames.XML != "" {
+		if err := assets.LoadItemsXML(cfg.ItemNames.XML, cfg.ItemNames.OTB); err != nil {
+			log.Fatalf("Critical Error: %v", err)
+		}
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
+	// Sessions can still be switched on and off from their dashboard.
+	recorder := capture.NewRecorder(cfg.Capture.Dir, cfg.Capture.MaxFileSize)
+	recorder.SetEnabled(cfg.Capture.Enabled)
 
-	loginHandler := &login.LoginHandler{
-		TargetAddr: "world.fibula.app:7171",
-		ProxyMOTD:  "Welcome to z07 Proxy!",
+	// Filled from the character lists, tells the game proxy where each character really plays.
+	routes := proxy.NewWorldRoutes()
+
+	// Every logged in character shares the one dashboard.
+	registry := dashboard.NewRegistry()
+
+	// One rule set for every session. The rules file is watched, the dashboard can replace the set as well.
+	packetRules := rules.NewEngine()
+	if cfg.Rules.File != "" {
+		go rules.Watch(context.Background(), packetRules, cfg.Rules.File, cfg.Rules.List, 2*time.Second)
+	} else {
+		_ = packetRules.Set(cfg.Rules.List) // Validate already checked the list
 	}
 
-	gameHandler := game.NewGameHandler("world.fibula.app:7172")
+	botSettings := cfg.BotSettings()
+	gameHandler := game.NewGameHandler(cfg.Game.Backend)
+	gameHandler.Routes = routes
+	gameHandler.Registry = registry
+	gameHandler.Recorder = recorder
+	gameHandler.BotSettings = &botSettings
+	gameHandler.Outbox = cfg.OutboxOptions()
+	gameHandler.PacketRules = packetRules
+	if cfg.Minimap.Dir != "" {
+		gameHandler.Minimap = minimap.NewStore(cfg.Minimap.Dir)
+	}
+
+	logins := cfg.AllLogins()
+
+	var wg sync.WaitGroup
+	wg.Add(len(logins) + 2)
+
+	for _, lc := range logins {
+		loginHandler := &login.LoginHandler{
+			TargetAddr:    lc.Backend,
+			ProxyMOTD:     lc.MOTD,
+			GameworldIP:   gameworldIP,
+			GameworldPort: cfg.Advertise.Port,
+			WorldName:     cfg.Advertise.WorldName,
+			Routes:        routes,
+			Recorder:      recorder,
+		}
+
+		go func() {
+			defer wg.Done()
+			srv := proxy.NewServer(
+				"Login",
+				lc.Listen,
+				loginHandler,
+			)
+			log.Fatal(srv.Start())
+		}()
+	}
 
 	go func() {
 		defer wg.Done()
 		srv := proxy.NewServer(
-			"Login",
-			":7171",
-			loginHandler,
+			"Game",
+			cfg.Game.Listen,
+			gameHandler,
 		)
 		log.Fatal(srv.Start())
 	}()
 
 	go func() {
 		defer wg.Done()
-		srv := proxy.NewServer(
-			"Game",
-			":7172",
-			gameHandler,
-		)
-		log.Fatal(srv.Start())
+		srv := &dashboard.Server{Addr: cfg.UI.Addr, Registry: registry}
+		log.Fatal(srv.Run(context.Background()))
 	}()
 
 	wg.Wait()
@@ -0,0 +1,135 @@
+package assets
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/**
+An OpenTibia items.xml describes items by their server ID:
+
+	<item id="2148" article="a" name="gold coin" plural="gold coins">
+		<attribute key="weight" value="10"/>
+	</item>
+
+A range of items can share one entry with fromid and toid instead of id.
+The server IDs only match the client IDs of the dat when an items.otb maps them.
+*/
+
+type xmlItems struct {
+	Items []xmlItem `xml:"item"`
+}
+
+type xmlItem struct {
+	ID         uint16         `xml:"id,attr"`
+	FromID     uint16         `xml:"fromid,attr"`
+	ToID       uint16         `xml:"toid,attr"`
+	Name       string         `xml:"name,attr"`
+	Attributes []xmlAttribute `xml:"attribute"`
+}
+
+type xmlAttribute struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// LoadItemsXML merges names and attributes of an items.xml into the loaded items.
+// otbPath may be empty when the server IDs of the file are the client IDs.
+func LoadItemsXML(xmlPath, otbPath string) error {
+	var clientIds map[uint16]uint16
+	if otbPath != "" {
+		f, err := os.Open(otbPath)
+		if err != nil {
+			return fmt.Errorf("failed to open items otb: %w", err)
+		}
+		clientIds, err = ReadOTB(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse items otb: %w", err)
+		}
+	}
+
+	f, err := os.Open(xmlPath)
+	if err != nil {
+		return fmt.Errorf("failed to open items xml: %w", err)
+	}
+	defer f.Close()
+
+	count, err := ImportItemsXML(f, clientIds)
+	if err != nil {
+		return fmt.Errorf("failed to parse items xml: %w", err)
+	}
+	fmt.Printf("[Data] Merged %d item names from %s\n", count, xmlPath)
+	return nil
+}
+
+// ImportItemsXML merges an items.xml into the registry and returns how many items it named.
+// clientIds maps server IDs to client IDs, nil takes the IDs of the file as they are.
+// Only items the registry already knows are changed, the rest exist on the server alone.
+func ImportItemsXML(r io.Reader, clientIds map[uint16]uint16) (int, error) {
+	var doc xmlItems
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, entry := range doc.Items {
+		from, to := entry.ID, entry.ID
+		if entry.FromID != 0 {
+			from, to = entry.FromID, max(entry.ToID, entry.FromID)
+		}
+		for serverId := int(from); serverId <= int(to); serverId++ {
+			id := uint16(serverId)
+			if clientIds != nil {
+				var ok bool
+				if id, ok = clientIds[id]; !ok {
+					continue
+				}
+			}
+			if int(id) >= len(things) || id == 0 {
+				continue
+			}
+			item := things[id]
+			if err := entry.apply(&item); err != nil {
+				return count, fmt.Errorf("item %d: %w", serverId, err)
+			}
+			Register(item)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (e xmlItem) apply(item *ItemType) error {
+	if e.Name != "" {
+		item.Name = e.Name
+	}
+	for _, attr := range e.Attributes {
+		var err error
+		switch strings.ToLower(attr.Key) {
+		case "weight":
+			var v uint64
+			v, err = strconv.ParseUint(attr.Value, 10, 32)
+			item.Weight = uint32(v)
+		case "slottype":
+			item.SlotType = strings.ToLower(attr.Value)
+		case "charges":
+			item.Charges, err = parseUint16(attr.Value)
+		case "food", "nutrition":
+			item.FoodValue, err = parseUint16(attr.Value)
+		}
+		if err != nil {
+			return fmt.Errorf("attribute %s: %w", attr.Key, err)
+		}
+	}
+	return nil
+}
+
+func parseUint16(s string) (uint16, error) {
+	v, err := strconv.ParseUint(s, 10, 16)
+	return uint16(v), err
+}
@@ -0,0 +1,121 @@
+package assets_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"z07/internal/assets"
+
+	"github.com/stretchr/testify/require"
+)
+
+const itemsXML = `<?xml version="1.0"?>
+<items>
+	<item id="65001" article="a" name="gold coin" plural="gold coins">
+		<attribute key="weight" value="10"/>
+	</item>
+	<item id="65002" name="Plate Armor">
+		<attribute key="weight" value="12000"/>
+		<attribute key="slotType" value="body"/>
+	</item>
+	<item id="65003" name="sudden death rune">
+		<attribute key="charges" value="3"/>
+	</item>
+	<item id="65004" name="ham">
+		<attribute key="nutrition" value="30"/>
+	</item>
+	<item fromid="65010" toid="65012" name="stone wall"/>
+	<item id="65500" name="server only"/>
+</items>`
+
+func register(ids ...uint16) {
+	for _, id := range ids {
+		assets.Register(assets.ItemType{ID: id, IsPickupable: true})
+	}
+}
+
+func TestImportItemsXML(t *testing.T) {
+	register(65001, 65002, 65003, 65004, 65010, 65011, 65012)
+
+	count, err := assets.ImportItemsXML(strings.NewReader(itemsXML), nil)
+	require.NoError(t, err)
+	require.Equal(t, 7, count, "Items the client does not know are skipped")
+
+	require.Equal(t, assets.ItemType{ID: 65001, Name: "gold coin", Weight: 10, IsPickupable: true}, assets.Get(65001), "Flags from the dat are kept")
+	require.Equal(t, "body", assets.Get(65002).SlotType)
+	require.Equal(t, uint16(3), assets.Get(65003).Charges)
+	require.Equal(t, uint16(30), assets.Get(65004).FoodValue)
+	require.Equal(t, "stone wall", assets.Get(65011).Name)
+	require.Empty(t, assets.Get(65500).Name)
+
+	t.Run("FindByName", func(t *testing.T) {
+		item, ok := assets.FindByName("plate armor")
+		require.True(t, ok, "Names match in any case")
+		require.Equal(t, uint16(65002), item.ID)
+
+		item, ok = assets.FindByName("Stone Wall")
+		require.True(t, ok)
+		require.Equal(t, uint16(65010), item.ID, "The lowest ID wins")
+
+		_, ok = assets.FindByName("server only")
+		require.False(t, ok)
+
+		// A later registration under another name drops the old one.
+		assets.Register(assets.ItemType{ID: 65004, Name: "meat"})
+		_, ok = assets.FindByName("ham")
+		require.False(t, ok)
+		_, ok = assets.FindByName("meat")
+		require.True(t, ok)
+	})
+
+	t.Run("Broken attribute", func(t *testing.T) {
+		_, err := assets.ImportItemsXML(strings.NewReader(`<items><item id="65001"><attribute key="weight" value="heavy"/></item></items>`), nil)
+		require.ErrorContains(t, err, "item 65001")
+	})
+}
+
+// otbItem encodes an item node with its flags and ID attributes, escaping the markers.
+func otbItem(serverId, clientId uint16) []byte {
+	var data []byte
+	data = append(data, 0, 0, 0, 0) // Flags
+	data = append(data, 0x10, 2, 0)
+	data = binary.LittleEndian.AppendUint16(data, serverId)
+	data = append(data, 0x11, 2, 0)
+	data = binary.LittleEndian.AppendUint16(data, clientId)
+
+	node := []byte{0xFE, 0x01}
+	for _, b := range data {
+		if b >= 0xFD {
+			node = append(node, 0xFD)
+		}
+		node = append(node, b)
+	}
+	return append(node, 0xFF)
+}
+
+func TestReadOTB(t *testing.T) {
+	var otb bytes.Buffer
+	otb.Write([]byte{0, 0, 0, 0})          // Identifier
+	otb.Write([]byte{0xFE, 0, 0, 0, 0, 0}) // Root node with its flags
+	otb.Write(otbItem(2148, 65020))
+	otb.Write(otbItem(2463, 0xFDFE)) // Both bytes need escaping
+	otb.Write([]byte{0xFF})
+
+	ids, err := assets.ReadOTB(bytes.NewReader(otb.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, map[uint16]uint16{2148: 65020, 2463: 0xFDFE}, ids)
+
+	t.Run("Server IDs are mapped", func(t *testing.T) {
+		register(65020)
+		count, err := assets.ImportItemsXML(strings.NewReader(`<items><item id="2148" name="gold coin"/><item id="2149" name="unmapped"/></items>`), ids)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+		require.Equal(t, "gold coin", assets.Get(65020).Name)
+	})
+
+	t.Run("Unclosed node", func(t *testing.T) {
+		_, err := assets.ReadOTB(bytes.NewReader(otb.Bytes()[:otb.Len()-1]))
+		require.Error(t, err)
+	})
+}
@@ -10,16 +10,25 @@ type ItemType struct {
 	IsBlocking     bool   `json:"is_blocking,omitempty"`      // Solids
 	IsMissileBlock bool   `json:"is_missile_block,omitempty"` // Walls projectiles
 	IsPathBlock    bool   `json:"is_path_block,omitempty"`    // Unpassable
+	TopOrder       uint8  `json:"top_order,omitempty"`        // 1 ground border, 2 bottom (walls), 3 top, 0 for all other items
 
 	IsContainer  bool `json:"is_container,omitempty"`
 	IsStackable  bool `json:"is_stackable,omitempty"`
 	IsFluid      bool `json:"is_fluid,omitempty"`
 	IsMultiUse   bool `json:"is_multi_use,omitempty"`
 	IsPickupable bool `json:"is_pickupable,omitempty"`
+	IsCorpse     bool `json:"is_corpse,omitempty"` // Lying corpse, the dead body of a creature
 
 	// Visuals
 	IsTranslucent bool   `json:"is_translucent,omitempty"`
 	LightLevel    uint8  `json:"light_level,omitempty"`
 	LightColor    uint8  `json:"light_color,omitempty"`
 	Elevation     uint16 `json:"elevation,omitempty"`
+	MinimapColor  uint16 `json:"minimap_color,omitempty"` // Palette index, 0 leaves the tile to the items below
+
+	// Server side attributes, merged from an OpenTibia items.xml
+	Weight    uint32 `json:"weight,omitempty"`    // Hundredths of an oz
+	SlotType  string `json:"slot_type,omitempty"` // Equipment slot, e.g. "head" or "two-handed"
+	Charges   uint16 `json:"charges,omitempty"`   // Charges of a fresh rune
+	FoodValue uint16 `json:"food_value,omitempty"`
 }
@@ -29,6 +29,7 @@ func LoadItemsJson(path string) error {
 	count := 0
 	for _, item := range loadedItems {
 		things[item.ID] = item
+		indexName(item)
 		count++
 	}
 
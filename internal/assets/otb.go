@@ -0,0 +1,124 @@
+package assets
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+/**
+items.otb is a tree of nodes behind a 4 byte identifier. A node starts with 0xFE and its type,
+followed by its data and child nodes, and ends with 0xFF. Data bytes equal to one of the markers
+are escaped with 0xFD. Every child of the root is an item: 4 bytes of flags, then attributes made
+of a type byte, a uint16 length and the value.
+*/
+
+const (
+	otbNodeStart = 0xFE
+	otbNodeEnd   = 0xFF
+	otbEscape    = 0xFD
+
+	otbAttrServerId = 0x10
+	otbAttrClientId = 0x11
+)
+
+type otbNode struct {
+	data     []byte
+	children []otbNode
+}
+
+// ReadOTB reads the server to client ID mapping of an items.otb.
+func ReadOTB(r io.Reader) (map[uint16]uint16, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 5 {
+		return nil, errors.New("file too short")
+	}
+
+	p := otbParser{raw: raw[4:]}
+	root, err := p.node()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[uint16]uint16, len(root.children))
+	for i, item := range root.children {
+		serverId, clientId, err := otbItemIds(item.data)
+		if err != nil {
+			return nil, fmt.Errorf("item node %d: %w", i, err)
+		}
+		if serverId != 0 && clientId != 0 {
+			ids[serverId] = clientId
+		}
+	}
+	return ids, nil
+}
+
+func otbItemIds(data []byte) (serverId, clientId uint16, err error) {
+	if len(data) < 4 {
+		return 0, 0, errors.New("missing flags")
+	}
+	data = data[4:]
+
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return 0, 0, errors.New("truncated attribute")
+		}
+		attr, size := data[0], int(binary.LittleEndian.Uint16(data[1:3]))
+		data = data[3:]
+		if len(data) < size {
+			return 0, 0, fmt.Errorf("attribute 0x%02X is longer than the node", attr)
+		}
+		value := data[:size]
+		data = data[size:]
+
+		switch {
+		case attr == otbAttrServerId && size == 2:
+			serverId = binary.LittleEndian.Uint16(value)
+		case attr == otbAttrClientId && size == 2:
+			clientId = binary.LittleEndian.Uint16(value)
+		}
+	}
+	return serverId, clientId, nil
+}
+
+type otbParser struct {
+	raw []byte
+	pos int
+}
+
+func (p *otbParser) node() (otbNode, error) {
+	if p.pos+1 >= len(p.raw) || p.raw[p.pos] != otbNodeStart {
+		return otbNode{}, fmt.Errorf("no node at offset %d", p.pos)
+	}
+	p.pos += 2 // Start marker and node type
+
+	var n otbNode
+	for p.pos < len(p.raw) {
+		b := p.raw[p.pos]
+		switch b {
+		case otbNodeStart:
+			child, err := p.node()
+			if err != nil {
+				return otbNode{}, err
+			}
+			n.children = append(n.children, child)
+		case otbNodeEnd:
+			p.pos++
+			return n, nil
+		case otbEscape:
+			if p.pos+1 >= len(p.raw) {
+				return otbNode{}, errors.New("escape at the end of the file")
+			}
+			n.data = append(n.data, p.raw[p.pos+1])
+			p.pos += 2
+		default:
+			n.data = append(n.data, b)
+			p.pos++
+		}
+	}
+	return otbNode{}, errors.New("node is not closed")
+}
@@ -1,10 +1,16 @@
 package assets
 
+import "strings"
+
 // Global Registry
 var things []ItemType
 
+// byName maps lower case names to the lowest item ID carrying them.
+var byName = make(map[string]uint16)
+
 func initialize(size int) {
 	things = make([]ItemType, size)
+	clear(byName)
 }
 
 func Get(id uint16) ItemType {
@@ -13,3 +19,38 @@ func Get(id uint16) ItemType {
 	}
 	return things[id]
 }
+
+// Register adds or replaces item types, growing the registry when needed.
+func Register(items ...ItemType) {
+	for _, item := range items {
+		if int(item.ID) >= len(things) {
+			grown := make([]ItemType, int(item.ID)+1)
+			copy(grown, things)
+			things = grown
+		}
+		things[item.ID] = item
+		indexName(item)
+	}
+}
+
+// FindByName looks an item up by its name, ignoring case.
+// Many items share a name (walls, water), the one with the lowest ID is returned.
+func FindByName(name string) (ItemType, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	id, ok := byName[key]
+	// The item may have been registered again under another name since.
+	if !ok || strings.ToLower(things[id].Name) != key {
+		return ItemType{}, false
+	}
+	return things[id], true
+}
+
+func indexName(item ItemType) {
+	if item.Name == "" {
+		return
+	}
+	key := strings.ToLower(item.Name)
+	if id, ok := byName[key]; !ok || item.ID < id || strings.ToLower(things[id].Name) != key {
+		byName[key] = item.ID
+	}
+}
@@ -1,20 +1,111 @@
 package bot
 
 import (
+	"fmt"
+	"z07/internal/assets"
 	"z07/internal/game/domain"
 	"z07/internal/game/packets"
 	"z07/internal/game/state"
+	"z07/internal/protocol"
 )
 
-func (b *Bot) UseItemFromInventoryOnTile(item state.ItemInInventory, to domain.Tile) {
+// itemId finds an item by name once the item names are loaded, the ID of the 7.72 client is the fallback.
+func itemId(name string, fallback uint16) uint16 {
+	if item, ok := assets.FindByName(name); ok {
+		return item.ID
+	}
+	return fallback
+}
+
+// UseItemFromInventoryOnTile uses an item on the thing of the tile the client would pick, see domain.Tile.TopUseItem.
+func (b *Bot) UseItemFromInventoryOnTile(item state.ItemInInventory, to domain.Tile) error {
+	target, stackpos, ok := to.TopUseItem()
+	if !ok {
+		return fmt.Errorf("tile %v has no item to use on", to.Position)
+	}
 	pkt := packets.UseItemWithCrosshairRequest{
 		FromPos:      item.Position,
 		FromItemId:   item.Item.ID,
 		FromStackPos: 0, // stack pos is always 0 for inventory items
 
 		ToPos:      to.Position,
-		ToItemId:   to.TopItem().ID,
-		ToStackPos: uint8(len(to.Items) - 1),
+		ToItemId:   target.ID,
+		ToStackPos: stackpos,
+	}
+	return b.sendToServer(&pkt, protocol.PriorityNormal)
+}
+
+func (b *Bot) UseItemOnCreature(item state.ItemInInventory, creatureId uint32, prio protocol.Priority) error {
+	pkt := packets.UseItemOnCreatureRequest{
+		FromPos:      item.Position,
+		FromItemId:   item.Item.ID,
+		FromStackPos: 0, // stack pos is always 0 for inventory items
+		CreatureID:   creatureId,
+	}
+	return b.sendToServer(&pkt, prio)
+}
+
+// UseTileItem uses the top item of a map tile, e.g. a ladder or a lever.
+func (b *Bot) UseTileItem(tile domain.Tile) error {
+	item, stackpos, ok := tile.TopUseItem()
+	if !ok {
+		return fmt.Errorf("tile %v has no item to use", tile.Position)
+	}
+	pkt := packets.UseItemRequest{
+		Pos:      tile.Position,
+		ItemId:   item.ID,
+		StackPos: stackpos,
+	}
+	return b.sendToServer(&pkt, protocol.PriorityNormal)
+}
+
+// MoveItem moves count items of an inventory item to another inventory slot or onto the map.
+func (b *Bot) MoveItem(item state.ItemInInventory, to domain.Position, count int, prio protocol.Priority) error {
+	pkt := packets.MoveThingRequest{
+		FromPos:      item.Position,
+		ItemId:       item.Item.ID,
+		FromStackPos: item.StackPos(),
+		ToPos:        to,
+		Count:        uint8(count),
+	}
+	return b.sendToServer(&pkt, prio)
+}
+
+// StackItem moves a whole stack onto another stack of the same item that has room for it.
+func (b *Bot) StackItem(frame state.WorldSnapshot, item state.ItemInInventory) error {
+	stack := frame.FindStack(item, nil)
+	if stack == nil {
+		return fmt.Errorf("no stack of item %d has room for %d more", item.Item.ID, item.Amount())
+	}
+	return b.MoveItem(item, stack.Position, item.Amount(), protocol.PriorityNormal)
+}
+
+// SplitItem moves count items of a stack into a free slot.
+func (b *Bot) SplitItem(frame state.WorldSnapshot, item state.ItemInInventory, count int) error {
+	if count <= 0 || count >= item.Amount() {
+		return fmt.Errorf("cannot split %d off a stack of %d", count, item.Amount())
+	}
+	to, ok := frame.FindFreeSlot(nil)
+	if !ok {
+		return fmt.Errorf("no free slot for item %d", item.Item.ID)
+	}
+	return b.MoveItem(item, to, count, protocol.PriorityNormal)
+}
+
+// EquipItem moves an item into an equipment slot, the server swaps out what was there.
+func (b *Bot) EquipItem(item state.ItemInInventory, slot domain.EquipmentSlot, prio protocol.Priority) error {
+	return b.MoveItem(item, domain.NewInventoryPosition(slot), item.Amount(), prio)
+}
+
+// UnequipItem moves the item of an equipment slot into a free slot of the open containers.
+func (b *Bot) UnequipItem(frame state.WorldSnapshot, slot domain.EquipmentSlot) error {
+	if int(slot) >= len(frame.Equipment) || frame.Equipment[slot].ID == 0 {
+		return fmt.Errorf("nothing is equipped in the %v slot", slot)
+	}
+	item := state.ItemInInventory{Item: frame.Equipment[slot], Position: domain.NewInventoryPosition(slot)}
+	to, ok := frame.FindFreeSlot(nil)
+	if !ok {
+		return fmt.Errorf("no free slot for item %d", item.Item.ID)
 	}
-	b.serverConn.SendPacket(&pkt)
+	return b.MoveItem(item, to, item.Amount(), protocol.PriorityNormal)
 }
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"testing"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+	"z07/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBot_InventoryActions(t *testing.T) {
+	var frame state.WorldSnapshot
+	frame.Equipment[domain.SlotLeft] = domain.Item{ID: testSword}
+	frame.Containers[0] = &domain.Container{ID: 0, ItemID: testBackpack, Capacity: 20, Items: []domain.Item{
+		{ID: testGold, Count: 60},
+		{ID: testGold, Count: 30},
+	}}
+	gold := state.ItemInInventory{Item: domain.Item{ID: testGold, Count: 30}, Position: domain.NewContainerPosition(0, 1)}
+
+	t.Run("Stack", func(t *testing.T) {
+		conn := &recordingConn{}
+		b := &Bot{serverConn: conn}
+		require.NoError(t, b.StackItem(frame, gold))
+		require.Equal(t, &packets.MoveThingRequest{
+			FromPos:      domain.NewContainerPosition(0, 1),
+			ItemId:       testGold,
+			FromStackPos: 1,
+			ToPos:        domain.NewContainerPosition(0, 0),
+			Count:        30,
+		}, conn.sent[0])
+	})
+
+	t.Run("Split", func(t *testing.T) {
+		conn := &recordingConn{}
+		b := &Bot{serverConn: conn}
+		require.NoError(t, b.SplitItem(frame, gold, 10))
+		require.Equal(t, domain.NewContainerPosition(0, 2), conn.sent[0].(*packets.MoveThingRequest).ToPos)
+		require.Equal(t, uint8(10), conn.sent[0].(*packets.MoveThingRequest).Count)
+
+		require.Error(t, b.SplitItem(frame, gold, 30), "Splitting off the whole stack is a move")
+	})
+
+	t.Run("Equip", func(t *testing.T) {
+		conn := &recordingConn{}
+		b := &Bot{serverConn: conn}
+		require.NoError(t, b.EquipItem(gold, domain.SlotAmmo, protocol.PriorityHigh))
+		require.Equal(t, &packets.MoveThingRequest{
+			FromPos:      domain.NewContainerPosition(0, 1),
+			ItemId:       testGold,
+			FromStackPos: 1,
+			ToPos:        domain.NewInventoryPosition(domain.SlotAmmo),
+			Count:        30,
+		}, conn.sent[0])
+		require.Equal(t, []protocol.Priority{protocol.PriorityHigh}, conn.prios)
+	})
+
+	t.Run("Unequip", func(t *testing.T) {
+		conn := &recordingConn{}
+		b := &Bot{serverConn: conn}
+		require.NoError(t, b.UnequipItem(frame, domain.SlotLeft))
+		require.Equal(t, &packets.MoveThingRequest{
+			FromPos:      domain.NewInventoryPosition(domain.SlotLeft),
+			ItemId:       testSword,
+			FromStackPos: 0,
+			ToPos:        domain.NewContainerPosition(0, 2),
+			Count:        1,
+		}, conn.sent[0])
+
+		require.Error(t, b.UnequipItem(frame, domain.SlotHead))
+	})
+}
@@ -0,0 +1,443 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+	"z07/internal/protocol"
+)
+
+const (
+	AlarmPlayerOnScreen    = "playerOnScreen"   // A player outside our party appears on screen
+	AlarmAttackedByPlayer  = "attackedByPlayer" // A player attacks us
+	AlarmPrivateMessage    = "privateMessage"
+	AlarmGamemasterMessage = "gmMessage" // Red private message, broadcast or a red channel
+	AlarmLowHealth         = "lowHp"
+	AlarmDisconnect        = "disconnect"
+	AlarmMoved             = "moved" // The player moved without walking, e.g. pushed or teleported
+)
+
+const (
+	// alarmBacklog is how many fired alarms are kept for the dashboard.
+	alarmBacklog = 100
+	// alarmWalkGrace is how long after a walk or use request the player may still move.
+	alarmWalkGrace = 2 * time.Second
+	// alarmWebhookTimeout bounds a webhook call, an alarm must not wait for a hanging script.
+	alarmWebhookTimeout = 5 * time.Second
+)
+
+// Module names an alarm can pause.
+const (
+	ModuleFishing   = "fishing"
+	ModuleLighthack = "lighthack"
+	ModuleHealer    = "healer"
+	ModuleCavebot   = "cavebot"
+	ModuleTargeting = "targeting"
+	ModuleLooter    = "looter"
+)
+
+// AlarmRule runs its actions when its trigger fires, then waits for its cooldown.
+type AlarmRule struct {
+	ID         string   `json:"id"`
+	Enabled    bool     `json:"enabled"`
+	Trigger    string   `json:"trigger"`
+	Below      int      `json:"below,omitempty"`   // Health percent for lowHp
+	Pause      []string `json:"pause,omitempty"`   // Modules switched off, they stay off until switched on again
+	Notify     bool     `json:"notify"`            // Shows the alarm on the dashboard and plays a sound
+	Webhook    string   `json:"webhook,omitempty"` // URL on this machine the alarm is posted to as JSON, e.g. a script
+	Logout     bool     `json:"logout"`            // Logs out as soon as the player is out of combat
+	CooldownMs int      `json:"cooldownMs"`
+}
+
+// Alarm is a fired alarm as the dashboard and webhooks receive it.
+type Alarm struct {
+	Seq       uint64 `json:"seq"`
+	Time      int64  `json:"time"` // Unix milliseconds
+	RuleID    string `json:"ruleId"`
+	Trigger   string `json:"trigger"`
+	Character string `json:"character"`
+	Text      string `json:"text"`
+}
+
+// firing is a rule that fires now with the alarm it raises.
+type firing struct {
+	rule  AlarmRule
+	alarm Alarm
+}
+
+type alarms struct {
+	mu       sync.Mutex
+	enabled  bool
+	rules    []AlarmRule
+	nextId   int
+	lastUsed map[string]time.Time
+
+	fired   []Alarm
+	lastSeq uint64
+
+	// What the last tick saw, triggers fire on changes. Nothing fires on the first tick after enabling.
+	primed    bool
+	players   map[uint32]bool
+	chatSeq   uint64
+	pos       domain.Position
+	checked   time.Time
+	lowHealth map[string]bool // Rules whose threshold the health is below
+	walked    time.Time       // Last request that may move the player
+	logout    bool            // Waiting to leave combat to log out
+}
+
+func newAlarms() *alarms {
+	a := &alarms{
+		lastUsed:  make(map[string]time.Time),
+		players:   make(map[uint32]bool),
+		lowHealth: make(map[string]bool),
+	}
+	a.setRules([]AlarmRule{
+		{ID: "player", Enabled: true, Trigger: AlarmPlayerOnScreen, Notify: true, Pause: []string{ModuleFishing}, CooldownMs: 10000},
+		{ID: "attacked", Enabled: true, Trigger: AlarmAttackedByPlayer, Notify: true, Pause: []string{ModuleFishing, ModuleCavebot}, Logout: true, CooldownMs: 10000},
+		{ID: "private", Enabled: true, Trigger: AlarmPrivateMessage, Notify: true, CooldownMs: 5000},
+		{ID: "gm", Enabled: true, Trigger: AlarmGamemasterMessage, Notify: true, Pause: []string{ModuleFishing, ModuleCavebot, ModuleTargeting, ModuleLooter}},
+		{ID: "low-hp", Enabled: true, Trigger: AlarmLowHealth, Below: 30, Notify: true, CooldownMs: 10000},
+		{ID: "disconnect", Enabled: true, Trigger: AlarmDisconnect, Notify: true},
+		{ID: "moved", Enabled: true, Trigger: AlarmMoved, Notify: true, Pause: []string{ModuleFishing}, CooldownMs: 10000},
+	})
+	return a
+}
+
+// setRules replaces the whole list, rules without an id get one. A list with an invalid rule is rejected as a whole.
+func (a *alarms) setRules(rules []AlarmRule) error {
+	var errs []error
+	for _, rule := range rules {
+		if rule.Webhook == "" {
+			continue
+		}
+		if err := checkWebhook(rule.Webhook); err != nil {
+			errs = append(errs, fmt.Errorf("alarm %q: %w", rule.ID, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.rules = slices.Clone(rules)
+	for i := range a.rules {
+		if a.rules[i].ID == "" {
+			a.nextId++
+			a.rules[i].ID = fmt.Sprintf("alarm-%d", a.nextId)
+		}
+	}
+	clear(a.lowHealth)
+	return nil
+}
+
+// checkWebhook only lets alarms reach this machine, a rule from the dashboard must not send the alarms anywhere else.
+func checkWebhook(webhook string) error {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return fmt.Errorf("invalid webhook: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("webhook %q is not an http URL", webhook)
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("webhook %q is not on this machine", webhook)
+}
+
+func (a *alarms) snapshot() (bool, []AlarmRule) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.enabled, slices.Clone(a.rules)
+}
+
+func (a *alarms) toggle() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.enabled = !a.enabled
+	a.primed = false
+	a.logout = false
+}
+
+// since returns the fired alarms newer than seq, oldest first.
+func (a *alarms) since(seq uint64) []Alarm {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	i := slices.IndexFunc(a.fired, func(alarm Alarm) bool { return alarm.Seq > seq })
+	if i < 0 {
+		return nil
+	}
+	return slices.Clone(a.fired[i:])
+}
+
+// noteWalk records a request that may move the player, the moved trigger ignores the moves that follow it.
+func (a *alarms) noteWalk(now time.Time) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.walked = now
+}
+
+// events collects what happened since the last tick, by trigger. It is called with the lock held.
+func (a *alarms) events(frame state.WorldSnapshot, chat []domain.ChatMessage, fighting bool, now time.Time) map[string][]string {
+	events := make(map[string][]string)
+
+	onScreen := make(map[uint32]bool)
+	for _, c := range frame.CreaturesOnScreen() {
+		if !c.IsPlayer() || c.IsPartyMember() {
+			continue
+		}
+		onScreen[c.ID] = true
+		if !a.players[c.ID] {
+			events[AlarmPlayerOnScreen] = append(events[AlarmPlayerOnScreen], c.Name+" is on screen")
+		}
+	}
+	a.players = onScreen
+
+	for _, c := range frame.Creatures {
+		if c.IsPlayer() && c.LastAttack.After(a.checked) {
+			events[AlarmAttackedByPlayer] = append(events[AlarmAttackedByPlayer], c.Name+" attacks us")
+		}
+	}
+	a.checked = now
+
+	for _, m := range chat {
+		a.chatSeq = max(a.chatSeq, m.Seq)
+		if m.Author == frame.Player.Name {
+			continue
+		}
+		text := fmt.Sprintf("%s: %s", m.Author, m.Text)
+		switch {
+		case m.Class.IsGamemaster():
+			events[AlarmGamemasterMessage] = append(events[AlarmGamemasterMessage], text)
+		case m.Class.IsPrivate():
+			events[AlarmPrivateMessage] = append(events[AlarmPrivateMessage], text)
+		}
+	}
+
+	// The server walks after the target by itself when chasing it.
+	pos := frame.Player.Pos
+	if a.pos != (domain.Position{}) && pos != a.pos && now.Sub(a.walked) > alarmWalkGrace && !fighting {
+		events[AlarmMoved] = append(events[AlarmMoved], fmt.Sprintf("Moved from %v to %v", a.pos, pos))
+	}
+	a.pos = pos
+
+	return events
+}
+
+// check returns the rules that fire now with what triggered them. Nothing fires on the first tick after enabling.
+// It is called with the lock held.
+func (a *alarms) check(frame state.WorldSnapshot, chat []domain.ChatMessage, fighting bool, now time.Time) []firing {
+	events := a.events(frame, chat, fighting, now)
+	if !a.primed {
+		a.primed = true
+		return nil
+	}
+
+	var result []firing
+	for _, rule := range a.rules {
+		if !rule.Enabled {
+			continue
+		}
+		texts := events[rule.Trigger]
+		if rule.Trigger == AlarmLowHealth {
+			texts = a.lowHealthEvent(rule, frame.Player.Stats)
+		}
+		if len(texts) == 0 {
+			continue
+		}
+		if now.Sub(a.lastUsed[rule.ID]) < time.Duration(rule.CooldownMs)*time.Millisecond {
+			continue
+		}
+		result = append(result, firing{rule: rule, alarm: a.fire(rule, frame.Player.Name, strings.Join(texts, ", "), now)})
+	}
+	return result
+}
+
+// lowHealthEvent fires once when the health drops below the threshold, and again only after it recovered.
+func (a *alarms) lowHealthEvent(rule AlarmRule, stats domain.Stats) []string {
+	below := stats.MaxHealth > 0 && stats.HealthPercent() < rule.Below
+	wasBelow := a.lowHealth[rule.ID]
+	a.lowHealth[rule.ID] = below
+	if !below || wasBelow {
+		return nil
+	}
+	return []string{fmt.Sprintf("Health is at %d%%", stats.HealthPercent())}
+}
+
+// fire records a fired alarm for the dashboard. It is called with the lock held.
+func (a *alarms) fire(rule AlarmRule, character string, text string, now time.Time) Alarm {
+	a.lastUsed[rule.ID] = now
+	a.lastSeq++
+	alarm := Alarm{
+		Seq:       a.lastSeq,
+		Time:      now.UnixMilli(),
+		RuleID:    rule.ID,
+		Trigger:   rule.Trigger,
+		Character: character,
+		Text:      text,
+	}
+	if rule.Logout {
+		a.logout = true
+	}
+	if rule.Notify {
+		a.fired = append(a.fired, alarm)
+		if len(a.fired) > alarmBacklog {
+			a.fired = a.fired[len(a.fired)-alarmBacklog:]
+		}
+	}
+	return alarm
+}
+
+// disconnected fires the disconnect rules.
+func (a *alarms) disconnected(character string, now time.Time) []firing {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.enabled {
+		return nil
+	}
+	var result []firing
+	for _, rule := range a.rules {
+		if rule.Enabled && rule.Trigger == AlarmDisconnect {
+			result = append(result, firing{rule: rule, alarm: a.fire(rule, character, "Disconnected", now)})
+		}
+	}
+	return result
+}
+
+func (b *Bot) loopAlarms() {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stopChan:
+			return
+
+		case <-ticker.C:
+			b.alarmsTick(b.state.CaptureFrame(), time.Now())
+		}
+	}
+}
+
+func (b *Bot) alarmsTick(frame state.WorldSnapshot, now time.Time) {
+	a := b.alarms
+	// The other modules note their walks with their own lock held, theirs are never taken with this one.
+	fighting := b.targeting.attacking()
+	a.mu.Lock()
+	if !a.enabled || frame.Player.ID == 0 {
+		a.mu.Unlock()
+		return
+	}
+	chat := b.state.ChatHistory(state.ChatQuery{AfterSeq: a.chatSeq})
+	fired := a.check(frame, chat, fighting, now)
+	logout := a.logout && !frame.Player.Icons.Has(domain.IconSwords)
+	if logout {
+		a.logout = false
+	}
+	a.mu.Unlock()
+
+	for _, f := range fired {
+		b.runAlarm(f.rule, f.alarm)
+	}
+
+	if logout {
+		log.Printf("[Bot][Alarms] Logging out")
+		if err := b.sendToServer(&packets.LogoutRequest{}, protocol.PriorityHigh); err != nil {
+			log.Printf("[Bot][Alarms] Failed to log out: %v", err)
+		}
+	}
+}
+
+// alarmDisconnected runs the disconnect rules, with the session gone only their notifications and webhooks matter.
+func (b *Bot) alarmDisconnected() {
+	if b.state == nil {
+		return
+	}
+	for _, f := range b.alarms.disconnected(b.state.CaptureFrame().Player.Name, time.Now()) {
+		b.runAlarm(f.rule, f.alarm)
+	}
+}
+
+// runAlarm runs the actions of a fired rule, the logout waits for the next tick out of combat.
+func (b *Bot) runAlarm(rule AlarmRule, alarm Alarm) {
+	log.Printf("[Bot][Alarms] %s: %s", rule.ID, alarm.Text)
+	for _, module := range rule.Pause {
+		b.pauseModule(module)
+	}
+	if rule.Webhook != "" {
+		go postAlarm(rule.Webhook, alarm)
+	}
+}
+
+// pauseModule switches a module off, it stays off until switched on from the dashboard.
+func (b *Bot) pauseModule(name string) {
+	switch name {
+	case ModuleFishing:
+		b.mu.Lock()
+		b.fishingEnabled = false
+		b.mu.Unlock()
+	case ModuleLighthack:
+		b.mu.Lock()
+		b.lighthackEnabled = false
+		b.mu.Unlock()
+	case ModuleHealer:
+		b.healer.setEnabled(false)
+	case ModuleCavebot:
+		b.cavebot.setEnabled(false)
+	case ModuleTargeting:
+		b.targeting.setEnabled(false)
+	case ModuleLooter:
+		b.looter.setEnabled(false)
+	default:
+		log.Printf("[Bot][Alarms] Unknown module %q", name)
+	}
+}
+
+func postAlarm(url string, alarm Alarm) {
+	body, err := json.Marshal(alarm)
+	if err != nil {
+		log.Printf("[Bot][Alarms] Failed to encode the alarm: %v", err)
+		return
+	}
+	client := http.Client{
+		Timeout: alarmWebhookTimeout,
+		// A redirect could lead the alarm off this machine.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[Bot][Alarms] Webhook %s failed: %v", url, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("[Bot][Alarms] Webhook %s answered %s", url, resp.Status)
+	}
+}
@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"testing"
+	"time"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+
+	"github.com/stretchr/testify/require"
+)
+
+func alarmBot(rules ...AlarmRule) (*Bot, *recordingConn) {
+	conn := &recordingConn{}
+	b := &Bot{state: state.New(), serverConn: conn, alarms: newAlarms(), cavebot: newCavebot()}
+	b.alarms.setRules(rules)
+	b.alarms.toggle()
+	return b, conn
+}
+
+func alarmFrame() state.WorldSnapshot {
+	frame := groundFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	frame.Player.ID = 0x10000001
+	frame.Player.Name = "Knight"
+	frame.Player.Stats = domain.Stats{Health: 100, MaxHealth: 100}
+	return frame
+}
+
+func TestAlarms_PlayerOnScreen(t *testing.T) {
+	b, _ := alarmBot(AlarmRule{ID: "player", Enabled: true, Trigger: AlarmPlayerOnScreen, Notify: true, Pause: []string{ModuleFishing, ModuleCavebot}})
+	b.fishingEnabled = true
+	b.cavebot.toggle()
+	now := time.Now()
+
+	frame := alarmFrame()
+	frame.Creatures[0x10000002] = domain.Creature{ID: 0x10000002, Name: "Friend", Pos: domain.Position{X: 102, Y: 100, Z: 7}, Visible: true, Shield: domain.ShieldBlue}
+	b.alarmsTick(frame, now)
+	require.Empty(t, b.alarms.since(0), "The first tick only looks around")
+
+	frame.Creatures[0x10000003] = domain.Creature{ID: 0x10000003, Name: "Stranger", Pos: domain.Position{X: 103, Y: 101, Z: 7}, Visible: true}
+	monster(frame, 0x40000001, "Rat", 100, 1, 1)
+	b.alarmsTick(frame, now.Add(time.Second))
+
+	alarms := b.alarms.since(0)
+	require.Len(t, alarms, 1)
+	require.Equal(t, "Stranger is on screen", alarms[0].Text, "Party members and monsters are no alarm")
+	require.Equal(t, "Knight", alarms[0].Character)
+	b.mu.Lock()
+	require.False(t, b.fishingEnabled)
+	b.mu.Unlock()
+	enabled, _ := b.cavebot.snapshot()
+	require.False(t, enabled)
+
+	b.alarmsTick(frame, now.Add(2*time.Second))
+	require.Len(t, b.alarms.since(0), 1, "A player that stays is reported once")
+}
+
+func TestAlarms_Messages(t *testing.T) {
+	b, _ := alarmBot(
+		AlarmRule{ID: "private", Enabled: true, Trigger: AlarmPrivateMessage, Notify: true, CooldownMs: 5000},
+		AlarmRule{ID: "gm", Enabled: true, Trigger: AlarmGamemasterMessage, Notify: true},
+	)
+	now := time.Now()
+	b.state.AddChatMessage(domain.ChatMessage{Author: "Old", Class: domain.SpeakPrivate, Text: "before"})
+	b.alarmsTick(alarmFrame(), now)
+
+	b.state.AddChatMessage(domain.ChatMessage{Author: "Knight", Class: domain.SpeakPrivate, Text: "our own"})
+	b.state.AddChatMessage(domain.ChatMessage{Author: "Bob", Class: domain.SpeakPrivate, Text: "hi"})
+	b.state.AddChatMessage(domain.ChatMessage{Author: "GM Tom", Class: domain.SpeakPrivateRed, Text: "are you there?"})
+	b.state.AddChatMessage(domain.ChatMessage{Author: "Bob", Class: domain.SpeakSay, Text: "hello"})
+	b.alarmsTick(alarmFrame(), now.Add(time.Second))
+
+	alarms := b.alarms.since(0)
+	require.Len(t, alarms, 2)
+	require.Equal(t, "Bob: hi", alarms[0].Text)
+	require.Equal(t, "GM Tom: are you there?", alarms[1].Text)
+
+	b.state.AddChatMessage(domain.ChatMessage{Author: "Bob", Class: domain.SpeakPrivate, Text: "again"})
+	b.alarmsTick(alarmFrame(), now.Add(2*time.Second))
+	require.Empty(t, b.alarms.since(alarms[1].Seq), "The rule is on cooldown")
+}
+
+func TestAlarms_LowHealthAndLogout(t *testing.T) {
+	b, conn := alarmBot(AlarmRule{ID: "low-hp", Enabled: true, Trigger: AlarmLowHealth, Below: 30, Logout: true})
+	now := time.Now()
+	frame := alarmFrame()
+	b.alarmsTick(frame, now)
+
+	frame.Player.Stats.Health = 20
+	frame.Player.Icons = domain.IconSwords
+	b.alarmsTick(frame, now.Add(time.Second))
+	require.Empty(t, b.alarms.since(0), "Only rules that notify reach the dashboard")
+	require.Empty(t, conn.sent, "No logout in combat")
+
+	frame.Player.Icons = 0
+	b.alarmsTick(frame, now.Add(2*time.Second))
+	require.Equal(t, []any{&packets.LogoutRequest{}}, sentSince(conn, 0))
+
+	b.alarmsTick(frame, now.Add(3*time.Second))
+	require.Len(t, conn.sent, 1, "Staying low fires once")
+}
+
+func TestAlarms_Moved(t *testing.T) {
+	b, _ := alarmBot(AlarmRule{ID: "moved", Enabled: true, Trigger: AlarmMoved, Notify: true})
+	now := time.Now()
+	frame := alarmFrame()
+	b.alarmsTick(frame, now)
+
+	// Walking from the client is expected.
+	_, err := b.InterceptC2SPacket([]byte{byte(packets.C2SWalkEast)})
+	require.NoError(t, err)
+	frame.Player.Pos.X++
+	b.alarmsTick(frame, time.Now())
+	require.Empty(t, b.alarms.since(0))
+
+	frame.Player.Pos.Y += 3
+	b.alarmsTick(frame, time.Now().Add(alarmWalkGrace+time.Second))
+	alarms := b.alarms.since(0)
+	require.Len(t, alarms, 1)
+	require.Equal(t, AlarmMoved, alarms[0].Trigger)
+}
+
+func TestAlarms_Disconnect(t *testing.T) {
+	b, _ := alarmBot(AlarmRule{ID: "disconnect", Enabled: true, Trigger: AlarmDisconnect, Notify: true})
+	b.state.SetPlayerName("Knight")
+	b.stopChan = make(chan struct{})
+	b.Stop()
+
+	alarms := b.alarms.since(0)
+	require.Len(t, alarms, 1)
+	require.Equal(t, "Disconnected", alarms[0].Text)
+}
+
+func TestAlarms_Webhooks(t *testing.T) {
+	a := newAlarms()
+	_, defaults := a.snapshot()
+
+	err := a.setRules([]AlarmRule{
+		{ID: "local", Trigger: AlarmLowHealth, Webhook: "http://127.0.0.1:9000/alarm"},
+		{ID: "remote", Trigger: AlarmLowHealth, Webhook: "https://example.com/alarm"},
+		{ID: "file", Trigger: AlarmLowHealth, Webhook: "file:///etc/passwd"},
+	})
+	require.ErrorContains(t, err, `"remote"`)
+	require.ErrorContains(t, err, `"file"`)
+	require.NotContains(t, err.Error(), `"local"`)
+	_, rules := a.snapshot()
+	require.Equal(t, defaults, rules, "The previous rules stay")
+
+	require.NoError(t, a.setRules([]AlarmRule{
+		{ID: "v4", Trigger: AlarmLowHealth, Webhook: "http://127.0.0.1:9000/alarm"},
+		{ID: "v6", Trigger: AlarmLowHealth, Webhook: "http://[::1]:9000/alarm"},
+		{ID: "name", Trigger: AlarmLowHealth, Webhook: "http://localhost:9000/alarm"},
+	}))
+}
+
+func TestAlarms_PauseKeepsModulesOff(t *testing.T) {
+	b, _ := alarmBot()
+	b.healer, b.targeting, b.looter = newHealer(), newTargeting(), newLooter()
+	b.Configure(Settings{Fishing: true, Lighthack: true, LighthackLevel: 0x0F, Healer: true, Cavebot: true, Targeting: true, Looter: true})
+
+	// Pausing twice leaves a paused module off, unlike a toggle.
+	for range 2 {
+		for _, module := range []string{ModuleFishing, ModuleLighthack, ModuleHealer, ModuleCavebot, ModuleTargeting, ModuleLooter} {
+			b.pauseModule(module)
+		}
+	}
+
+	snap := b.snapshot(state.ChatQuery{}, 0)
+	require.False(t, snap.FishingEnabled)
+	require.False(t, snap.LighthackEnabled)
+	require.Equal(t, uint8(0x0F), snap.LighthackLevel)
+	require.False(t, snap.HealerEnabled)
+	require.False(t, snap.CavebotEnabled)
+	require.False(t, snap.TargetingEnabled)
+	require.False(t, snap.LooterEnabled)
+}
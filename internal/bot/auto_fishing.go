@@ -7,7 +7,11 @@ import (
 	"z07/internal/game/state"
 )
 
-const fishingRodItemId = 3483
+const (
+	fishingRodItemId = 3483
+	// fishWaterItemId stays an ID, every kind of water is just called "water".
+	fishWaterItemId = 4598
+)
 
 func (b *Bot) loopFishing() {
 	ticker := time.NewTicker(1000 * time.Millisecond)
@@ -19,13 +23,16 @@ func (b *Bot) loopFishing() {
 			return
 
 		case <-ticker.C:
-			if !b.fishingEnabled {
+			b.mu.Lock()
+			enabled := b.fishingEnabled
+			b.mu.Unlock()
+			if !enabled {
 				continue
 			}
 
 			frame := b.state.CaptureFrame()
 
-			fishingRod := frame.FindItemInEqAndOpenWindows(fishingRodItemId)
+			fishingRod := frame.FindItemInEqAndOpenWindows(itemId("fishing rod", fishingRodItemId))
 			if fishingRod == nil {
 				log.Println("[Bot] No fishing rod found in equipment or containers.")
 				continue
@@ -36,7 +43,9 @@ func (b *Bot) loopFishing() {
 				continue
 			}
 
-			b.UseItemFromInventoryOnTile(*fishingRod, *tileWithFish)
+			if err := b.UseItemFromInventoryOnTile(*fishingRod, *tileWithFish); err != nil {
+				log.Printf("[Bot] Fishing failed: %v", err)
+			}
 		}
 	}
 }
@@ -47,7 +56,10 @@ func (b *Bot) findFishPos(frame state.WorldSnapshot) *domain.Tile {
 		for y := pos.Y - 5; y <= pos.Y+5; y++ {
 			currentPos := domain.Position{X: x, Y: y, Z: pos.Z}
 			tile, ok := frame.WorldMap[currentPos]
-			if ok && tile.Items[0].ID == 4598 {
+			if !ok {
+				continue
+			}
+			if ground, ok := tile.Ground(); ok && ground.ID == fishWaterItemId {
 				log.Printf("[Bot] Found water with tile at (%d, %d, %d)", x, y, pos.Z)
 				return tile
 			}
@@ -0,0 +1,184 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+	"z07/internal/protocol"
+)
+
+const (
+	ultimateHealingRuneItemId = 3160
+	vialItemId                = 2874
+	fluidMana                 = 7
+)
+
+const (
+	HealStatHealth = "hp"
+	HealStatMana   = "mana"
+
+	HealActionSay       = "say"
+	HealActionUseOnSelf = "useOnSelf"
+)
+
+// HealRule fires its action when the watched stat drops below the threshold.
+// Rules with a higher Priority are checked first and at most one rule fires per tick.
+type HealRule struct {
+	ID         string `json:"id"`
+	Enabled    bool   `json:"enabled"`
+	Stat       string `json:"stat"`  // hp or mana
+	Below      int    `json:"below"` // Percent of the maximum
+	Action     string `json:"action"`
+	Words      string `json:"words,omitempty"`   // Spell words for say
+	ItemID     uint16 `json:"itemId,omitempty"`  // Item for useOnSelf
+	SubType    uint8  `json:"subType,omitempty"` // Fluid type, 0 matches any
+	CooldownMs int    `json:"cooldownMs"`
+	Priority   int    `json:"priority"`
+}
+
+type healer struct {
+	mu       sync.Mutex
+	enabled  bool
+	rules    []HealRule
+	lastUsed map[string]time.Time
+}
+
+func newHealer() *healer {
+	h := &healer{lastUsed: make(map[string]time.Time)}
+	h.setRules([]HealRule{
+		{ID: "exura", Stat: HealStatHealth, Below: 70, Action: HealActionSay, Words: "exura", CooldownMs: 1000, Priority: 1},
+		{ID: "uh", Stat: HealStatHealth, Below: 40, Action: HealActionUseOnSelf, ItemID: ultimateHealingRuneItemId, CooldownMs: 1000, Priority: 2},
+		{ID: "mana-fluid", Stat: HealStatMana, Below: 30, Action: HealActionUseOnSelf, ItemID: vialItemId, SubType: fluidMana, CooldownMs: 1000, Priority: 0},
+	})
+	return h
+}
+
+func (h *healer) setRules(rules []HealRule) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sorted := make([]HealRule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	h.rules = sorted
+}
+
+func (h *healer) snapshot() (bool, []HealRule) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	rules := make([]HealRule, len(h.rules))
+	copy(rules, h.rules)
+	return h.enabled, rules
+}
+
+func (h *healer) toggle() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.enabled = !h.enabled
+}
+
+// setEnabled switches the healer on or off, e.g. when an alarm pauses it.
+func (h *healer) setEnabled(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.enabled = enabled
+}
+
+// nextRule returns the highest priority rule that should fire now, or nil.
+func (h *healer) nextRule(stats domain.Stats, now time.Time) *HealRule {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.enabled || stats.MaxHealth == 0 {
+		return nil
+	}
+
+	for i := range h.rules {
+		rule := &h.rules[i]
+		if !rule.Enabled || !rule.triggered(stats) {
+			continue
+		}
+		if now.Sub(h.lastUsed[rule.ID]) < time.Duration(rule.CooldownMs)*time.Millisecond {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+func (h *healer) markUsed(ruleId string, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastUsed[ruleId] = now
+}
+
+func (r HealRule) triggered(stats domain.Stats) bool {
+	switch r.Stat {
+	case HealStatHealth:
+		return stats.HealthPercent() < r.Below
+	case HealStatMana:
+		return stats.MaxMana > 0 && stats.ManaPercent() < r.Below
+	default:
+		return false
+	}
+}
+
+func (b *Bot) loopHealer() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stopChan:
+			return
+
+		case <-ticker.C:
+			frame := b.state.CaptureFrame()
+			now := time.Now()
+
+			rule := b.healer.nextRule(frame.Player.Stats, now)
+			if rule == nil {
+				continue
+			}
+
+			// A failed rule also waits for its cooldown, so a missing rune does not flood the log.
+			b.healer.markUsed(rule.ID, now)
+			if err := b.executeHealRule(*rule, frame); err != nil {
+				log.Printf("[Bot][Healer] Rule %s: %v", rule.ID, err)
+			}
+		}
+	}
+}
+
+func (b *Bot) executeHealRule(rule HealRule, frame state.WorldSnapshot) error {
+	switch rule.Action {
+	case HealActionSay:
+		return b.sendToServer(&packets.SayRequest{
+			Class: domain.SpeakSay,
+			Text:  rule.Words,
+		}, protocol.PriorityHigh)
+
+	case HealActionUseOnSelf:
+		items := frame.FindItems(func(item domain.Item) bool {
+			return item.ID == rule.ItemID && (rule.SubType == 0 || item.Count == rule.SubType)
+		})
+		if len(items) == 0 {
+			return fmt.Errorf("item %d not found in equipment or open containers", rule.ItemID)
+		}
+		return b.UseItemOnCreature(items[0], frame.Player.ID, protocol.PriorityHigh)
+
+	default:
+		return fmt.Errorf("unknown action %q", rule.Action)
+	}
+}
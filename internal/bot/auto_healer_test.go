@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"net"
+	"testing"
+	"time"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+	"z07/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingConn captures every packet the bot sends, and the priority of injected ones.
+type recordingConn struct {
+	sent  []protocol.Encodable
+	prios []protocol.Priority
+}
+
+func (c *recordingConn) Inject(packet protocol.Encodable, prio protocol.Priority) error {
+	c.prios = append(c.prios, prio)
+	return c.SendPacket(packet)
+}
+
+func (c *recordingConn) ReadMessage() ([]byte, error) { return nil, nil }
+func (c *recordingConn) WriteMessage([]byte) error    { return nil }
+func (c *recordingConn) SendPacket(packet protocol.Encodable) error {
+	c.sent = append(c.sent, packet)
+	return nil
+}
+func (c *recordingConn) RemoteAddr() net.Addr     { return nil }
+func (c *recordingConn) EnableXTEA(key [4]uint32) {}
+func (c *recordingConn) Close() error             { return nil }
+
+func TestHealer_NextRule(t *testing.T) {
+	h := newHealer()
+	h.setRules([]HealRule{
+		{ID: "exura", Enabled: true, Stat: HealStatHealth, Below: 70, Action: HealActionSay, Words: "exura", CooldownMs: 1000, Priority: 1},
+		{ID: "uh", Enabled: true, Stat: HealStatHealth, Below: 40, Action: HealActionUseOnSelf, ItemID: 3160, CooldownMs: 1000, Priority: 2},
+	})
+	now := time.Now()
+
+	t.Run("Disabled healer does nothing", func(t *testing.T) {
+		require.Nil(t, h.nextRule(domain.Stats{Health: 10, MaxHealth: 100}, now))
+	})
+
+	h.toggle()
+
+	t.Run("Healthy player", func(t *testing.T) {
+		require.Nil(t, h.nextRule(domain.Stats{Health: 90, MaxHealth: 100}, now))
+	})
+
+	t.Run("Higher priority rule wins", func(t *testing.T) {
+		rule := h.nextRule(domain.Stats{Health: 30, MaxHealth: 100}, now)
+		require.NotNil(t, rule)
+		require.Equal(t, "uh", rule.ID)
+	})
+
+	t.Run("Rule on cooldown is skipped", func(t *testing.T) {
+		h.markUsed("uh", now)
+
+		rule := h.nextRule(domain.Stats{Health: 30, MaxHealth: 100}, now.Add(500*time.Millisecond))
+		require.NotNil(t, rule)
+		require.Equal(t, "exura", rule.ID)
+
+		rule = h.nextRule(domain.Stats{Health: 30, MaxHealth: 100}, now.Add(1500*time.Millisecond))
+		require.Equal(t, "uh", rule.ID)
+	})
+}
+
+func TestHealer_ExecuteHealRule(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn}
+
+	frame := state.WorldSnapshot{
+		Player: domain.Player{ID: 0x10000001},
+	}
+	frame.Containers[0] = &domain.Container{
+		Items: []domain.Item{{ID: 2874, Count: 2}, {ID: 2874, Count: 7}},
+	}
+
+	t.Run("Say spell", func(t *testing.T) {
+		err := b.executeHealRule(HealRule{Action: HealActionSay, Words: "exura"}, frame)
+		require.NoError(t, err)
+		require.Equal(t, &packets.SayRequest{Class: domain.SpeakSay, Text: "exura"}, conn.sent[len(conn.sent)-1])
+		require.Equal(t, protocol.PriorityHigh, conn.prios[len(conn.prios)-1])
+	})
+
+	t.Run("Use fluid on self", func(t *testing.T) {
+		err := b.executeHealRule(HealRule{Action: HealActionUseOnSelf, ItemID: 2874, SubType: 7}, frame)
+		require.NoError(t, err)
+		require.Equal(t, &packets.UseItemOnCreatureRequest{
+			FromPos:    domain.NewContainerPosition(0, 1),
+			FromItemId: 2874,
+			CreatureID: 0x10000001,
+		}, conn.sent[len(conn.sent)-1])
+		require.Equal(t, protocol.PriorityHigh, conn.prios[len(conn.prios)-1])
+	})
+
+	t.Run("Missing item", func(t *testing.T) {
+		err := b.executeHealRule(HealRule{Action: HealActionUseOnSelf, ItemID: 3160}, frame)
+		require.Error(t, err)
+	})
+}
@@ -4,9 +4,12 @@ import (
 	"log"
 	"sync"
 	"time"
+	"z07/internal/assets"
+	"z07/internal/capture"
 	"z07/internal/game/packets"
 	"z07/internal/game/state"
 	"z07/internal/protocol"
+	"z07/internal/rules"
 )
 
 type Bot struct {
@@ -18,26 +21,85 @@ type Bot struct {
 	wg         sync.WaitGroup // To wait for modules to finish
 	stopOnce   sync.Once      // To ensure we close the channel only once
 
-	// Module states
+	// Module states, mu guards the fishing and lighthack settings, the other modules have their own lock.
+	mu               sync.Mutex
 	fishingEnabled   bool
 	lighthackEnabled bool
 	lighthackLevel   uint8
 	lighthackColor   uint8
+	healer           *healer
+	cavebot          *cavebot
+	targeting        *targeting
+	looter           *looter
+	alarms           *alarms
+	capture          *capture.Session
+	rules            *rules.Engine
 
 	lastLookedAt uint16
 }
 
+// Settings are what a session starts with, every module can still be switched from the dashboard.
+type Settings struct {
+	Fishing        bool
+	Lighthack      bool
+	LighthackLevel uint8
+	LighthackColor uint8
+	Healer         bool
+	Cavebot        bool
+	Targeting      bool
+	Looter         bool
+	Alarms         bool
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		LighthackLevel: 0x0F,
+		LighthackColor: 0xD7,
+	}
+}
+
 func NewBot(state *state.GameState, clientConn protocol.Connection, serverConn protocol.Connection) *Bot {
-	return &Bot{
+	b := &Bot{
 		state: state,
 
 		clientConn: clientConn,
 		serverConn: serverConn,
 		stopChan:   make(chan struct{}),
 
-		lighthackLevel: 0x0F,
-		lighthackColor: 0xD7,
+		healer:    newHealer(),
+		cavebot:   newCavebot(),
+		targeting: newTargeting(),
+		looter:    newLooter(),
+		alarms:    newAlarms(),
+		rules:     rules.NewEngine(),
 	}
+	b.Configure(DefaultSettings())
+	return b
+}
+
+// Configure applies the session defaults. It must be called before Start.
+func (b *Bot) Configure(s Settings) {
+	b.mu.Lock()
+	b.fishingEnabled = s.Fishing
+	b.lighthackEnabled = s.Lighthack
+	b.lighthackLevel = s.LighthackLevel
+	b.lighthackColor = s.LighthackColor
+	b.mu.Unlock()
+	b.healer.enabled = s.Healer
+	b.cavebot.enabled = s.Cavebot
+	b.targeting.enabled = s.Targeting
+	b.looter.enabled = s.Looter
+	b.alarms.enabled = s.Alarms
+}
+
+// SetCapture lets the UI switch the recording of this session. A nil session leaves capturing unavailable.
+func (b *Bot) SetCapture(c *capture.Session) {
+	b.capture = c
+}
+
+// SetRules replaces the packet rules of this session, usually with the engine every session shares.
+func (b *Bot) SetRules(e *rules.Engine) {
+	b.rules = e
 }
 
 func (b *Bot) Start() {
@@ -45,18 +107,18 @@ func (b *Bot) Start() {
 
 	b.runModule("LightHack", b.loopLightHack)
 	b.runModule("Fishing", b.loopFishing)
-	b.runModule("UI", b.loopWebUI)
-}
-
-func (b *Bot) StartUIOnly() {
-	log.Println("[Bot] Engine started in UI-only mode")
-
-	b.runModule("UI", b.loopWebUI)
+	b.runModule("Healer", b.loopHealer)
+	b.runModule("Cavebot", b.loopCavebot)
+	b.runModule("Targeting", b.loopTargeting)
+	b.runModule("Looter", b.loopLooter)
+	b.runModule("Alarms", b.loopAlarms)
 }
 
 func (b *Bot) Stop() {
 	b.stopOnce.Do(func() {
 		log.Println("[Bot] Stopping engine...")
+		// Fired before the broadcast, so the dashboard still gets the notification with its last update.
+		b.alarmDisconnected()
 		close(b.stopChan) // This broadcasts the signal to ALL loops instantly
 	})
 
@@ -64,6 +126,34 @@ func (b *Bot) Stop() {
 	log.Println("[Bot] Engine stopped cleanly.")
 }
 
+// sendToServer injects a packet into the stream to the server, the priority decides where it lands among the queued traffic.
+func (b *Bot) sendToServer(pkt protocol.Encodable, prio protocol.Priority) error {
+	b.trackRequest(pkt)
+	return inject(b.serverConn, pkt, prio)
+}
+
+// action is what a module decided to do with its lock held. The module runs it once the lock is released,
+// injecting can wait for the outbox and the dashboard and the alarms must not wait with it.
+type action func() error
+
+// request is an action sending a single packet to the server.
+func (b *Bot) request(pkt protocol.Encodable, prio protocol.Priority) action {
+	return func() error {
+		return b.sendToServer(pkt, prio)
+	}
+}
+
+func (b *Bot) sendToClient(pkt protocol.Encodable, prio protocol.Priority) error {
+	return inject(b.clientConn, pkt, prio)
+}
+
+func inject(conn protocol.Connection, pkt protocol.Encodable, prio protocol.Priority) error {
+	if injector, ok := conn.(protocol.Injector); ok {
+		return injector.Inject(pkt, prio)
+	}
+	return conn.SendPacket(pkt)
+}
+
 func (b *Bot) runModule(name string, logic func()) {
 	b.wg.Add(1)
 	go func() {
@@ -86,7 +176,10 @@ func (b *Bot) loopLightHack() {
 			return
 
 		case <-ticker.C:
-			if !b.lighthackEnabled {
+			b.mu.Lock()
+			enabled, level, color := b.lighthackEnabled, b.lighthackLevel, b.lighthackColor
+			b.mu.Unlock()
+			if !enabled {
 				continue
 			}
 			pId := b.state.CaptureFrame().Player.ID
@@ -97,10 +190,10 @@ func (b *Bot) loopLightHack() {
 
 			pkt := &packets.CreatureLightMsg{
 				CreatureID: pId,
-				LightLevel: b.lighthackLevel,
-				Color:      b.lighthackColor,
+				LightLevel: level,
+				Color:      color,
 			}
-			err := b.clientConn.SendPacket(pkt)
+			err := b.sendToClient(pkt, protocol.PriorityLow)
 			if err != nil {
 				log.Printf("[Bot][LightHack] Failed to send light packet: %v", err)
 				return
@@ -119,28 +212,69 @@ func (b *Bot) InterceptS2CPacket(data []byte) ([]byte, error) {
 		msg.Encode(pw)
 		return pw.GetBytes()
 	}
-	return data, nil
+	return b.rules.Apply(rules.ServerToClient, data, b.state), nil
 }
 
 // InterceptC2SPacket has to return immediately.
 func (b *Bot) InterceptC2SPacket(data []byte) ([]byte, error) {
-	opcode := packets.C2SOpcode(data[0])
-
-	// LOG FOR TESTING
-	// This only prints in terminal so you can copy it
-	//fmt.Println("\n--- COPY ME FOR TEST ---")
-	//fmt.Println(FormatForTest(fmt.Sprintf("Opcode: %d", opcode), data))
-	//fmt.Println("------------------------")
+	// Only what reaches the server is tracked, a request a rule dropped never happens.
+	data = b.rules.Apply(rules.ClientToServer, data, b.state)
+	if len(data) == 0 {
+		return data, nil
+	}
 
-	switch opcode {
+	switch packets.C2SOpcode(data[0]) {
 	case packets.C2SLookRequest:
 		pr := protocol.NewPacketReader(data)
 		pr.ReadUint8() // skip opcode
 		b.handleLookRequest(pr)
+	case packets.C2SSetFightModes:
+		pr := protocol.NewPacketReader(data)
+		pr.ReadUint8() // skip opcode
+		if p, err := packets.ParseSetFightModesRequest(pr); err == nil {
+			b.targeting.clientFightModes(p)
+		}
+	case packets.C2SAttack:
+		pr := protocol.NewPacketReader(data)
+		pr.ReadUint8() // skip opcode
+		if p, err := packets.ParseAttackRequest(pr); err == nil {
+			b.targeting.clientTarget(p.CreatureID, false)
+		}
+	case packets.C2SFollow:
+		pr := protocol.NewPacketReader(data)
+		pr.ReadUint8() // skip opcode
+		if p, err := packets.ParseFollowRequest(pr); err == nil {
+			b.targeting.clientTarget(p.CreatureID, true)
+		}
+	case packets.C2SUseItem, packets.C2SUpContainer, packets.C2SAutoWalk,
+		packets.C2SWalkNorth, packets.C2SWalkEast, packets.C2SWalkSouth, packets.C2SWalkWest,
+		packets.C2SWalkNorthEast, packets.C2SWalkSouthEast, packets.C2SWalkSouthWest, packets.C2SWalkNorthWest:
+		if p, err := packets.ReadAndParseC2S(protocol.NewPacketReader(data)); err == nil {
+			b.trackRequest(p)
+		}
 	}
 	return data, nil
 }
 
+// trackRequest notes what a request is about to do: which container it opens in the state's backpack tree,
+// or that the player walks, which the alarms expect. Client requests and the ones the modules send are both tracked.
+func (b *Bot) trackRequest(pkt any) {
+	switch p := pkt.(type) {
+	case *packets.UseItemRequest:
+		// Using an item can move the player as well, e.g. a ladder.
+		b.alarms.noteWalk(time.Now())
+		if b.state != nil && assets.Get(p.ItemId).IsContainer {
+			b.state.ExpectContainer(p.Index, p.ItemId, p.Pos)
+		}
+	case *packets.UpContainerRequest:
+		if b.state != nil {
+			b.state.ExpectParentContainer(p.ContainerID)
+		}
+	case *packets.WalkRequest, *packets.AutoWalkRequest:
+		b.alarms.noteWalk(time.Now())
+	}
+}
+
 func (b *Bot) handleLookRequest(pr *protocol.PacketReader) {
 	p, err := packets.ParseLookRequest(pr)
 	if err != nil {
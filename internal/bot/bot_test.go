@@ -2,8 +2,11 @@ package bot
 
 import (
 	"testing"
+	"z07/internal/game/domain"
 	"z07/internal/game/packets"
+	"z07/internal/game/state"
 	"z07/internal/protocol"
+	"z07/internal/rules"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -43,3 +46,21 @@ func TestInterceptS2CPacket_LoginQueue(t *testing.T) {
 	assert.Equal(t, uint8(1), msg.RetryTimeSeconds)
 	require.NoError(t, pr.Err())
 }
+
+func TestInterceptC2SPacket_DroppedRequestIsNotTracked(t *testing.T) {
+	gs := state.New()
+	b := &Bot{state: gs, rules: rules.NewEngine()}
+	require.NoError(t, b.rules.Set([]rules.Rule{{ID: "no-use", Enabled: true, Direction: rules.ClientToServer, Opcode: byte(packets.C2SUseItem), Action: rules.ActionDrop}}))
+
+	pw := protocol.NewPacketWriter()
+	(&packets.UseItemRequest{Pos: domain.NewInventoryPosition(domain.SlotBackpack), ItemId: testBackpack}).Encode(pw)
+	useBackpack, err := pw.GetBytes()
+	require.NoError(t, err)
+
+	packet, err := b.InterceptC2SPacket(useBackpack)
+	require.NoError(t, err)
+	require.Empty(t, packet)
+
+	gs.OpenContainer(domain.Container{ID: 0, ItemID: testBackpack, Capacity: 20})
+	require.Equal(t, domain.Position{}, gs.CaptureFrame().Containers[0].Source, "The server never got the request")
+}
@@ -0,0 +1,379 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/pathfinding"
+	"z07/internal/game/state"
+	"z07/internal/protocol"
+)
+
+const (
+	ropeItemId   = 3003
+	shovelItemId = 3457
+)
+
+const (
+	WaypointWalk   = "Walk"
+	WaypointRope   = "Rope"
+	WaypointShovel = "Shovel"
+	WaypointLadder = "Ladder"
+	WaypointStairs = "Stairs"
+	WaypointUse    = "Use"
+	WaypointSay    = "Say"
+)
+
+const (
+	// cavebotStuckTicks is how many ticks the position may stay the same before a retry.
+	cavebotStuckTicks = 8
+	// cavebotMaxRetries is how many times a waypoint is retried before it is skipped.
+	cavebotMaxRetries = 3
+)
+
+type Waypoint struct {
+	ID   string `json:"id"` // Required for DND reordering
+	Type string `json:"type"`
+	X    uint16 `json:"x"`
+	Y    uint16 `json:"y"`
+	Z    uint8  `json:"z"`
+	Text string `json:"text,omitempty"` // Words for Say waypoints
+}
+
+func (w Waypoint) Pos() domain.Position {
+	return domain.Position{X: w.X, Y: w.Y, Z: w.Z}
+}
+
+// changesFloor reports whether the waypoint is done once the player leaves its floor.
+func (w Waypoint) changesFloor() bool {
+	switch w.Type {
+	case WaypointRope, WaypointShovel, WaypointLadder, WaypointStairs:
+		return true
+	default:
+		return false
+	}
+}
+
+type cavebot struct {
+	mu        sync.Mutex
+	enabled   bool
+	waypoints []Waypoint
+	nextId    int
+
+	// Progress through the list
+	current    int
+	retries    int
+	lastPos    domain.Position
+	stuckTicks int
+	dug        bool // Shovel waypoints: the hole was opened, step into it
+
+	// The last step sent, the next one waits until the player moved or the step should be done.
+	stepFrom  domain.Position
+	stepUntil time.Time
+}
+
+func newCavebot() *cavebot {
+	return &cavebot{}
+}
+
+func (c *cavebot) snapshot() (bool, []Waypoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	waypoints := make([]Waypoint, len(c.waypoints))
+	copy(waypoints, c.waypoints)
+	return c.enabled, waypoints
+}
+
+func (c *cavebot) toggle() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.enabled = !c.enabled
+	c.resetProgress()
+}
+
+// setEnabled switches the cavebot on or off, the progress is only reset when that changes anything.
+func (c *cavebot) setEnabled(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.enabled == enabled {
+		return
+	}
+	c.enabled = enabled
+	c.resetProgress()
+}
+
+func (c *cavebot) add(wp Waypoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextId++
+	wp.ID = fmt.Sprintf("wp-%d", c.nextId)
+	c.waypoints = append(c.waypoints, wp)
+}
+
+func (c *cavebot) remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, wp := range c.waypoints {
+		if wp.ID == id {
+			c.waypoints = append(c.waypoints[:i], c.waypoints[i+1:]...)
+			if c.current > i {
+				c.current--
+			} else if c.current == i {
+				c.advance()
+			}
+			if c.current >= len(c.waypoints) {
+				c.resetProgress()
+			}
+			break
+		}
+	}
+}
+
+// load replaces the whole list. It is used both for reordering and for loading a saved route.
+func (c *cavebot) load(waypoints []Waypoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.waypoints = make([]Waypoint, 0, len(waypoints))
+	for _, wp := range waypoints {
+		if wp.ID == "" {
+			c.nextId++
+			wp.ID = fmt.Sprintf("wp-%d", c.nextId)
+		}
+		c.waypoints = append(c.waypoints, wp)
+	}
+	if c.current >= len(c.waypoints) {
+		c.resetProgress()
+	}
+}
+
+func (c *cavebot) resetProgress() {
+	c.current = 0
+	c.advance()
+}
+
+func (c *cavebot) advance() {
+	c.retries = 0
+	c.stuckTicks = 0
+	c.dug = false
+}
+
+func (b *Bot) loopCavebot() {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stopChan:
+			return
+
+		case <-ticker.C:
+			b.cavebotTick(b.state.CaptureFrame(), time.Now())
+		}
+	}
+}
+
+func (b *Bot) cavebotTick(frame state.WorldSnapshot, now time.Time) {
+	wp, act := b.cavebotStep(frame, now)
+	if act == nil {
+		return
+	}
+	if err := act(); err != nil {
+		log.Printf("[Bot][Cavebot] Waypoint %s (%s): %v", wp.ID, wp.Type, err)
+	}
+}
+
+// cavebotStep decides what the tick does for the current waypoint, with the cavebot lock held.
+func (b *Bot) cavebotStep(frame state.WorldSnapshot, now time.Time) (Waypoint, action) {
+	c := b.cavebot
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.enabled || len(c.waypoints) == 0 || frame.Player.ID == 0 {
+		return Waypoint{}, nil
+	}
+	// Monsters on the way are fought and looted first, the route continues afterwards.
+	if b.targeting.attacking() || b.looter.busy() {
+		return Waypoint{}, nil
+	}
+
+	wp := c.waypoints[c.current]
+	pos := frame.Player.Pos
+
+	if wp.changesFloor() && pos.Z != wp.Z {
+		c.next()
+		return wp, nil
+	}
+
+	// Stuck detection, the position did not change for a while.
+	if pos == c.lastPos {
+		c.stuckTicks++
+	} else {
+		c.lastPos = pos
+		c.stuckTicks = 0
+	}
+	if c.stuckTicks >= cavebotStuckTicks {
+		c.stuckTicks = 0
+		c.retries++
+		c.dug = false
+		c.stepUntil = time.Time{}
+		if c.retries > cavebotMaxRetries {
+			log.Printf("[Bot][Cavebot] Stuck at %v, skipping waypoint %s (%s)", pos, wp.ID, wp.Type)
+			c.next()
+			return wp, nil
+		}
+		log.Printf("[Bot][Cavebot] Stuck at %v, retrying waypoint %s (%d/%d)", pos, wp.ID, c.retries, cavebotMaxRetries)
+	}
+
+	done, act, err := b.runWaypoint(wp, frame, now)
+	if err != nil {
+		log.Printf("[Bot][Cavebot] Waypoint %s (%s): %v", wp.ID, wp.Type, err)
+		return wp, nil
+	}
+	if done {
+		c.next()
+	}
+	return wp, act
+}
+
+func (c *cavebot) next() {
+	c.current = (c.current + 1) % len(c.waypoints)
+	c.advance()
+}
+
+// runWaypoint decides one tick of work for the waypoint and reports whether it is finished.
+// It is called with the cavebot lock held.
+func (b *Bot) runWaypoint(wp Waypoint, frame state.WorldSnapshot, now time.Time) (bool, action, error) {
+	pos := frame.Player.Pos
+	target := wp.Pos()
+
+	if pos.Z != target.Z {
+		return false, nil, fmt.Errorf("player is on floor %d, waypoint is on floor %d", pos.Z, target.Z)
+	}
+
+	walk := func() (bool, action, error) {
+		d, ok, err := b.cavebot.nextStep(frame, target, now)
+		if err != nil || !ok {
+			return false, nil, err
+		}
+		return false, b.request(&packets.WalkRequest{Direction: d}, protocol.PriorityNormal), nil
+	}
+
+	switch wp.Type {
+	case WaypointWalk:
+		if pos == target {
+			return true, nil, nil
+		}
+		return walk()
+
+	case WaypointStairs:
+		// Stepping on the stairs changes the floor, which finishes the waypoint.
+		return walk()
+
+	case WaypointSay:
+		if pos != target {
+			return walk()
+		}
+		return true, b.request(&packets.SayRequest{Class: domain.SpeakSay, Text: wp.Text}, protocol.PriorityNormal), nil
+
+	case WaypointLadder, WaypointUse:
+		if pos.DistanceTo(target) > 1 {
+			return walk()
+		}
+		tile, ok := frame.WorldMap[target]
+		if !ok {
+			return false, nil, fmt.Errorf("tile %v is not known", target)
+		}
+		// A ladder finishes by changing the floor, a plain use is done right away.
+		return wp.Type == WaypointUse, func() error { return b.UseTileItem(*tile) }, nil
+
+	case WaypointRope:
+		if pos.DistanceTo(target) > 1 {
+			return walk()
+		}
+		act, err := b.useToolOnTile(frame, itemId("rope", ropeItemId), target)
+		return false, act, err
+
+	case WaypointShovel:
+		if b.cavebot.dug || pos.DistanceTo(target) > 1 {
+			return walk()
+		}
+		act, err := b.useToolOnTile(frame, itemId("shovel", shovelItemId), target)
+		if err != nil {
+			return false, nil, err
+		}
+		b.cavebot.dug = true
+		return false, act, nil
+
+	default:
+		return false, nil, fmt.Errorf("unknown waypoint type %q", wp.Type)
+	}
+}
+
+func (b *Bot) useToolOnTile(frame state.WorldSnapshot, toolId uint16, target domain.Position) (action, error) {
+	tool := frame.FindItemInEqAndOpenWindows(toolId)
+	if tool == nil {
+		return nil, fmt.Errorf("item %d not found in equipment or open containers", toolId)
+	}
+	tile, ok := frame.WorldMap[target]
+	if !ok {
+		return nil, fmt.Errorf("tile %v is not known", target)
+	}
+	return func() error { return b.UseItemFromInventoryOnTile(*tool, *tile) }, nil
+}
+
+// nextStep returns the next step towards the target once the previous one is done: the player moved,
+// or the step took as long as it does at the player's speed and the server refused it.
+// It is called with the cavebot lock held.
+func (c *cavebot) nextStep(frame state.WorldSnapshot, target domain.Position, now time.Time) (domain.Direction, bool, error) {
+	pos := frame.Player.Pos
+	if pos == c.stepFrom && now.Before(c.stepUntil) {
+		return 0, false, nil
+	}
+	d, ok, err := stepTowards(frame, target)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	c.stepFrom = pos
+	c.stepUntil = now.Add(pathfinding.StepDuration(frame, d))
+	return d, true, nil
+}
+
+// stepTowards returns the first step of the shortest route to the target, false when there is nowhere to go.
+// A single step per tick keeps the route fresh while creatures move around.
+func stepTowards(frame state.WorldSnapshot, target domain.Position) (domain.Direction, bool, error) {
+	path, err := pathfinding.FindPath(frame, frame.Player.Pos, target, pathfinding.Options{})
+	if errors.Is(err, pathfinding.ErrNoPath) {
+		// The target itself may be blocked (stairs or a hole under a creature), get next to it instead.
+		path, err = pathfinding.FindPath(frame, frame.Player.Pos, target, pathfinding.Options{Distance: 1})
+		if err == nil && len(path) == 0 {
+			return stepOnto(frame, target)
+		}
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("walking from %v to %v: %w", frame.Player.Pos, target, err)
+	}
+	if len(path) == 0 {
+		return 0, false, nil
+	}
+	return path[0], true, nil
+}
+
+// stepOnto returns the last step onto an adjacent target tile the pathfinder refuses to enter.
+func stepOnto(frame state.WorldSnapshot, target domain.Position) (domain.Direction, bool, error) {
+	for d := domain.North; d <= domain.NorthEast; d++ {
+		if frame.Player.Pos.Translate(d) == target {
+			return d, true, nil
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"testing"
+	"time"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+	"z07/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+// groundFrame builds a snapshot with walkable ground around the player.
+func groundFrame(player domain.Position) state.WorldSnapshot {
+	frame := state.WorldSnapshot{
+		Player:    domain.Player{ID: 0x10000001, Pos: player},
+		WorldMap:  make(map[domain.Position]*domain.Tile),
+		Creatures: make(map[uint32]domain.Creature),
+	}
+	for dx := -3; dx <= 3; dx++ {
+		for dy := -3; dy <= 3; dy++ {
+			pos := domain.Position{X: uint16(int(player.X) + dx), Y: uint16(int(player.Y) + dy), Z: player.Z}
+			frame.WorldMap[pos] = state.NewTile(pos, domain.Item{ID: 100})
+		}
+	}
+	return frame
+}
+
+// blockingConn holds every injected packet until it is released, like an outbox that is full.
+type blockingConn struct {
+	recordingConn
+	injecting chan struct{}
+	release   chan struct{}
+}
+
+func newBlockingConn() *blockingConn {
+	return &blockingConn{injecting: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (c *blockingConn) Inject(packet protocol.Encodable, prio protocol.Priority) error {
+	c.injecting <- struct{}{}
+	<-c.release
+	return c.recordingConn.Inject(packet, prio)
+}
+
+func TestCavebot_Waypoints(t *testing.T) {
+	c := newCavebot()
+	c.add(Waypoint{Type: WaypointWalk, X: 100, Y: 100, Z: 7})
+	c.add(Waypoint{Type: WaypointRope, X: 101, Y: 100, Z: 7})
+	c.add(Waypoint{Type: WaypointWalk, X: 101, Y: 101, Z: 6})
+
+	_, waypoints := c.snapshot()
+	require.Len(t, waypoints, 3)
+	require.Equal(t, "wp-1", waypoints[0].ID)
+	require.Equal(t, "wp-3", waypoints[2].ID)
+
+	t.Run("Remove", func(t *testing.T) {
+		c.remove("wp-2")
+		_, waypoints := c.snapshot()
+		require.Len(t, waypoints, 2)
+		require.Equal(t, "wp-3", waypoints[1].ID)
+	})
+
+	t.Run("Reorder keeps ids", func(t *testing.T) {
+		c.load([]Waypoint{waypoints[2], waypoints[0]})
+		_, waypoints := c.snapshot()
+		require.Equal(t, "wp-3", waypoints[0].ID)
+		require.Equal(t, "wp-1", waypoints[1].ID)
+	})
+
+	t.Run("Load assigns missing ids", func(t *testing.T) {
+		c.load([]Waypoint{{Type: WaypointSay, X: 100, Y: 100, Z: 7, Text: "hi"}})
+		_, waypoints := c.snapshot()
+		require.Len(t, waypoints, 1)
+		require.Equal(t, "wp-4", waypoints[0].ID)
+	})
+}
+
+func TestCavebot_StepTowards(t *testing.T) {
+	player := domain.Position{X: 100, Y: 100, Z: 7}
+
+	t.Run("Straight step", func(t *testing.T) {
+		frame := groundFrame(player)
+		d, ok, err := stepTowards(frame, domain.Position{X: 100, Y: 97, Z: 7})
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, domain.North, d)
+	})
+
+	t.Run("Diagonal step", func(t *testing.T) {
+		frame := groundFrame(player)
+		frame.WorldMap[player.Translate(domain.East)].Things = nil
+		frame.WorldMap[player.Translate(domain.South)].Things = nil
+		d, _, err := stepTowards(frame, domain.Position{X: 101, Y: 101, Z: 7})
+		require.NoError(t, err)
+		require.Equal(t, domain.SouthEast, d)
+	})
+
+	t.Run("Creature blocks the way", func(t *testing.T) {
+		frame := groundFrame(player)
+		frame.Creatures[0x40000001] = domain.Creature{ID: 0x40000001, Pos: player.Translate(domain.East), Visible: true}
+		d, _, err := stepTowards(frame, domain.Position{X: 103, Y: 100, Z: 7})
+		require.NoError(t, err)
+		require.NotEqual(t, domain.East, d)
+	})
+
+	t.Run("Already there", func(t *testing.T) {
+		_, ok, err := stepTowards(groundFrame(player), player)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("Unknown tiles are not walkable", func(t *testing.T) {
+		frame := groundFrame(player)
+		frame.WorldMap = map[domain.Position]*domain.Tile{}
+		_, _, err := stepTowards(frame, domain.Position{X: 103, Y: 100, Z: 7})
+		require.Error(t, err)
+	})
+}
+
+func TestCavebot_Tick(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn, cavebot: newCavebot()}
+	b.cavebot.add(Waypoint{Type: WaypointWalk, X: 100, Y: 100, Z: 7})
+	b.cavebot.add(Waypoint{Type: WaypointSay, X: 100, Y: 100, Z: 7, Text: "hi"})
+	b.cavebot.add(Waypoint{Type: WaypointStairs, X: 101, Y: 100, Z: 7})
+	b.cavebot.toggle()
+
+	frame := groundFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	now := time.Now()
+
+	t.Run("Reached walk waypoint", func(t *testing.T) {
+		b.cavebotTick(frame, now)
+		require.Equal(t, 1, b.cavebot.current)
+	})
+
+	t.Run("Say waypoint", func(t *testing.T) {
+		b.cavebotTick(frame, now)
+		require.Equal(t, &packets.SayRequest{Class: domain.SpeakSay, Text: "hi"}, conn.sent[len(conn.sent)-1])
+		require.Equal(t, 2, b.cavebot.current)
+	})
+
+	t.Run("Stairs finish on floor change", func(t *testing.T) {
+		b.cavebotTick(frame, now)
+		require.Equal(t, &packets.WalkRequest{Direction: domain.East}, conn.sent[len(conn.sent)-1])
+
+		b.cavebotTick(groundFrame(domain.Position{X: 101, Y: 100, Z: 6}), now)
+		require.Equal(t, 0, b.cavebot.current)
+	})
+
+	t.Run("Stuck waypoint is skipped", func(t *testing.T) {
+		b.cavebot.load([]Waypoint{
+			{Type: WaypointWalk, X: 110, Y: 100, Z: 7},
+			{Type: WaypointWalk, X: 100, Y: 101, Z: 7},
+		})
+		for i := 0; i < cavebotStuckTicks*(cavebotMaxRetries+1); i++ {
+			b.cavebotTick(frame, now)
+		}
+		require.Equal(t, 1, b.cavebot.current)
+	})
+}
+
+func TestCavebot_PacesSteps(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn, cavebot: newCavebot()}
+	b.cavebot.add(Waypoint{Type: WaypointWalk, X: 103, Y: 100, Z: 7})
+	b.cavebot.toggle()
+
+	frame := groundFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	frame.Creatures[frame.Player.ID] = domain.Creature{ID: frame.Player.ID, Speed: 300}
+	now := time.Now()
+	east := &packets.WalkRequest{Direction: domain.East}
+
+	b.cavebotTick(frame, now)
+	require.Equal(t, []any{east}, sentSince(conn, 0))
+
+	b.cavebotTick(frame, now.Add(250*time.Millisecond))
+	require.Len(t, conn.sent, 1, "The step is still being walked")
+
+	b.cavebotTick(frame, now.Add(time.Second))
+	require.Equal(t, []any{east}, sentSince(conn, 1), "The server refused the step, it is sent again")
+
+	frame.Player.Pos.X++
+	b.cavebotTick(frame, now.Add(1100*time.Millisecond))
+	require.Equal(t, []any{east}, sentSince(conn, 2), "The player moved, the next step follows right away")
+}
+
+func TestCavebot_SendsWithoutItsLock(t *testing.T) {
+	conn := newBlockingConn()
+	b := &Bot{serverConn: conn, cavebot: newCavebot()}
+	b.cavebot.add(Waypoint{Type: WaypointWalk, X: 103, Y: 100, Z: 7})
+	b.cavebot.toggle()
+
+	done := make(chan struct{})
+	go func() {
+		b.cavebotTick(groundFrame(domain.Position{X: 100, Y: 100, Z: 7}), time.Now())
+		close(done)
+	}()
+	<-conn.injecting
+
+	// The dashboard and the alarms go on while the step waits for the outbox.
+	b.cavebot.setEnabled(false)
+	enabled, waypoints := b.cavebot.snapshot()
+	require.False(t, enabled)
+	require.Len(t, waypoints, 1)
+
+	close(conn.release)
+	<-done
+	require.Len(t, conn.sent, 1)
+}
@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"fmt"
+	"z07/internal/assets"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+	"z07/internal/protocol"
+)
+
+// OpenContainer opens a container of the inventory in a window, replacing what was open there.
+func (b *Bot) OpenContainer(item state.ItemInInventory, window int) error {
+	if !assets.Get(item.Item.ID).IsContainer {
+		return fmt.Errorf("item %d is not a container", item.Item.ID)
+	}
+	pkt := packets.UseItemRequest{
+		Pos:      item.Position,
+		ItemId:   item.Item.ID,
+		StackPos: item.StackPos(),
+		Index:    uint8(window),
+	}
+	return b.sendToServer(&pkt, protocol.PriorityNormal)
+}
+
+// UpContainer opens the parent of the window's container in the same window.
+func (b *Bot) UpContainer(window int) error {
+	return b.sendToServer(&packets.UpContainerRequest{ContainerID: uint8(window)}, protocol.PriorityNormal)
+}
+
+// OpenContainerPath works its way down to a nested container, see state.WorldSnapshot.FindContainerByPath.
+// Every call opens one level and returns -1, once the container is open its window is returned.
+// Nested containers are opened in the window of their parent, the way the client browses a backpack.
+func (b *Bot) OpenContainerPath(frame state.WorldSnapshot, path []int) (int, error) {
+	if window := frame.FindContainerByPath(path); window >= 0 {
+		return window, nil
+	}
+
+	// Continue from the deepest level that is open.
+	for depth := len(path) - 1; depth >= 0; depth-- {
+		window := frame.FindContainerByPath(path[:depth])
+		if window < 0 {
+			continue
+		}
+		slot := path[depth]
+		items := frame.Containers[window].Items
+		if slot < 0 || slot >= len(items) || !assets.Get(items[slot].ID).IsContainer {
+			return -1, fmt.Errorf("slot %d of %q holds no container", slot, frame.Containers[window].Name)
+		}
+		item := state.ItemInInventory{Item: items[slot], Position: domain.NewContainerPosition(window, slot)}
+		return -1, b.OpenContainer(item, window)
+	}
+
+	backpack := frame.Equipment[domain.SlotBackpack]
+	if backpack.ID == 0 {
+		return -1, fmt.Errorf("no backpack is equipped")
+	}
+	window := frame.FreeContainerWindow()
+	if window < 0 {
+		return -1, fmt.Errorf("every container window is in use")
+	}
+	item := state.ItemInInventory{Item: backpack, Position: domain.NewInventoryPosition(domain.SlotBackpack)}
+	return -1, b.OpenContainer(item, window)
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"testing"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBot_OpenContainerPath(t *testing.T) {
+	gs := state.New()
+	conn := &recordingConn{}
+	b := &Bot{state: gs, serverConn: conn}
+	gs.SetEquipment(domain.SlotBackpack, domain.Item{ID: testBackpack})
+	path := []int{1, 0}
+
+	step := func() (int, error) {
+		return b.OpenContainerPath(gs.CaptureFrame(), path)
+	}
+
+	t.Run("Opens the backpack", func(t *testing.T) {
+		window, err := step()
+		require.NoError(t, err)
+		require.Equal(t, -1, window)
+		require.Equal(t, &packets.UseItemRequest{Pos: domain.NewInventoryPosition(domain.SlotBackpack), ItemId: testBackpack}, conn.sent[len(conn.sent)-1])
+	})
+
+	gs.OpenContainer(domain.Container{ID: 0, ItemID: testBackpack, Capacity: 20, Items: []domain.Item{{ID: testGold, Count: 5}, {ID: testBackpack}}})
+
+	t.Run("Opens the nested backpack in the same window", func(t *testing.T) {
+		_, err := step()
+		require.NoError(t, err)
+		require.Equal(t, &packets.UseItemRequest{Pos: domain.NewContainerPosition(0, 1), ItemId: testBackpack, StackPos: 1}, conn.sent[len(conn.sent)-1])
+	})
+
+	gs.OpenContainer(domain.Container{ID: 0, ItemID: testBackpack, Capacity: 20, HasParent: true, Items: []domain.Item{{ID: testSword}}})
+
+	t.Run("A slot without a container ends the path", func(t *testing.T) {
+		_, err := step()
+		require.Error(t, err)
+	})
+
+	t.Run("Reached", func(t *testing.T) {
+		path = []int{1}
+		window, err := step()
+		require.NoError(t, err)
+		require.Equal(t, 0, window)
+	})
+}
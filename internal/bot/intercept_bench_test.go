@@ -3,6 +3,8 @@ package bot
 import (
 	"testing"
 	"z07/internal/game/packets"
+	"z07/internal/game/state"
+	"z07/internal/rules"
 )
 
 /*
@@ -80,3 +82,28 @@ func BenchmarkInterceptS2CPacket_Login(b *testing.B) {
 		_, _ = bot.InterceptS2CPacket(loginPacket)
 	}
 }
+
+// A typical combat message with one packet dropped by a rule.
+func BenchmarkInterceptS2CPacket_Rules(b *testing.B) {
+	engine := rules.NewEngine()
+	err := engine.Set([]rules.Rule{{
+		ID: "hide-effect", Enabled: true, Direction: rules.ServerToClient, Opcode: byte(packets.S2CMagicEffect),
+		When:   []rules.Condition{{Field: "Type", Op: rules.OpEq, Value: 13}},
+		Action: rules.ActionDrop,
+	}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	bot := &Bot{state: state.New(), rules: engine}
+	msg := []byte{
+		0x8C, 0x01, 0x00, 0x00, 0x40, 0x32, // Creature health
+		0x83, 0x64, 0x00, 0x64, 0x00, 0x07, 0x0D, // Magic effect
+		0x1E, // Ping
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = bot.InterceptS2CPacket(msg)
+	}
+}
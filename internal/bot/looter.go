@@ -0,0 +1,356 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+	"z07/internal/assets"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/pathfinding"
+	"z07/internal/game/state"
+	"z07/internal/protocol"
+)
+
+const goldCoinItemId = 3031
+
+// lootOpenTicks is how many ticks a corpse may take to open before it is given up.
+const lootOpenTicks = 8
+
+// LootItem is an item the looter takes out of corpses.
+type LootItem struct {
+	ItemID      uint16 `json:"itemId"`                // 0 looks the item up by Name
+	Name        string `json:"name,omitempty"`        // Filled from the item names when they are loaded
+	Destination uint16 `json:"destination,omitempty"` // Item ID of the backpack it goes into, 0 for any open one
+}
+
+// LootSettings are edited from the dashboard as a whole.
+type LootSettings struct {
+	Items []LootItem `json:"items"`
+	// MinCapacity stops looting when the free capacity drops below it, in oz.
+	// Items heavier than the free capacity stay in the corpse anyway, as far as their weight is known.
+	MinCapacity uint16 `json:"minCapacity"`
+}
+
+type watchedMonster struct {
+	pos     domain.Position
+	corpses int // Corpses on its tile when it was last seen
+}
+
+type looter struct {
+	mu       sync.Mutex
+	enabled  bool
+	settings LootSettings
+
+	// A monster that vanished from a tile which gained a corpse was killed there.
+	watched map[uint32]watchedMonster
+	corpses []domain.Position
+
+	// The corpse being looted
+	window    int // Container window it was opened in, -1 when none
+	waitTicks int
+}
+
+func newLooter() *looter {
+	return &looter{
+		settings: LootSettings{Items: []LootItem{{ItemID: goldCoinItemId, Name: "gold coin"}}},
+		watched:  make(map[uint32]watchedMonster),
+		window:   -1,
+	}
+}
+
+func (l *looter) snapshot() (bool, LootSettings, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	settings := l.settings
+	settings.Items = slices.Clone(l.settings.Items)
+	return l.enabled, settings, len(l.corpses)
+}
+
+func (l *looter) setSettings(s LootSettings) {
+	items := make([]LootItem, 0, len(s.Items))
+	for _, item := range s.Items {
+		if item.ItemID == 0 {
+			found, ok := assets.FindByName(item.Name)
+			if !ok {
+				log.Printf("[Bot][Looter] Unknown item %q", item.Name)
+				continue
+			}
+			item.ItemID = found.ID
+		}
+		if item.Name == "" {
+			item.Name = assets.Get(item.ItemID).Name
+		}
+		items = append(items, item)
+	}
+	s.Items = items
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.settings = s
+}
+
+func (l *looter) toggle() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.enabled = !l.enabled
+	l.corpses = nil
+	l.window = -1
+	clear(l.watched)
+}
+
+// setEnabled switches the looter on or off, queued corpses are dropped only when that changes anything.
+func (l *looter) setEnabled(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.enabled == enabled {
+		return
+	}
+	l.enabled = enabled
+	l.corpses = nil
+	l.window = -1
+	clear(l.watched)
+}
+
+// busy reports whether corpses are waiting, the cavebot stays until they are looted.
+func (l *looter) busy() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.enabled && (l.window >= 0 || len(l.corpses) > 0)
+}
+
+func (l *looter) lootItem(id uint16) (LootItem, bool) {
+	i := slices.IndexFunc(l.settings.Items, func(item LootItem) bool { return item.ItemID == id })
+	if i < 0 {
+		return LootItem{}, false
+	}
+	return l.settings.Items[i], true
+}
+
+// watch queues the corpses of monsters that died since the last tick.
+func (l *looter) watch(frame state.WorldSnapshot) {
+	seen := make(map[uint32]bool)
+	for _, c := range frame.CreaturesOnScreen() {
+		if !c.IsMonster() {
+			continue
+		}
+		seen[c.ID] = true
+		l.watched[c.ID] = watchedMonster{pos: c.Pos, corpses: countCorpses(frame.WorldMap[c.Pos])}
+	}
+
+	for id, m := range l.watched {
+		if seen[id] {
+			continue
+		}
+		delete(l.watched, id)
+		// A monster that walked away leaves no new corpse behind.
+		if countCorpses(frame.WorldMap[m.pos]) > m.corpses && !slices.Contains(l.corpses, m.pos) {
+			l.corpses = append(l.corpses, m.pos)
+		}
+	}
+}
+
+func isCorpse(item domain.Item) bool {
+	thing := assets.Get(item.ID)
+	return thing.IsCorpse && thing.IsContainer
+}
+
+func countCorpses(tile *domain.Tile) int {
+	if tile == nil {
+		return 0
+	}
+	n := 0
+	for _, item := range tile.Items() {
+		if isCorpse(item) {
+			n++
+		}
+	}
+	return n
+}
+
+// topCorpse finds the uppermost corpse of the tile, the one the client opens.
+func topCorpse(tile *domain.Tile) (domain.Item, uint8, bool) {
+	if tile == nil {
+		return domain.Item{}, 0, false
+	}
+	for stackpos, thing := range tile.Things {
+		if !thing.IsCreature() && isCorpse(thing.Item) {
+			return thing.Item, uint8(stackpos), true
+		}
+	}
+	return domain.Item{}, 0, false
+}
+
+func (b *Bot) loopLooter() {
+	ticker := time.NewTicker(300 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stopChan:
+			return
+
+		case <-ticker.C:
+			b.looterTick(b.state.CaptureFrame())
+		}
+	}
+}
+
+func (b *Bot) looterTick(frame state.WorldSnapshot) {
+	if act := b.looterStep(frame); act != nil {
+		if err := act(); err != nil {
+			log.Printf("[Bot][Looter] Failed to %v", err)
+		}
+	}
+}
+
+// looterStep queues new corpses and decides what to send for the next one, with the looter lock held.
+func (b *Bot) looterStep(frame state.WorldSnapshot) action {
+	l := b.looter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.enabled || frame.Player.ID == 0 {
+		return nil
+	}
+	l.watch(frame)
+
+	// Loot once the fight is over, the corpses do not run away.
+	if b.targeting.attacking() || (l.window < 0 && len(l.corpses) == 0) {
+		return nil
+	}
+
+	if frame.Player.Stats.Capacity < l.settings.MinCapacity {
+		log.Printf("[Bot][Looter] Capacity %d is below %d, leaving %d corpses", frame.Player.Stats.Capacity, l.settings.MinCapacity, len(l.corpses))
+		l.corpses = nil
+		if l.window >= 0 {
+			return b.closeCorpse()
+		}
+		return nil
+	}
+
+	if l.window >= 0 {
+		return b.lootCorpse(frame)
+	}
+	return b.openNextCorpse(frame)
+}
+
+// openNextCorpse walks to the closest corpse and opens it, corpses out of reach are skipped.
+// It is called with the looter lock held.
+func (b *Bot) openNextCorpse(frame state.WorldSnapshot) action {
+	l := b.looter
+	player := frame.Player.Pos
+	slices.SortStableFunc(l.corpses, func(a, b domain.Position) int {
+		return player.DistanceTo(a) - player.DistanceTo(b)
+	})
+
+	pos := l.corpses[0]
+	corpse, stackpos, ok := topCorpse(frame.WorldMap[pos])
+	if !ok || pos.Z != player.Z {
+		// It rotted away or we left the floor.
+		l.corpses = l.corpses[1:]
+		return nil
+	}
+
+	if player.DistanceTo(pos) > 1 {
+		path, err := pathfinding.FindPath(frame, player, pos, pathfinding.Options{Distance: 1})
+		if err != nil {
+			log.Printf("[Bot][Looter] Corpse at %v is out of reach, skipping it", pos)
+			l.corpses = l.corpses[1:]
+			return nil
+		}
+		return b.lootRequest("walk to the corpse", &packets.WalkRequest{Direction: path[0]})
+	}
+
+	window := frame.FreeContainerWindow()
+	if window < 0 {
+		log.Printf("[Bot][Looter] Every container window is in use, skipping the corpse at %v", pos)
+		l.corpses = l.corpses[1:]
+		return nil
+	}
+	// A corpse that does not open is given up after lootOpenTicks, a failed request included.
+	l.corpses = l.corpses[1:]
+	l.window = window
+	l.waitTicks = 0
+	pkt := &packets.UseItemRequest{Pos: pos, ItemId: corpse.ID, StackPos: stackpos, Index: uint8(window)}
+	return b.lootRequest(fmt.Sprintf("open the corpse at %v", pos), pkt)
+}
+
+// lootCorpse moves one listed item per tick out of the open corpse and closes it once nothing is left.
+// It is called with the looter lock held.
+func (b *Bot) lootCorpse(frame state.WorldSnapshot) action {
+	l := b.looter
+	corpse := frame.Containers[l.window]
+	if corpse == nil || !assets.Get(corpse.ItemID).IsCorpse {
+		l.waitTicks++
+		if l.waitTicks >= lootOpenTicks {
+			log.Printf("[Bot][Looter] The corpse did not open, giving up on it")
+			l.window = -1
+		}
+		return nil
+	}
+
+	for slot, item := range corpse.Items {
+		loot, ok := l.lootItem(item.ID)
+		if !ok {
+			continue
+		}
+		to, ok := lootDestination(frame, item, loot.Destination)
+		if !ok {
+			log.Printf("[Bot][Looter] No room for item %d", item.ID)
+			continue
+		}
+		from := state.ItemInInventory{Item: item, Position: domain.NewContainerPosition(l.window, slot)}
+		if weight := assets.Get(item.ID).Weight * uint32(from.Amount()); weight > uint32(frame.Player.Stats.Capacity)*100 {
+			log.Printf("[Bot][Looter] Item %d weighs %d.%02d oz, more than the free capacity", item.ID, weight/100, weight%100)
+			continue
+		}
+		return func() error {
+			if err := b.MoveItem(from, to, from.Amount(), protocol.PriorityNormal); err != nil {
+				return fmt.Errorf("move item %d: %w", item.ID, err)
+			}
+			return nil
+		}
+	}
+	return b.closeCorpse()
+}
+
+// closeCorpse forgets the open corpse. It is called with the looter lock held.
+func (b *Bot) closeCorpse() action {
+	l := b.looter
+	pkt := &packets.CloseContainerRequest{ContainerID: uint8(l.window)}
+	l.window = -1
+	return b.lootRequest("close the corpse", pkt)
+}
+
+// lootRequest sends a packet of the looter, a failure says what it was meant to do.
+func (b *Bot) lootRequest(what string, pkt protocol.Encodable) action {
+	return func() error {
+		if err := b.sendToServer(pkt, protocol.PriorityNormal); err != nil {
+			return fmt.Errorf("%s: %w", what, err)
+		}
+		return nil
+	}
+}
+
+// lootDestination picks where a looted item goes: onto a stack of the same item with room
+// for all of it, otherwise into the first free slot. Corpses are never a destination.
+func lootDestination(frame state.WorldSnapshot, item domain.Item, backpack uint16) (domain.Position, bool) {
+	into := func(c *domain.Container) bool {
+		return backpack == 0 || c.ItemID == backpack
+	}
+	if stack := frame.FindStack(state.ItemInInventory{Item: item}, into); stack != nil {
+		return stack.Position, true
+	}
+	return frame.FindFreeSlot(into)
+}
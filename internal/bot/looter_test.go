@@ -0,0 +1,277 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+	"z07/internal/assets"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCorpse   = 64001
+	testGold     = 64002
+	testSword    = 64003
+	testBackpack = 64004
+	testFloor    = 64005
+	testArmor    = 64006
+)
+
+func init() {
+	assets.Register(
+		assets.ItemType{ID: testCorpse, IsCorpse: true, IsContainer: true},
+		assets.ItemType{ID: testGold, IsStackable: true, IsPickupable: true},
+		assets.ItemType{ID: testSword, IsPickupable: true},
+		assets.ItemType{ID: testBackpack, IsContainer: true, IsPickupable: true},
+		assets.ItemType{ID: testFloor, IsGround: true},
+		assets.ItemType{ID: testArmor, IsPickupable: true, Weight: 12000},
+	)
+}
+
+func lootFrame(player domain.Position) state.WorldSnapshot {
+	frame := groundFrame(player)
+	frame.Player.Stats.Capacity = 400
+	frame.Containers[0] = &domain.Container{ID: 0, ItemID: testBackpack, Capacity: 20, Items: []domain.Item{{ID: testGold, Count: 60}}}
+	return frame
+}
+
+// killAt replaces the monster standing on pos with its corpse.
+func killAt(frame state.WorldSnapshot, id uint32, pos domain.Position) {
+	c := frame.Creatures[id]
+	c.Visible = false
+	frame.Creatures[id] = c
+	tile := state.NewTile(pos, domain.Item{ID: testFloor})
+	tile.Add(packets.ItemThing(domain.Item{ID: testCorpse}))
+	frame.WorldMap[pos] = tile
+}
+
+func TestLooter_Tick(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn, looter: newLooter()}
+	b.looter.setSettings(LootSettings{Items: []LootItem{{ItemID: testGold}, {ItemID: testSword}}, MinCapacity: 100})
+	b.looter.toggle()
+
+	player := domain.Position{X: 100, Y: 100, Z: 7}
+	frame := lootFrame(player)
+	monster(frame, 0x40000001, "Rat", 10, 2, 0)
+	monster(frame, 0x40000002, "Rat", 100, -2, 0)
+	b.looterTick(frame)
+	require.False(t, b.looter.busy())
+
+	corpsePos := domain.Position{X: 102, Y: 100, Z: 7}
+	killAt(frame, 0x40000001, corpsePos)
+	// The other rat walks off screen, it did not die.
+	frame.Creatures[0x40000002] = domain.Creature{ID: 0x40000002, Name: "Rat", Pos: domain.Position{X: 90, Y: 100, Z: 7}, Visible: true}
+
+	t.Run("Walks to the corpse", func(t *testing.T) {
+		b.looterTick(frame)
+		require.True(t, b.looter.busy())
+		require.Equal(t, []domain.Position{corpsePos}, b.looter.corpses)
+		require.Equal(t, &packets.WalkRequest{Direction: domain.East}, conn.sent[len(conn.sent)-1])
+	})
+
+	frame.Player.Pos = domain.Position{X: 101, Y: 100, Z: 7}
+
+	t.Run("Opens it in a free window", func(t *testing.T) {
+		b.looterTick(frame)
+		require.Equal(t, &packets.UseItemRequest{Pos: corpsePos, ItemId: testCorpse, StackPos: 1, Index: 1}, conn.sent[len(conn.sent)-1])
+	})
+
+	frame.Containers[1] = &domain.Container{ID: 1, ItemID: testCorpse, Capacity: 10, Items: []domain.Item{
+		{ID: 3000}, // Not on the list
+		{ID: testGold, Count: 30},
+		{ID: testSword},
+	}}
+
+	t.Run("Stacks gold", func(t *testing.T) {
+		b.looterTick(frame)
+		require.Equal(t, &packets.MoveThingRequest{
+			FromPos:      domain.NewContainerPosition(1, 1),
+			ItemId:       testGold,
+			FromStackPos: 1,
+			ToPos:        domain.NewContainerPosition(0, 0),
+			Count:        30,
+		}, conn.sent[len(conn.sent)-1])
+	})
+
+	frame.Containers[1].Items = []domain.Item{{ID: 3000}, {ID: testSword}}
+
+	t.Run("Moves other items to a free slot", func(t *testing.T) {
+		b.looterTick(frame)
+		require.Equal(t, &packets.MoveThingRequest{
+			FromPos:      domain.NewContainerPosition(1, 1),
+			ItemId:       testSword,
+			FromStackPos: 1,
+			ToPos:        domain.NewContainerPosition(0, 1),
+			Count:        1,
+		}, conn.sent[len(conn.sent)-1])
+	})
+
+	frame.Containers[1].Items = []domain.Item{{ID: 3000}}
+
+	t.Run("Closes the looted corpse", func(t *testing.T) {
+		b.looterTick(frame)
+		require.Equal(t, &packets.CloseContainerRequest{ContainerID: 1}, conn.sent[len(conn.sent)-1])
+		require.False(t, b.looter.busy())
+	})
+}
+
+func TestLooter_SkipsHeavyItems(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn, looter: newLooter()}
+	b.looter.setSettings(LootSettings{Items: []LootItem{{ItemID: testArmor}, {ItemID: testGold}}})
+	b.looter.toggle()
+	b.looter.window = 1
+
+	frame := lootFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	frame.Player.Stats.Capacity = 100
+	frame.Containers[1] = &domain.Container{ID: 1, ItemID: testCorpse, Capacity: 10, Items: []domain.Item{
+		{ID: testArmor},
+		{ID: testGold, Count: 30},
+	}}
+
+	b.looterTick(frame)
+	require.Equal(t, uint16(testGold), conn.sent[len(conn.sent)-1].(*packets.MoveThingRequest).ItemId, "The armor weighs 120 oz")
+
+	frame.Containers[1].Items = frame.Containers[1].Items[:1]
+	b.looterTick(frame)
+	require.Equal(t, &packets.CloseContainerRequest{ContainerID: 1}, conn.sent[len(conn.sent)-1])
+}
+
+func TestLooter_LootsWhileTheServerEditsTheCorpse(t *testing.T) {
+	conn := &recordingConn{}
+	gs := state.New()
+	b := &Bot{state: gs, serverConn: conn, looter: newLooter()}
+	b.looter.setSettings(LootSettings{Items: []LootItem{{ItemID: testGold}}})
+	b.looter.toggle()
+	b.looter.window = 1
+
+	gs.SetPlayerId(0x10000001)
+	gs.SetPlayerStats(domain.Stats{Capacity: 400})
+	gs.OpenContainer(domain.Container{ID: 0, ItemID: testBackpack, Capacity: 20})
+	items := make([]domain.Item, 10)
+	for i := range items {
+		items[i] = domain.Item{ID: testGold, Count: uint8(i + 1)}
+	}
+	gs.OpenContainer(domain.Container{ID: 1, ItemID: testCorpse, Capacity: 10, Items: items})
+
+	// The items leave the corpse while the looter reads its snapshots, run with -race.
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for range items {
+			gs.UpdateContainerItem(1, 0, domain.Item{ID: testGold, Count: 100})
+			gs.RemoveContainerItem(1, 0)
+		}
+	})
+	for range 50 {
+		b.looterTick(gs.CaptureFrame())
+	}
+	wg.Wait()
+
+	b.looterTick(gs.CaptureFrame())
+	require.Equal(t, &packets.CloseContainerRequest{ContainerID: 1}, conn.sent[len(conn.sent)-1])
+}
+
+func TestLooter_SendsWithoutItsLock(t *testing.T) {
+	conn := newBlockingConn()
+	b := &Bot{serverConn: conn, looter: newLooter()}
+	b.looter.toggle()
+	b.looter.window = 1
+
+	frame := lootFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	frame.Containers[1] = &domain.Container{ID: 1, ItemID: testCorpse, Capacity: 10, Items: []domain.Item{{ID: goldCoinItemId, Count: 5}}}
+	done := make(chan struct{})
+	go func() {
+		b.looterTick(frame)
+		close(done)
+	}()
+	<-conn.injecting
+
+	// The cavebot and the dashboard go on while the move waits for the outbox.
+	require.True(t, b.looter.busy())
+	enabled, _, _ := b.looter.snapshot()
+	require.True(t, enabled)
+
+	close(conn.release)
+	<-done
+	require.Len(t, conn.sent, 1)
+}
+
+func TestLooter_SkipsCorpses(t *testing.T) {
+	player := domain.Position{X: 100, Y: 100, Z: 7}
+
+	t.Run("Out of reach", func(t *testing.T) {
+		conn := &recordingConn{}
+		b := &Bot{serverConn: conn, looter: newLooter()}
+		b.looter.toggle()
+
+		frame := lootFrame(player)
+		corpsePos := domain.Position{X: 103, Y: 100, Z: 7}
+		monster(frame, 0x40000001, "Rat", 10, 3, 0)
+		b.looterTick(frame)
+		killAt(frame, 0x40000001, corpsePos)
+		for y := -3; y <= 3; y++ {
+			frame.WorldMap[domain.Position{X: 102, Y: uint16(100 + y), Z: 7}].Things = nil
+		}
+
+		b.looterTick(frame)
+		require.Empty(t, conn.sent)
+		require.False(t, b.looter.busy())
+	})
+
+	t.Run("Not enough capacity", func(t *testing.T) {
+		conn := &recordingConn{}
+		b := &Bot{serverConn: conn, looter: newLooter()}
+		b.looter.setSettings(LootSettings{Items: []LootItem{{ItemID: testGold}}, MinCapacity: 100})
+		b.looter.toggle()
+
+		frame := lootFrame(player)
+		frame.Player.Stats.Capacity = 50
+		monster(frame, 0x40000001, "Rat", 10, 1, 0)
+		b.looterTick(frame)
+		killAt(frame, 0x40000001, domain.Position{X: 101, Y: 100, Z: 7})
+
+		b.looterTick(frame)
+		require.Empty(t, conn.sent)
+		require.False(t, b.looter.busy())
+	})
+
+	t.Run("Waits for the fight to end", func(t *testing.T) {
+		conn := &recordingConn{}
+		b := &Bot{serverConn: conn, looter: newLooter(), targeting: newTargeting()}
+		b.looter.toggle()
+		b.targeting.toggle()
+
+		frame := lootFrame(player)
+		monster(frame, 0x40000001, "Rat", 10, 1, 0)
+		monster(frame, 0x40000002, "Rat", 100, 0, 1)
+		b.looterTick(frame)
+		killAt(frame, 0x40000001, domain.Position{X: 101, Y: 100, Z: 7})
+		b.targetingTick(frame)
+
+		n := len(conn.sent)
+		b.looterTick(frame)
+		require.Empty(t, sentSince(conn, n))
+		require.True(t, b.looter.busy(), "The corpse is kept for later")
+	})
+}
+
+func TestLootDestination(t *testing.T) {
+	frame := lootFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	frame.Containers[2] = &domain.Container{ID: 2, ItemID: 3000, Capacity: 1, Items: []domain.Item{{ID: testSword}}}
+	frame.Containers[3] = &domain.Container{ID: 3, ItemID: 3001, Capacity: 8}
+
+	to, ok := lootDestination(frame, domain.Item{ID: testGold, Count: 50}, 0)
+	require.True(t, ok)
+	require.Equal(t, domain.NewContainerPosition(0, 1), to, "A stack that would overflow is not used")
+
+	to, ok = lootDestination(frame, domain.Item{ID: testSword}, 3001)
+	require.True(t, ok)
+	require.Equal(t, domain.NewContainerPosition(3, 0), to)
+
+	_, ok = lootDestination(frame, domain.Item{ID: testSword}, 3000)
+	require.False(t, ok, "The chosen backpack is full")
+}
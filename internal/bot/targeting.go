@@ -0,0 +1,363 @@
+package bot
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/pathfinding"
+	"z07/internal/game/state"
+	"z07/internal/protocol"
+)
+
+const (
+	// StanceChase lets the server walk after the target, like the chase button of the client.
+	StanceChase = "chase"
+	// StanceKeepDistance walks so the target stays Distance steps away, for paladins and mages.
+	StanceKeepDistance = "keepDistance"
+	// StanceStand attacks without moving.
+	StanceStand = "stand"
+)
+
+// TargetAnyMonster as the rule name matches every monster.
+const TargetAnyMonster = "*"
+
+// targetingUnreachableTicks is how many ticks the target may stay unreachable before another one is picked.
+const targetingUnreachableTicks = 4
+
+// TargetRule decides which monsters are attacked and how.
+// The matching rule with the highest Priority applies to a monster, among monsters of the
+// same priority the closest one is attacked.
+type TargetRule struct {
+	ID          string `json:"id"`
+	Enabled     bool   `json:"enabled"`
+	Name        string `json:"name"` // Monster name, * matches any monster
+	Priority    int    `json:"priority"`
+	MinHp       int    `json:"minHp"`       // Percent, monsters with less health are left alone
+	MaxHp       int    `json:"maxHp"`       // Percent, 0 means no upper limit
+	MaxDistance int    `json:"maxDistance"` // Steps from the player, 0 means anywhere on screen
+	Stance      string `json:"stance"`
+	Distance    int    `json:"distance,omitempty"` // Steps kept to the monster for keepDistance
+}
+
+func (r TargetRule) matches(c domain.Creature, player domain.Position) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.Name != TargetAnyMonster && !strings.EqualFold(r.Name, c.Name) {
+		return false
+	}
+	hp := int(c.HealthPercent)
+	if hp < r.MinHp || (r.MaxHp > 0 && hp > r.MaxHp) {
+		return false
+	}
+	return r.MaxDistance == 0 || player.DistanceTo(c.Pos) <= r.MaxDistance
+}
+
+func (r TargetRule) chaseMode() domain.ChaseMode {
+	if r.Stance == StanceChase {
+		return domain.ChaseFollow
+	}
+	return domain.ChaseStand
+}
+
+type targeting struct {
+	mu      sync.Mutex
+	enabled bool
+	rules   []TargetRule
+	nextId  int
+
+	// The fight modes the server knows, the client sets them on login and on every button press.
+	fightMode  domain.FightMode
+	chaseMode  domain.ChaseMode
+	secureMode bool
+
+	targetID    uint32
+	unreachable int
+	cancels     uint32 // Attacks the server cancelled so far, see domain.Player.TargetCancels
+}
+
+// candidate is a monster on screen with the rule that applies to it.
+type candidate struct {
+	creature  domain.Creature
+	rule      TargetRule
+	distance  int
+	reachable bool
+}
+
+func newTargeting() *targeting {
+	t := &targeting{fightMode: domain.FightBalanced, chaseMode: domain.ChaseStand, secureMode: true}
+	t.setRules([]TargetRule{
+		{ID: "any", Enabled: true, Name: TargetAnyMonster, Stance: StanceChase},
+	})
+	return t
+}
+
+// setRules replaces the whole list, rules without an id get one.
+func (t *targeting) setRules(rules []TargetRule) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sorted := make([]TargetRule, len(rules))
+	copy(sorted, rules)
+	for i := range sorted {
+		if sorted[i].ID == "" {
+			t.nextId++
+			sorted[i].ID = fmt.Sprintf("target-%d", t.nextId)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	t.rules = sorted
+}
+
+func (t *targeting) snapshot() (bool, []TargetRule, uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rules := make([]TargetRule, len(t.rules))
+	copy(rules, t.rules)
+	return t.enabled, rules, t.targetID
+}
+
+func (t *targeting) toggle() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.enabled = !t.enabled
+	t.targetID = 0
+	t.unreachable = 0
+}
+
+// setEnabled is toggle for callers that know the state they want, a toggle racing with the dashboard could switch the module back on.
+func (t *targeting) setEnabled(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.enabled == enabled {
+		return
+	}
+	t.enabled = enabled
+	t.targetID = 0
+	t.unreachable = 0
+}
+
+// attacking reports whether a target is being fought, other modules that walk wait for it.
+func (t *targeting) attacking() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.enabled && t.targetID != 0
+}
+
+// clientFightModes remembers the modes the client sent, so switching the chase mode keeps the others.
+func (t *targeting) clientFightModes(p *packets.SetFightModesRequest) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.fightMode = p.FightMode
+	t.chaseMode = p.ChaseMode
+	t.secureMode = p.SecureMode
+}
+
+// clientTarget follows an attack or follow request of the client. The server attacks what the player
+// clicked, following stops the attack.
+func (t *targeting) clientTarget(creatureId uint32, follow bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if follow {
+		creatureId = 0
+	}
+	t.targetID = creatureId
+	t.unreachable = 0
+}
+
+// ruleFor returns the highest priority rule matching the monster.
+func (t *targeting) ruleFor(c domain.Creature, player domain.Position) (TargetRule, bool) {
+	for _, rule := range t.rules {
+		if rule.matches(c, player) {
+			return rule, true
+		}
+	}
+	return TargetRule{}, false
+}
+
+// candidates lists the living monsters on screen some rule wants attacked, the best one first.
+func (t *targeting) candidates(frame state.WorldSnapshot) []candidate {
+	player := frame.Player.Pos
+
+	var result []candidate
+	for _, c := range frame.CreaturesOnScreen() {
+		if !c.IsMonster() || c.HealthPercent == 0 {
+			continue
+		}
+		rule, ok := t.ruleFor(c, player)
+		if !ok {
+			continue
+		}
+		_, err := pathfinding.FindPath(frame, player, c.Pos, pathfinding.Options{Distance: 1})
+		result = append(result, candidate{
+			creature:  c,
+			rule:      rule,
+			distance:  player.DistanceTo(c.Pos),
+			reachable: err == nil,
+		})
+	}
+	slices.SortFunc(result, func(a, b candidate) int {
+		return cmp.Or(
+			cmp.Compare(b.rule.Priority, a.rule.Priority),
+			cmp.Compare(a.distance, b.distance),
+			cmp.Compare(a.creature.HealthPercent, b.creature.HealthPercent),
+			cmp.Compare(a.creature.ID, b.creature.ID),
+		)
+	})
+	return result
+}
+
+func (b *Bot) loopTargeting() {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stopChan:
+			return
+
+		case <-ticker.C:
+			b.targetingTick(b.state.CaptureFrame())
+		}
+	}
+}
+
+func (b *Bot) targetingTick(frame state.WorldSnapshot) {
+	what, act := b.targetingStep(frame)
+	if act == nil {
+		return
+	}
+	if err := act(); err != nil {
+		log.Printf("[Bot][Targeting] %s: %v", what, err)
+	}
+}
+
+// targetingStep picks the target and decides what to send for it, with the targeting lock held.
+func (b *Bot) targetingStep(frame state.WorldSnapshot) (string, action) {
+	t := b.targeting
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The server gave up the attack, e.g. the target went out of reach or to another floor.
+	if frame.Player.TargetCancels != t.cancels {
+		t.cancels = frame.Player.TargetCancels
+		t.targetID = 0
+	}
+	if !t.enabled || frame.Player.ID == 0 {
+		return "", nil
+	}
+
+	candidates := t.candidates(frame)
+	best := slices.IndexFunc(candidates, func(c candidate) bool { return c.reachable })
+	current := slices.IndexFunc(candidates, func(c candidate) bool { return c.creature.ID == t.targetID })
+
+	if current >= 0 {
+		target := candidates[current]
+		if target.reachable {
+			t.unreachable = 0
+		} else {
+			t.unreachable++
+		}
+		better := best >= 0 && candidates[best].rule.Priority > target.rule.Priority
+		if !better && t.unreachable < targetingUnreachableTicks {
+			act, err := b.holdStance(frame, target)
+			if err != nil {
+				log.Printf("[Bot][Targeting] %s: %v", target.creature.Name, err)
+			}
+			return target.creature.Name, act
+		}
+	}
+
+	// The target died, left the screen, became unreachable or something more important showed up.
+	if best < 0 {
+		if t.targetID == 0 {
+			return "", nil
+		}
+		log.Printf("[Bot][Targeting] No target left")
+		t.targetID = 0
+		return "Cancelling the attack", b.request(&packets.AttackRequest{}, protocol.PriorityNormal)
+	}
+	return "Attacking " + candidates[best].creature.Name, b.attack(candidates[best])
+}
+
+// attack switches to the target, the chase mode follows the stance of its rule.
+// It is called with the targeting lock held.
+func (b *Bot) attack(target candidate) action {
+	t := b.targeting
+	log.Printf("[Bot][Targeting] Attacking %s (%d%%) at %v", target.creature.Name, target.creature.HealthPercent, target.creature.Pos)
+	t.targetID = target.creature.ID
+	t.unreachable = 0
+
+	// A follow request would cancel the attack, the server chases the target through the chase mode instead.
+	var modes *packets.SetFightModesRequest
+	if chase := target.rule.chaseMode(); chase != t.chaseMode {
+		modes = &packets.SetFightModesRequest{FightMode: t.fightMode, ChaseMode: chase, SecureMode: t.secureMode}
+		t.chaseMode = chase
+	}
+	return func() error {
+		if modes != nil {
+			if err := b.sendToServer(modes, protocol.PriorityNormal); err != nil {
+				return err
+			}
+		}
+		return b.sendToServer(&packets.AttackRequest{CreatureID: target.creature.ID}, protocol.PriorityNormal)
+	}
+}
+
+// holdStance walks for the stances the server does not handle itself.
+func (b *Bot) holdStance(frame state.WorldSnapshot, target candidate) (action, error) {
+	if target.rule.Stance != StanceKeepDistance {
+		return nil, nil
+	}
+	pos := frame.Player.Pos
+	want := max(target.rule.Distance, 1)
+
+	switch {
+	case target.distance < want:
+		// Back off to the neighbour furthest from the target.
+		away, bestDistance := domain.Direction(0), target.distance
+		found := false
+		for d := domain.North; d <= domain.NorthEast; d++ {
+			next := pos.Translate(d)
+			if dist := next.DistanceTo(target.creature.Pos); dist > bestDistance && pathfinding.IsWalkable(frame, next) {
+				away, bestDistance, found = d, dist, true
+			}
+		}
+		if !found {
+			return nil, nil // Cornered, keep shooting
+		}
+		return b.request(&packets.WalkRequest{Direction: away}, protocol.PriorityNormal), nil
+
+	case target.distance > want:
+		path, err := pathfinding.FindPath(frame, pos, target.creature.Pos, pathfinding.Options{Distance: want})
+		if err != nil {
+			return nil, fmt.Errorf("walking from %v to %v: %w", pos, target.creature.Pos, err)
+		}
+		if len(path) == 0 {
+			return nil, nil
+		}
+		return b.request(&packets.WalkRequest{Direction: path[0]}, protocol.PriorityNormal), nil
+	}
+	return nil, nil
+}
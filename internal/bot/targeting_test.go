@@ -0,0 +1,269 @@
+package bot
+
+import (
+	"testing"
+	"time"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+	"z07/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func monster(frame state.WorldSnapshot, id uint32, name string, hp uint8, dx, dy int) {
+	pos := frame.Player.Pos
+	frame.Creatures[id] = domain.Creature{
+		ID:            id,
+		Name:          name,
+		Pos:           domain.Position{X: uint16(int(pos.X) + dx), Y: uint16(int(pos.Y) + dy), Z: pos.Z},
+		HealthPercent: hp,
+		Visible:       true,
+	}
+}
+
+func TestTargetRule_Matches(t *testing.T) {
+	player := domain.Position{X: 100, Y: 100, Z: 7}
+	rat := domain.Creature{Name: "Rat", Pos: domain.Position{X: 103, Y: 100, Z: 7}, HealthPercent: 60}
+
+	require.True(t, TargetRule{Enabled: true, Name: "rat"}.matches(rat, player), "Names match in any case")
+	require.True(t, TargetRule{Enabled: true, Name: TargetAnyMonster}.matches(rat, player))
+	require.False(t, TargetRule{Name: "Rat"}.matches(rat, player), "Disabled")
+	require.False(t, TargetRule{Enabled: true, Name: "Troll"}.matches(rat, player))
+	require.False(t, TargetRule{Enabled: true, Name: "Rat", MinHp: 70}.matches(rat, player))
+	require.False(t, TargetRule{Enabled: true, Name: "Rat", MaxHp: 50}.matches(rat, player))
+	require.False(t, TargetRule{Enabled: true, Name: "Rat", MaxDistance: 2}.matches(rat, player))
+	require.True(t, TargetRule{Enabled: true, Name: "Rat", MinHp: 60, MaxHp: 60, MaxDistance: 3}.matches(rat, player))
+}
+
+func TestTargeting_Tick(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn, targeting: newTargeting()}
+	b.targeting.setRules([]TargetRule{
+		{Enabled: true, Name: "Rat", Priority: 0, Stance: StanceStand},
+		{Enabled: true, Name: "Dragon", Priority: 5, Stance: StanceChase},
+	})
+	b.targeting.clientFightModes(&packets.SetFightModesRequest{FightMode: domain.FightOffensive, ChaseMode: domain.ChaseStand, SecureMode: true})
+	b.targeting.toggle()
+
+	frame := groundFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	monster(frame, 0x40000001, "Rat", 100, 2, 0)
+	monster(frame, 0x40000002, "Rat", 100, 1, 1)
+	monster(frame, 0x40000003, "Bug", 100, 1, 0)
+	frame.Creatures[0x10000002] = domain.Creature{ID: 0x10000002, Name: "Rat", Pos: domain.Position{X: 99, Y: 100, Z: 7}, HealthPercent: 100, Visible: true}
+
+	t.Run("Closest matching monster", func(t *testing.T) {
+		b.targetingTick(frame)
+		require.Equal(t, []any{&packets.AttackRequest{CreatureID: 0x40000002}}, sentSince(conn, 0))
+		require.True(t, b.targeting.attacking())
+	})
+
+	t.Run("Target is kept while it is fought", func(t *testing.T) {
+		n := len(conn.sent)
+		monster(frame, 0x40000004, "Rat", 100, 0, 1)
+		b.targetingTick(frame)
+		require.Empty(t, sentSince(conn, n))
+	})
+
+	t.Run("Higher priority takes over and is chased", func(t *testing.T) {
+		n := len(conn.sent)
+		monster(frame, 0x40000005, "Dragon", 100, 3, 3)
+		b.targetingTick(frame)
+		require.Equal(t, []any{
+			&packets.SetFightModesRequest{FightMode: domain.FightOffensive, ChaseMode: domain.ChaseFollow, SecureMode: true},
+			&packets.AttackRequest{CreatureID: 0x40000005},
+		}, sentSince(conn, n))
+	})
+
+	t.Run("Dead target is replaced", func(t *testing.T) {
+		n := len(conn.sent)
+		dragon := frame.Creatures[0x40000005]
+		dragon.Visible = false
+		frame.Creatures[0x40000005] = dragon
+		b.targetingTick(frame)
+		require.Equal(t, []any{
+			&packets.SetFightModesRequest{FightMode: domain.FightOffensive, ChaseMode: domain.ChaseStand, SecureMode: true},
+			&packets.AttackRequest{CreatureID: 0x40000002},
+		}, sentSince(conn, n))
+	})
+
+	t.Run("Attack is cancelled when nothing is left", func(t *testing.T) {
+		n := len(conn.sent)
+		b.targetingTick(groundFrame(frame.Player.Pos))
+		require.Equal(t, []any{&packets.AttackRequest{}}, sentSince(conn, n))
+		require.False(t, b.targeting.attacking())
+	})
+}
+
+func TestTargeting_Unreachable(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn, targeting: newTargeting()}
+	b.targeting.setRules([]TargetRule{{Enabled: true, Name: TargetAnyMonster, Stance: StanceStand}})
+	b.targeting.toggle()
+
+	player := domain.Position{X: 100, Y: 100, Z: 7}
+	frame := groundFrame(player)
+	monster(frame, 0x40000001, "Rat", 100, 2, 0)
+	monster(frame, 0x40000002, "Rat", 100, -3, 0)
+	b.targetingTick(frame)
+	require.Equal(t, uint32(0x40000001), b.targeting.targetID)
+
+	// The rat is walled in, the other one can still be reached.
+	frame = groundFrame(player)
+	monster(frame, 0x40000001, "Rat", 100, 3, 0)
+	monster(frame, 0x40000002, "Rat", 100, -3, 0)
+	for y := -3; y <= 3; y++ {
+		frame.WorldMap[domain.Position{X: 102, Y: uint16(100 + y), Z: 7}].Things = nil
+	}
+	for range targetingUnreachableTicks - 1 {
+		b.targetingTick(frame)
+		require.Equal(t, uint32(0x40000001), b.targeting.targetID, "A blocked path may clear up again")
+	}
+	b.targetingTick(frame)
+	require.Equal(t, uint32(0x40000002), b.targeting.targetID)
+	require.Equal(t, &packets.AttackRequest{CreatureID: 0x40000002}, conn.sent[len(conn.sent)-1])
+}
+
+func TestTargeting_ServerCancelsTarget(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn, targeting: newTargeting()}
+	b.targeting.setRules([]TargetRule{{Enabled: true, Name: TargetAnyMonster, Stance: StanceStand}})
+	b.targeting.toggle()
+
+	frame := groundFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	monster(frame, 0x40000001, "Rat", 100, 2, 0)
+	b.targetingTick(frame)
+	n := len(conn.sent)
+
+	frame.Player.TargetCancels++
+	b.targetingTick(frame)
+	require.Equal(t, []any{&packets.AttackRequest{CreatureID: 0x40000001}}, sentSince(conn, n), "The attack is sent again")
+
+	n = len(conn.sent)
+	b.targetingTick(frame)
+	require.Empty(t, sentSince(conn, n))
+}
+
+func TestTargeting_ClientTarget(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn, targeting: newTargeting()}
+	b.targeting.setRules([]TargetRule{{Enabled: true, Name: TargetAnyMonster, Stance: StanceStand}})
+	b.targeting.toggle()
+
+	frame := groundFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	monster(frame, 0x40000001, "Rat", 100, 1, 0)
+	monster(frame, 0x40000002, "Rat", 100, 3, 0)
+	b.targetingTick(frame)
+
+	request := func(pkt protocol.Encodable) {
+		pw := protocol.NewPacketWriter()
+		pkt.Encode(pw)
+		data, err := pw.GetBytes()
+		require.NoError(t, err)
+		_, err = b.InterceptC2SPacket(data)
+		require.NoError(t, err)
+	}
+
+	t.Run("Attack from the client", func(t *testing.T) {
+		request(&packets.AttackRequest{CreatureID: 0x40000002})
+		n := len(conn.sent)
+		b.targetingTick(frame)
+		require.Empty(t, sentSince(conn, n), "The rat the player clicked is kept")
+		require.Equal(t, uint32(0x40000002), b.targeting.targetID)
+	})
+
+	t.Run("Follow from the client", func(t *testing.T) {
+		request(&packets.FollowRequest{CreatureID: 0x40000002})
+		n := len(conn.sent)
+		b.targetingTick(frame)
+		require.Equal(t, []any{&packets.AttackRequest{CreatureID: 0x40000001}}, sentSince(conn, n), "Following stopped the attack")
+	})
+}
+
+func TestTargeting_SendsWithoutItsLock(t *testing.T) {
+	conn := newBlockingConn()
+	b := &Bot{serverConn: conn, targeting: newTargeting()}
+	b.targeting.toggle()
+
+	frame := groundFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	monster(frame, 0x40000001, "Rat", 100, 1, 0)
+	done := make(chan struct{})
+	go func() {
+		b.targetingTick(frame)
+		close(done)
+	}()
+	<-conn.injecting
+
+	// The chase mode waits for the outbox, the cavebot, the dashboard and client requests do not.
+	require.True(t, b.targeting.attacking())
+	_, _, target := b.targeting.snapshot()
+	require.Equal(t, uint32(0x40000001), target)
+	b.targeting.clientTarget(0x40000001, false)
+
+	conn.release <- struct{}{}
+	<-conn.injecting
+	close(conn.release)
+	<-done
+	require.Equal(t, []any{
+		&packets.SetFightModesRequest{FightMode: domain.FightBalanced, ChaseMode: domain.ChaseFollow, SecureMode: true},
+		&packets.AttackRequest{CreatureID: 0x40000001},
+	}, sentSince(&conn.recordingConn, 0))
+}
+
+func TestTargeting_KeepDistance(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn, targeting: newTargeting()}
+	b.targeting.setRules([]TargetRule{{Enabled: true, Name: TargetAnyMonster, Stance: StanceKeepDistance, Distance: 3}})
+	b.targeting.toggle()
+
+	player := domain.Position{X: 100, Y: 100, Z: 7}
+	frame := groundFrame(player)
+	monster(frame, 0x40000001, "Rat", 100, 1, 0)
+	b.targetingTick(frame)
+	require.Equal(t, &packets.AttackRequest{CreatureID: 0x40000001}, conn.sent[len(conn.sent)-1])
+
+	t.Run("Too close", func(t *testing.T) {
+		b.targetingTick(frame)
+		walk, ok := conn.sent[len(conn.sent)-1].(*packets.WalkRequest)
+		require.True(t, ok)
+		require.Equal(t, 2, player.Translate(walk.Direction).DistanceTo(frame.Creatures[0x40000001].Pos))
+	})
+
+	t.Run("Too far", func(t *testing.T) {
+		frame := groundFrame(player)
+		monster(frame, 0x40000001, "Rat", 100, 0, -3)
+		b.targetingTick(frame)
+		n := len(conn.sent)
+		b.targetingTick(frame)
+		require.Empty(t, sentSince(conn, n), "At the right distance")
+
+		frame = groundFrame(player)
+		monster(frame, 0x40000001, "Rat", 100, 0, 4)
+		frame.WorldMap[domain.Position{X: 100, Y: 104, Z: 7}] = state.NewTile(domain.Position{X: 100, Y: 104, Z: 7}, domain.Item{ID: 100})
+		b.targetingTick(frame)
+		require.Equal(t, &packets.WalkRequest{Direction: domain.South}, conn.sent[len(conn.sent)-1])
+	})
+}
+
+func TestCavebot_WaitsForTargeting(t *testing.T) {
+	conn := &recordingConn{}
+	b := &Bot{serverConn: conn, cavebot: newCavebot(), targeting: newTargeting()}
+	b.cavebot.add(Waypoint{Type: WaypointWalk, X: 103, Y: 100, Z: 7})
+	b.cavebot.toggle()
+	b.targeting.toggle()
+
+	frame := groundFrame(domain.Position{X: 100, Y: 100, Z: 7})
+	monster(frame, 0x40000001, "Rat", 100, 0, 2)
+	b.targetingTick(frame)
+	n := len(conn.sent)
+	b.cavebotTick(frame, time.Now())
+	require.Empty(t, sentSince(conn, n))
+}
+
+func sentSince(conn *recordingConn, n int) []any {
+	var sent []any
+	for _, pkt := range conn.sent[n:] {
+		sent = append(sent, pkt)
+	}
+	return sent
+}
@@ -2,12 +2,20 @@ package bot
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"time"
+	"z07/internal/game/domain"
+	"z07/internal/game/state"
+	"z07/internal/rules"
 
 	"github.com/gorilla/websocket"
 )
 
+// chatBacklog is how many past messages a freshly opened page receives.
+const chatBacklog = 500
+
+// The dashboard server checks the origin before the request gets here, see dashboard.Server.
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
@@ -21,15 +29,59 @@ type BotSnapshot struct {
 	X                uint16     `json:"x"`
 	Y                uint16     `json:"y"`
 	Z                uint8      `json:"z"`
+	CavebotEnabled   bool       `json:"cavebotEnabled"`
 	Waypoints        []Waypoint `json:"waypoints"`
+	HealerEnabled    bool       `json:"healerEnabled"`
+	HealerRules      []HealRule `json:"healerRules"`
+	Capturing        bool       `json:"capturing"`
+	// Packet rules are shared by every session, RulesError tells why the last edit was rejected.
+	Rules      []rules.Status `json:"rules"`
+	RulesError string         `json:"rulesError,omitempty"`
+
+	TargetingEnabled bool         `json:"targetingEnabled"`
+	TargetRules      []TargetRule `json:"targetRules"`
+	Target           string       `json:"target,omitempty"` // Name of the monster being attacked
+
+	LooterEnabled bool         `json:"looterEnabled"`
+	Loot          LootSettings `json:"loot"`
+	LootQueue     int          `json:"lootQueue"` // Corpses waiting to be looted
+
+	Hp                uint16          `json:"hp"`
+	MaxHp             uint16          `json:"maxHp"`
+	Mana              uint16          `json:"mana"`
+	MaxMana           uint16          `json:"maxMana"`
+	Capacity          uint16          `json:"capacity"`
+	Experience        uint32          `json:"experience"`
+	Level             uint16          `json:"level"`
+	LevelPercent      uint8           `json:"levelPercent"`
+	MagicLevel        uint8           `json:"magicLevel"`
+	MagicLevelPercent uint8           `json:"magicLevelPercent"`
+	Soul              uint8           `json:"soul"`
+	Skills            []SkillSnapshot `json:"skills"`
+	Conditions        []string        `json:"conditions"`
+
+	AlarmsEnabled bool        `json:"alarmsEnabled"`
+	AlarmRules    []AlarmRule `json:"alarmRules"`
+
+	// Chat and Alarms only carry what the browser has not seen yet.
+	Chat   []ChatEntry `json:"chat,omitempty"`
+	Alarms []Alarm     `json:"alarms,omitempty"`
 }
 
-type Waypoint struct {
-	ID   string `json:"id"` // Required for DND reordering
-	Type string `json:"type"`
-	X    int    `json:"x"`
-	Y    int    `json:"y"`
-	Z    int    `json:"z"`
+type ChatEntry struct {
+	Seq       uint64 `json:"seq"`
+	Time      int64  `json:"time"` // Unix milliseconds
+	Author    string `json:"author"`
+	Level     uint16 `json:"level,omitempty"`
+	Kind      string `json:"kind"` // Speak class name, "server" or "loot"
+	ChannelID uint16 `json:"channelId,omitempty"`
+	Text      string `json:"text"`
+}
+
+type SkillSnapshot struct {
+	Name    string `json:"name"`
+	Level   uint8  `json:"level"`
+	Percent uint8  `json:"percent"`
 }
 
 func (b *Bot) HandleWS(w http.ResponseWriter, r *http.Request) {
@@ -55,20 +107,7 @@ func (b *Bot) HandleWS(w http.ResponseWriter, r *http.Request) {
 				Data json.RawMessage `json:"data"`
 			}
 			if err := json.Unmarshal(message, &cmd); err == nil {
-				if cmd.Type == "TOGGLE_FISHING" {
-					b.fishingEnabled = !b.fishingEnabled
-				} else if cmd.Type == "SET_LIGHTHACK" {
-					var data struct {
-						Enabled bool  `json:"enabled"`
-						Level   uint8 `json:"level"`
-						Color   uint8 `json:"color"`
-					}
-					if err := json.Unmarshal(cmd.Data, &data); err == nil {
-						b.lighthackEnabled = data.Enabled
-						b.lighthackLevel = data.Level
-						b.lighthackColor = data.Color
-					}
-				}
+				b.handleCommand(cmd.Type, cmd.Data)
 			}
 		}
 	}()
@@ -77,36 +116,225 @@ func (b *Bot) HandleWS(w http.ResponseWriter, r *http.Request) {
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
+	// A new page gets the recent history once, then only what was added since.
+	chatQuery := state.ChatQuery{Limit: chatBacklog}
+	var alarmSeq uint64
+
+	send := func() error {
+		snap := b.snapshot(chatQuery, alarmSeq)
+		if n := len(snap.Chat); n > 0 {
+			chatQuery = state.ChatQuery{AfterSeq: snap.Chat[n-1].Seq}
+		}
+		if n := len(snap.Alarms); n > 0 {
+			alarmSeq = snap.Alarms[n-1].Seq
+		}
+		// We use WriteJSON directly to simplify the code
+		return conn.WriteJSON(snap)
+	}
+
 	for {
 		select {
-		// EXIT if the Bot is stopped via Stop()
+		// EXIT if the Bot is stopped via Stop(), the last update carries the disconnect alarm.
 		case <-b.stopChan:
+			send()
 			return
 
 		// EXECUTE update every tick
 		case <-ticker.C:
-			snap := BotSnapshot{
-				FishingEnabled:   b.fishingEnabled,
-				LighthackEnabled: b.lighthackEnabled,
-				LighthackLevel:   b.lighthackLevel,
-				LighthackColor:   b.lighthackColor,
-				Name:             b.state.CaptureFrame().Player.Name,
-				X:                b.state.CaptureFrame().Player.Pos.X,
-				Y:                b.state.CaptureFrame().Player.Pos.Y,
-				Z:                b.state.CaptureFrame().Player.Pos.Z,
-				Waypoints: []Waypoint{
-					{ID: "wp-1", Type: "Walk", X: 32345, Y: 32222, Z: 7},
-					{ID: "wp-2", Type: "Walk", X: 32350, Y: 32230, Z: 7},
-					{ID: "wp-3", Type: "Rope", X: 32350, Y: 32230, Z: 7},
-					{ID: "wp-4", Type: "Walk", X: 32352, Y: 32235, Z: 6},
-				},
-			}
-
-			// We use WriteJSON directly to simplify the code
-			if err := conn.WriteJSON(snap); err != nil {
+			if err := send(); err != nil {
 				// If the browser tab is closed, this will error out and exit the loop
 				return
 			}
 		}
 	}
 }
+
+// snapshot is what the dashboard shows, with the chat and the alarms the query and seq have not seen.
+func (b *Bot) snapshot(chatQuery state.ChatQuery, alarmSeq uint64) BotSnapshot {
+	frame := b.state.CaptureFrame()
+	player := frame.Player
+	healerEnabled, healerRules := b.healer.snapshot()
+	cavebotEnabled, waypoints := b.cavebot.snapshot()
+	targetingEnabled, targetRules, targetId := b.targeting.snapshot()
+	looterEnabled, loot, lootQueue := b.looter.snapshot()
+	alarmsEnabled, alarmRules := b.alarms.snapshot()
+	b.mu.Lock()
+	fishingEnabled, lighthackEnabled := b.fishingEnabled, b.lighthackEnabled
+	lighthackLevel, lighthackColor := b.lighthackLevel, b.lighthackColor
+	b.mu.Unlock()
+	snap := BotSnapshot{
+		FishingEnabled:   fishingEnabled,
+		LighthackEnabled: lighthackEnabled,
+		LighthackLevel:   lighthackLevel,
+		LighthackColor:   lighthackColor,
+		Name:             player.Name,
+		X:                player.Pos.X,
+		Y:                player.Pos.Y,
+		Z:                player.Pos.Z,
+		HealerEnabled:    healerEnabled,
+		HealerRules:      healerRules,
+		Capturing:        b.capture.Enabled(),
+		Rules:            b.rules.Rules(),
+
+		Hp:                player.Stats.Health,
+		MaxHp:             player.Stats.MaxHealth,
+		Mana:              player.Stats.Mana,
+		MaxMana:           player.Stats.MaxMana,
+		Capacity:          player.Stats.Capacity,
+		Experience:        player.Stats.Experience,
+		Level:             player.Stats.Level,
+		LevelPercent:      player.Stats.LevelPercent,
+		MagicLevel:        player.Stats.MagicLevel,
+		MagicLevelPercent: player.Stats.MagicLevelPercent,
+		Soul:              player.Stats.Soul,
+		Skills:            skillSnapshots(player),
+		Conditions:        player.Icons.Names(),
+
+		CavebotEnabled: cavebotEnabled,
+		Waypoints:      waypoints,
+
+		TargetingEnabled: targetingEnabled,
+		TargetRules:      targetRules,
+		Target:           frame.Creatures[targetId].Name,
+
+		LooterEnabled: looterEnabled,
+		Loot:          loot,
+		LootQueue:     lootQueue,
+
+		AlarmsEnabled: alarmsEnabled,
+		AlarmRules:    alarmRules,
+
+		Chat:   chatEntries(b.state.ChatHistory(chatQuery)),
+		Alarms: b.alarms.since(alarmSeq),
+	}
+	if err := b.rules.Err(); err != nil {
+		snap.RulesError = err.Error()
+	}
+	return snap
+}
+
+func (b *Bot) handleCommand(cmdType string, data json.RawMessage) {
+	switch cmdType {
+	case "TOGGLE_FISHING":
+		b.mu.Lock()
+		b.fishingEnabled = !b.fishingEnabled
+		b.mu.Unlock()
+	case "SET_LIGHTHACK":
+		var lighthack struct {
+			Enabled bool  `json:"enabled"`
+			Level   uint8 `json:"level"`
+			Color   uint8 `json:"color"`
+		}
+		if err := json.Unmarshal(data, &lighthack); err == nil {
+			b.mu.Lock()
+			b.lighthackEnabled = lighthack.Enabled
+			b.lighthackLevel = lighthack.Level
+			b.lighthackColor = lighthack.Color
+			b.mu.Unlock()
+		}
+	case "TOGGLE_HEALER":
+		b.healer.toggle()
+	case "SET_HEALER_RULES":
+		var rules []HealRule
+		if err := json.Unmarshal(data, &rules); err == nil {
+			b.healer.setRules(rules)
+		}
+	case "SET_RULES":
+		var list []rules.Rule
+		if err := json.Unmarshal(data, &list); err == nil {
+			if err := b.rules.Set(list); err != nil {
+				log.Printf("[Bot] Rejected packet rules: %v", err)
+			}
+		}
+	case "TOGGLE_CAPTURE":
+		b.capture.SetEnabled(!b.capture.Enabled())
+	case "TOGGLE_TARGETING":
+		b.targeting.toggle()
+	case "SET_TARGET_RULES":
+		var rules []TargetRule
+		if err := json.Unmarshal(data, &rules); err == nil {
+			b.targeting.setRules(rules)
+		}
+	case "TOGGLE_LOOTER":
+		b.looter.toggle()
+	case "SET_LOOT":
+		var loot LootSettings
+		if err := json.Unmarshal(data, &loot); err == nil {
+			b.looter.setSettings(loot)
+		}
+	case "TOGGLE_ALARMS":
+		b.alarms.toggle()
+	case "SET_ALARM_RULES":
+		var rules []AlarmRule
+		if err := json.Unmarshal(data, &rules); err == nil {
+			if err := b.alarms.setRules(rules); err != nil {
+				log.Printf("[Bot] Rejected alarm rules: %v", err)
+			}
+		}
+	case "TOGGLE_CAVEBOT":
+		b.cavebot.toggle()
+	case "ADD_WAYPOINT":
+		var wp struct {
+			Type string  `json:"type"`
+			X    *uint16 `json:"x"`
+			Y    *uint16 `json:"y"`
+			Z    *uint8  `json:"z"`
+			Text string  `json:"text"`
+		}
+		if err := json.Unmarshal(data, &wp); err == nil {
+			// Without coordinates the waypoint is placed where the player stands.
+			pos := b.state.CaptureFrame().Player.Pos
+			if wp.X != nil && wp.Y != nil && wp.Z != nil {
+				pos = domain.Position{X: *wp.X, Y: *wp.Y, Z: *wp.Z}
+			}
+			b.cavebot.add(Waypoint{Type: wp.Type, X: pos.X, Y: pos.Y, Z: pos.Z, Text: wp.Text})
+		}
+	case "REMOVE_WAYPOINT":
+		var wp struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(data, &wp); err == nil {
+			b.cavebot.remove(wp.ID)
+		}
+	case "REORDER_WAYPOINTS", "LOAD_WAYPOINTS":
+		var waypoints []Waypoint
+		if err := json.Unmarshal(data, &waypoints); err == nil {
+			b.cavebot.load(waypoints)
+		}
+	}
+}
+
+func chatEntries(messages []domain.ChatMessage) []ChatEntry {
+	entries := make([]ChatEntry, 0, len(messages))
+	for _, m := range messages {
+		kind := m.Class.String()
+		switch {
+		case m.IsLoot():
+			kind = "loot"
+		case m.IsServerMessage():
+			kind = "server"
+		}
+		entries = append(entries, ChatEntry{
+			Seq:       m.Seq,
+			Time:      m.Time.UnixMilli(),
+			Author:    m.Author,
+			Level:     m.Level,
+			Kind:      kind,
+			ChannelID: m.ChannelID,
+			Text:      m.Text,
+		})
+	}
+	return entries
+}
+
+func skillSnapshots(player domain.Player) []SkillSnapshot {
+	skills := make([]SkillSnapshot, 0, len(player.Skills))
+	for i, skill := range player.Skills {
+		skills = append(skills, SkillSnapshot{
+			Name:    domain.SkillType(i).String(),
+			Level:   skill.Level,
+			Percent: skill.Percent,
+		})
+	}
+	return skills
+}
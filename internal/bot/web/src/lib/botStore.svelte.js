@@ -1,11 +1,44 @@
 // src/lib/botStore.svelte.js
 import { socket } from './socket.js'; // We'll move socket logic here
 
+const CHAT_LIMIT = 2000;
+const ALARM_LIMIT = 100;
+
+// A short two-tone beep, the dashboard needs no sound files for it
+function beep() {
+    const ctx = new AudioContext();
+    [880, 660].forEach((freq, i) => {
+        const osc = ctx.createOscillator();
+        const gain = ctx.createGain();
+        osc.frequency.value = freq;
+        gain.gain.value = 0.2;
+        osc.connect(gain).connect(ctx.destination);
+        osc.start(ctx.currentTime + i * 0.25);
+        osc.stop(ctx.currentTime + i * 0.25 + 0.2);
+    });
+    setTimeout(() => ctx.close(), 1000);
+}
+
 class BotStore {
+    // Logged in characters, and the one this page shows ("" follows the latest)
+    sessions = $state([]);
+    sessionId = $state("");
+
     // These are reactive properties ($state)
     name = $state("Connecting...");
     hp = $state(0);
+    maxHp = $state(0);
     mana = $state(0);
+    maxMana = $state(0);
+    capacity = $state(0);
+    experience = $state(0);
+    level = $state(0);
+    levelPercent = $state(0);
+    magicLevel = $state(0);
+    magicLevelPercent = $state(0);
+    soul = $state(0);
+    skills = $state([]);
+    conditions = $state([]);
     x = $state(0);
     y = $state(0);
     z = $state(0);
@@ -13,17 +46,65 @@ class BotStore {
     lighthackEnabled = $state(false);
     lighthackLevel = $state(15);
     lighthackColor = $state(0xD7);
+    healerEnabled = $state(false);
+    healerRules = $state([]);
+
+    // Targeting
+    targetingEnabled = $state(false);
+    targetRules = $state([]);
+    target = $state("");
 
-    // Waypoint list
+    // Looter
+    looterEnabled = $state(false);
+    loot = $state({ items: [], minCapacity: 0 });
+    lootQueue = $state(0);
+
+    // Alarms, the ones fired since the page opened beep
+    alarmsEnabled = $state(false);
+    alarmRules = $state([]);
+    alarms = $state([]);
+    alarmsPrimed = false;
+
+    // Cavebot
+    cavebotEnabled = $state(false);
     waypoints = $state([]);
 
+    // Chat history, streamed in increments
+    chat = $state([]);
+
+    // Packet capture of this session
+    capturing = $state(false);
+
+    // Packet rules, shared by every session
+    rules = $state([]);
+    rulesError = $state("");
+
     isDraggingWaypoint = false;
 
+    // Called for every new connection, the chat history is sent again from the start
+    reset() {
+        this.name = "Connecting...";
+        this.chat = [];
+        this.alarms = [];
+        this.alarmsPrimed = false;
+    }
+
     // Methods to update state
     updateFromSnapshot(data) {
         this.name = data.name;
         this.hp = data.hp;
+        this.maxHp = data.maxHp;
         this.mana = data.mana;
+        this.maxMana = data.maxMana;
+        this.capacity = data.capacity;
+        this.experience = data.experience;
+        this.level = data.level;
+        this.levelPercent = data.levelPercent;
+        this.magicLevel = data.magicLevel;
+        this.magicLevelPercent = data.magicLevelPercent;
+        this.soul = data.soul;
+        this.skills = data.skills ?? [];
+        this.conditions = data.conditions ?? [];
         this.x = data.x;
         this.y = data.y;
         this.z = data.z;
@@ -34,9 +115,42 @@ class BotStore {
         this.lighthackLevel = data.lighthackLevel;
         this.lighthackColor = data.lighthackColor;
 
+        this.healerEnabled = data.healerEnabled;
+        this.healerRules = data.healerRules ?? [];
+
+        this.targetingEnabled = data.targetingEnabled;
+        this.targetRules = data.targetRules ?? [];
+        this.target = data.target ?? "";
+
+        this.looterEnabled = data.looterEnabled;
+        this.loot = { items: data.loot?.items ?? [], minCapacity: data.loot?.minCapacity ?? 0 };
+        this.lootQueue = data.lootQueue ?? 0;
+
+        this.alarmsEnabled = data.alarmsEnabled;
+        this.alarmRules = data.alarmRules ?? [];
+        if (data.alarms?.length) {
+            // The first update replays the backlog, only what fires afterwards beeps
+            if (this.alarmsPrimed) beep();
+            this.alarms = [...this.alarms, ...data.alarms].slice(-ALARM_LIMIT);
+        }
+        this.alarmsPrimed = true;
+
+        this.cavebotEnabled = data.cavebotEnabled;
+
+        this.capturing = data.capturing;
+
+        this.rules = data.rules ?? [];
+        this.rulesError = data.rulesError ?? "";
+
+        if (data.chat?.length) {
+            const lastSeq = this.chat.length ? this.chat[this.chat.length - 1].seq : 0;
+            const fresh = data.chat.filter(m => m.seq > lastSeq);
+            this.chat = [...this.chat, ...fresh].slice(-CHAT_LIMIT);
+        }
+
         // This is needed to prevent breaking the drag-and-drop UI
         if (!this.isDraggingWaypoint) {
-            this.waypoints = data.waypoints;
+            this.waypoints = data.waypoints ?? [];
         }
     }
 
@@ -44,6 +158,10 @@ class BotStore {
         socket.send(JSON.stringify({ type: "TOGGLE_FISHING" }));
     }
 
+    toggleCapture() {
+        socket.send(JSON.stringify({ type: "TOGGLE_CAPTURE" }));
+    }
+
     toggleLighthack = () => {
         this.lighthackEnabled = !this.lighthackEnabled;
         this.sendLighthackUpdate();
@@ -80,6 +198,113 @@ class BotStore {
         }));
     };
 
+    toggleHealer = () => {
+        socket.send(JSON.stringify({ type: "TOGGLE_HEALER" }));
+    };
+
+    updateHealerRule = (id, changes) => {
+        const rules = this.healerRules.map(r => r.id === id ? { ...r, ...changes } : r);
+        this.healerRules = rules;
+        socket.send(JSON.stringify({ type: "SET_HEALER_RULES", data: rules }));
+    };
+
+    // Hits and errors are reported by the Go side, they are not part of a rule
+    setRules = (list) => {
+        const rules = list.map(({ hits, error, ...rule }) => rule);
+        socket.send(JSON.stringify({ type: "SET_RULES", data: rules }));
+    };
+
+    updateRule = (id, changes) => {
+        this.setRules(this.rules.map(r => r.id === id ? { ...r, ...changes } : r));
+    };
+
+    toggleTargeting = () => {
+        socket.send(JSON.stringify({ type: "TOGGLE_TARGETING" }));
+    };
+
+    // The Go side keeps the list sorted by priority
+    setTargetRules = (rules) => {
+        this.targetRules = rules;
+        socket.send(JSON.stringify({ type: "SET_TARGET_RULES", data: rules }));
+    };
+
+    addTargetRule = (name) => {
+        this.setTargetRules([...this.targetRules, {
+            id: `target-${Date.now()}`, enabled: true, name, priority: 0, minHp: 0, maxHp: 0, maxDistance: 0, stance: "chase"
+        }]);
+    };
+
+    updateTargetRule = (id, changes) => {
+        this.setTargetRules(this.targetRules.map(r => r.id === id ? { ...r, ...changes } : r));
+    };
+
+    removeTargetRule = (id) => {
+        this.setTargetRules(this.targetRules.filter(r => r.id !== id));
+    };
+
+    toggleLooter = () => {
+        socket.send(JSON.stringify({ type: "TOGGLE_LOOTER" }));
+    };
+
+    setLoot = (changes) => {
+        this.loot = { ...this.loot, ...changes };
+        socket.send(JSON.stringify({ type: "SET_LOOT", data: this.loot }));
+    };
+
+    addLootItem = (itemId, destination, name = "") => {
+        this.setLoot({ items: [...this.loot.items, { itemId, destination, name }] });
+    };
+
+    removeLootItem = (index) => {
+        this.setLoot({ items: this.loot.items.filter((_, i) => i !== index) });
+    };
+
+    toggleAlarms = () => {
+        socket.send(JSON.stringify({ type: "TOGGLE_ALARMS" }));
+    };
+
+    setAlarmRules = (rules) => {
+        this.alarmRules = rules;
+        socket.send(JSON.stringify({ type: "SET_ALARM_RULES", data: rules }));
+    };
+
+    addAlarmRule = (trigger) => {
+        this.setAlarmRules([...this.alarmRules, {
+            id: `alarm-${Date.now()}`, enabled: true, trigger, below: trigger === "lowHp" ? 30 : 0,
+            pause: [], notify: true, webhook: "", logout: false, cooldownMs: 10000
+        }]);
+    };
+
+    updateAlarmRule = (id, changes) => {
+        this.setAlarmRules(this.alarmRules.map(r => r.id === id ? { ...r, ...changes } : r));
+    };
+
+    removeAlarmRule = (id) => {
+        this.setAlarmRules(this.alarmRules.filter(r => r.id !== id));
+    };
+
+    toggleCavebot = () => {
+        socket.send(JSON.stringify({ type: "TOGGLE_CAVEBOT" }));
+    };
+
+    // Without coordinates the Go side uses the current player position.
+    addWaypoint = (type, text = "") => {
+        socket.send(JSON.stringify({ type: "ADD_WAYPOINT", data: { type, text } }));
+    };
+
+    removeWaypoint = (id) => {
+        this.waypoints = this.waypoints.filter(w => w.id !== id);
+        socket.send(JSON.stringify({ type: "REMOVE_WAYPOINT", data: { id } }));
+    };
+
+    updateWaypoint = (id, changes) => {
+        this.reorderWaypoints(this.waypoints.map(w => w.id === id ? { ...w, ...changes } : w));
+    };
+
+    loadWaypoints = (list) => {
+        socket.send(JSON.stringify({ type: "LOAD_WAYPOINTS", data: list }));
+    };
+
     reorderWaypoints(newList) {
         this.waypoints = newList;
         socket.send(JSON.stringify({
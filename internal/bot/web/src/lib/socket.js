@@ -1,15 +1,49 @@
 // src/lib/socket.js
 import { bot } from './botStore.svelte.js';
 
+export const DASHBOARD = '127.0.0.1:8080';
+
 export let socket;
 
 export function connect() {
-    socket = new WebSocket('ws://127.0.0.1:8080/ws');
+    // Without a session the dashboard follows the latest logged in character
+    const query = bot.sessionId ? `?session=${encodeURIComponent(bot.sessionId)}` : '';
+    const ws = new WebSocket(`ws://${DASHBOARD}/ws${query}`);
+    socket = ws;
+
+    // Every connection starts with the chat backlog, possibly of another session than before
+    ws.onopen = () => bot.reset();
 
-    socket.onmessage = (event) => {
+    ws.onmessage = (event) => {
         const data = JSON.parse(event.data);
         bot.updateFromSnapshot(data);
     };
 
-    socket.onclose = () => setTimeout(connect, 1000);
-}
\ No newline at end of file
+    // Only the current socket reconnects, a replaced one just goes away
+    ws.onclose = () => {
+        if (socket === ws) setTimeout(connect, 1000);
+    };
+}
+
+export function selectSession(id) {
+    bot.sessionId = id;
+
+    const previous = socket;
+    connect();
+    previous?.close();
+}
+
+export async function refreshSessions() {
+    try {
+        const res = await fetch(`http://${DASHBOARD}/sessions`);
+        bot.sessions = await res.json();
+    } catch {
+        bot.sessions = [];
+        return;
+    }
+
+    // The chosen character logged out, follow the latest one again
+    if (bot.sessionId && !bot.sessions.some(s => s.id === bot.sessionId)) {
+        selectSession("");
+    }
+}
@@ -0,0 +1,136 @@
+// Package capture records the decrypted messages of a session to disk, so real sessions can be replayed in tests.
+//
+// A capture file starts with the 4 byte magic "Z07C" and a version byte, followed by records:
+//
+//	direction  u8   (0x01 client to server, 0x02 server to client)
+//	timestamp  i64  (unix microseconds)
+//	length     u16
+//	payload    [length]byte
+//
+// All numbers are little endian, like the Tibia protocol itself.
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+type Direction uint8
+
+const (
+	ClientToServer Direction = 0x01
+	ServerToClient Direction = 0x02
+)
+
+func (d Direction) String() string {
+	switch d {
+	case ClientToServer:
+		return "C2S"
+	case ServerToClient:
+		return "S2C"
+	default:
+		return fmt.Sprintf("Direction(0x%02X)", uint8(d))
+	}
+}
+
+const (
+	magic   = "Z07C"
+	version = 1
+
+	headerSize = len(magic) + 1
+	recordHead = 1 + 8 + 2
+)
+
+var ErrBadHeader = errors.New("capture: not a capture file")
+
+type Record struct {
+	Direction Direction
+	Time      time.Time
+	Payload   []byte
+}
+
+func writeHeader(w io.Writer) error {
+	_, err := w.Write(append([]byte(magic), version))
+	return err
+}
+
+func writeRecord(w io.Writer, r Record) error {
+	if len(r.Payload) > 0xFFFF {
+		return fmt.Errorf("capture: payload of %d bytes does not fit a record", len(r.Payload))
+	}
+	var head [recordHead]byte
+	head[0] = byte(r.Direction)
+	binary.LittleEndian.PutUint64(head[1:9], uint64(r.Time.UnixMicro()))
+	binary.LittleEndian.PutUint16(head[9:11], uint16(len(r.Payload)))
+	if _, err := w.Write(head[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(r.Payload)
+	return err
+}
+
+// Reader reads the records of a capture file in order.
+type Reader struct {
+	r      *bufio.Reader
+	header bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next record, or io.EOF at the end of the capture.
+func (cr *Reader) Next() (Record, error) {
+	if !cr.header {
+		var h [headerSize]byte
+		if _, err := io.ReadFull(cr.r, h[:]); err != nil {
+			return Record{}, ErrBadHeader
+		}
+		if string(h[:len(magic)]) != magic || h[len(magic)] != version {
+			return Record{}, ErrBadHeader
+		}
+		cr.header = true
+	}
+
+	var head [recordHead]byte
+	if _, err := io.ReadFull(cr.r, head[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Record{}, fmt.Errorf("capture: truncated record: %w", err)
+		}
+		return Record{}, err
+	}
+
+	payload := make([]byte, binary.LittleEndian.Uint16(head[9:11]))
+	if _, err := io.ReadFull(cr.r, payload); err != nil {
+		return Record{}, fmt.Errorf("capture: truncated record: %w", io.ErrUnexpectedEOF)
+	}
+	return Record{
+		Direction: Direction(head[0]),
+		Time:      time.UnixMicro(int64(binary.LittleEndian.Uint64(head[1:9]))),
+		Payload:   payload,
+	}, nil
+}
+
+// Writer writes a capture to any stream, e.g. to build a golden session in a test.
+type Writer struct {
+	w      io.Writer
+	header bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (cw *Writer) Write(r Record) error {
+	if !cw.header {
+		if err := writeHeader(cw.w); err != nil {
+			return err
+		}
+		cw.header = true
+	}
+	return writeRecord(cw.w, r)
+}
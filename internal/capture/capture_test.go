@@ -0,0 +1,125 @@
+package capture_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"z07/internal/capture"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, path string) []capture.Record {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var records []capture.Record
+	r := capture.NewReader(f)
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+}
+
+func captureFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.bin"))
+	require.NoError(t, err)
+	return files
+}
+
+func TestSession_Record(t *testing.T) {
+	t.Run("Disabled recorder writes nothing", func(t *testing.T) {
+		dir := t.TempDir()
+		s := capture.NewRecorder(dir, 0).Start("game-127.0.0.1:5000")
+		s.Record(capture.ServerToClient, []byte{0x1E})
+		s.Close()
+
+		require.Empty(t, captureFiles(t, dir))
+	})
+
+	t.Run("Records round trip in order", func(t *testing.T) {
+		dir := t.TempDir()
+		rec := capture.NewRecorder(dir, 0)
+		rec.SetEnabled(true)
+
+		s := rec.Start("game-127.0.0.1:5000")
+		s.Record(capture.ClientToServer, []byte{0x65})
+		s.Record(capture.ServerToClient, []byte{0x6D, 0x01, 0x02})
+		s.Close()
+
+		files := captureFiles(t, dir)
+		require.Len(t, files, 1)
+		require.Contains(t, filepath.Base(files[0]), "game-127.0.0.1_5000-")
+
+		records := readAll(t, files[0])
+		require.Len(t, records, 2)
+		require.Equal(t, capture.ClientToServer, records[0].Direction)
+		require.Equal(t, []byte{0x65}, records[0].Payload)
+		require.Equal(t, capture.ServerToClient, records[1].Direction)
+		require.Equal(t, []byte{0x6D, 0x01, 0x02}, records[1].Payload)
+		require.False(t, records[1].Time.Before(records[0].Time))
+	})
+
+	t.Run("Files are rotated", func(t *testing.T) {
+		dir := t.TempDir()
+		rec := capture.NewRecorder(dir, 80) // Header and two 31 byte records
+		rec.SetEnabled(true)
+
+		s := rec.Start("game")
+		for i := 0; i < 5; i++ {
+			s.Record(capture.ServerToClient, make([]byte, 20))
+		}
+		s.Close()
+
+		files := captureFiles(t, dir)
+		require.Len(t, files, 3)
+		total := 0
+		for _, f := range files {
+			total += len(readAll(t, f))
+		}
+		require.Equal(t, 5, total)
+	})
+
+	t.Run("Toggled at runtime", func(t *testing.T) {
+		dir := t.TempDir()
+		s := capture.NewRecorder(dir, 0).Start("game")
+		require.False(t, s.Enabled())
+
+		s.SetEnabled(true)
+		s.Record(capture.ServerToClient, []byte{0x1E})
+		s.SetEnabled(false)
+		s.Record(capture.ServerToClient, []byte{0x1E})
+
+		files := captureFiles(t, dir)
+		require.Len(t, files, 1)
+		require.Len(t, readAll(t, files[0]), 1)
+	})
+
+	t.Run("Nil session is a no-op", func(t *testing.T) {
+		var rec *capture.Recorder
+		s := rec.Start("game")
+		require.Nil(t, s)
+		s.Record(capture.ServerToClient, []byte{0x1E})
+		s.SetEnabled(true)
+		require.False(t, s.Enabled())
+		s.Close()
+	})
+}
+
+func TestReader_Errors(t *testing.T) {
+	_, err := capture.NewReader(bytes.NewReader([]byte("nope!"))).Next()
+	require.ErrorIs(t, err, capture.ErrBadHeader)
+
+	truncated := []byte{'Z', '0', '7', 'C', 1, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0x05, 0x00, 0x1E}
+	_, err = capture.NewReader(bytes.NewReader(truncated)).Next()
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
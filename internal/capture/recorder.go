@@ -0,0 +1,175 @@
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Recorder hands out one Session per proxied connection. Sessions start enabled when the recorder is.
+type Recorder struct {
+	Dir         string
+	MaxFileSize int64 // Files are rotated once they would grow past this, 0 disables rotation
+
+	enabled atomic.Bool
+}
+
+func NewRecorder(dir string, maxFileSize int64) *Recorder {
+	return &Recorder{Dir: dir, MaxFileSize: maxFileSize}
+}
+
+func (r *Recorder) SetEnabled(enabled bool) {
+	r.enabled.Store(enabled)
+}
+
+func (r *Recorder) Enabled() bool {
+	return r.enabled.Load()
+}
+
+// Start creates the capture of a single connection. A nil recorder returns a nil session, which records nothing.
+func (r *Recorder) Start(name string) *Session {
+	if r == nil {
+		return nil
+	}
+	s := &Session{
+		recorder: r,
+		name:     sanitize(name),
+	}
+	s.enabled = r.Enabled()
+	return s
+}
+
+// Session writes the messages of one connection. Files are only created once something is recorded.
+type Session struct {
+	recorder *Recorder
+	name     string
+
+	mu      sync.Mutex
+	enabled bool
+	file    *os.File
+	w       *bufio.Writer
+	size    int64
+	part    int
+	started time.Time
+}
+
+// SetEnabled switches recording at runtime. Disabling closes the current file, enabling again starts a new one.
+func (s *Session) SetEnabled(enabled bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enabled = enabled
+	if !enabled {
+		s.closeFile()
+	}
+}
+
+func (s *Session) Enabled() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enabled
+}
+
+// Record stores a decrypted message. Errors are logged and disable the session, a capture must never break the proxy.
+func (s *Session) Record(dir Direction, payload []byte) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.enabled {
+		return
+	}
+	if err := s.write(Record{Direction: dir, Time: time.Now(), Payload: payload}); err != nil {
+		log.Printf("[Capture] %s: %v, recording stopped", s.name, err)
+		s.enabled = false
+		s.closeFile()
+	}
+}
+
+func (s *Session) write(r Record) error {
+	size := int64(recordHead + len(r.Payload))
+	if s.file != nil && s.recorder.MaxFileSize > 0 && s.size+size > s.recorder.MaxFileSize {
+		s.closeFile()
+	}
+	if s.file == nil {
+		if err := s.openFile(); err != nil {
+			return err
+		}
+	}
+	if err := writeRecord(s.w, r); err != nil {
+		return err
+	}
+	s.size += size
+	// Flushed per record so a crash keeps everything up to the last message.
+	return s.w.Flush()
+}
+
+func (s *Session) openFile() error {
+	if err := os.MkdirAll(s.recorder.Dir, 0o755); err != nil {
+		return err
+	}
+	if s.part == 0 {
+		s.started = time.Now()
+	}
+	s.part++
+
+	name := fmt.Sprintf("%s-%s-%03d.bin", s.name, s.started.Format("20060102-150405"), s.part)
+	f, err := os.Create(filepath.Join(s.recorder.Dir, name))
+	if err != nil {
+		return err
+	}
+	s.file = f
+	s.w = bufio.NewWriter(f)
+	s.size = int64(headerSize)
+	if err := writeHeader(s.w); err != nil {
+		s.closeFile()
+		return err
+	}
+	log.Printf("[Capture] Recording to %s", f.Name())
+	return nil
+}
+
+func (s *Session) closeFile() {
+	if s.file == nil {
+		return
+	}
+	if err := s.w.Flush(); err != nil {
+		log.Printf("[Capture] %s: %v", s.name, err)
+	}
+	if err := s.file.Close(); err != nil {
+		log.Printf("[Capture] %s: %v", s.name, err)
+	}
+	s.file = nil
+	s.w = nil
+}
+
+// Close finishes the current file and stops recording.
+func (s *Session) Close() {
+	s.SetEnabled(false)
+}
+
+// sanitize turns a remote address like 127.0.0.1:51234 into something every filesystem accepts.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
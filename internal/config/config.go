@@ -0,0 +1,457 @@
+// Package config loads the proxy settings. In increasing priority: defaults, a YAML file, Z07_* environment variables and flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"z07/internal/bot"
+	"z07/internal/protocol"
+	"z07/internal/protocol/crypto"
+	"z07/internal/rules"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is read when no -config is given, it is fine for it not to exist.
+const DefaultPath = "z07.yaml"
+
+type Config struct {
+	Login     LoginConfig     `yaml:"login"`
+	Logins    []LoginConfig   `yaml:"logins"` // More login proxies, one per listen port and backend
+	Game      GameConfig      `yaml:"game"`
+	Advertise AdvertiseConfig `yaml:"advertise"`
+	RSA       RSAConfig       `yaml:"rsa"`
+	Items     string          `yaml:"items"` // Path to items.json
+	ItemNames ItemNamesConfig `yaml:"item_names"`
+	UI        UIConfig        `yaml:"ui"`
+	Capture   CaptureConfig   `yaml:"capture"`
+	Injection InjectionConfig `yaml:"injection"`
+	Rules     RulesConfig     `yaml:"rules"`
+	Minimap   MinimapConfig   `yaml:"minimap"`
+	Modules   ModulesConfig   `yaml:"modules"`
+}
+
+// ItemNamesConfig points at the item database of an OpenTibia server, merged over items.json for names and weights.
+type ItemNamesConfig struct {
+	XML string `yaml:"xml"` // items.xml, empty leaves the items unnamed
+	OTB string `yaml:"otb"` // items.otb mapping its server IDs to client IDs, empty when they are the same
+}
+
+type LoginConfig struct {
+	Listen  string `yaml:"listen"`
+	Backend string `yaml:"backend"`
+	MOTD    string `yaml:"motd"`
+}
+
+type GameConfig struct {
+	Listen  string `yaml:"listen"`
+	Backend string `yaml:"backend"`
+}
+
+// AdvertiseConfig is the game world put in the character list, it has to point the client at this proxy.
+type AdvertiseConfig struct {
+	IP        string `yaml:"ip"`
+	Port      uint16 `yaml:"port"`
+	WorldName string `yaml:"world_name"` // Empty keeps the name of the real world
+}
+
+// RSAConfig holds decimal key numbers, inline or in a file such as the rsa-finder output. Empty means the built-in key.
+type RSAConfig struct {
+	ServerModulus            string `yaml:"server_modulus"`
+	ServerModulusFile        string `yaml:"server_modulus_file"`
+	ProxyModulus             string `yaml:"proxy_modulus"`
+	ProxyModulusFile         string `yaml:"proxy_modulus_file"`
+	ProxyPrivateExponent     string `yaml:"proxy_private_exponent"`
+	ProxyPrivateExponentFile string `yaml:"proxy_private_exponent_file"`
+}
+
+type UIConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+type CaptureConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Dir         string `yaml:"dir"`
+	MaxFileSize int64  `yaml:"max_file_size"`
+}
+
+// InjectionConfig sizes the outbound queues of a game session and throttles the packets the bot injects.
+type InjectionConfig struct {
+	QueueSize   int           `yaml:"queue_size"`
+	MinInterval time.Duration `yaml:"min_interval"` // e.g. "50ms", 0 disables the limit
+}
+
+// RulesConfig declares the packet rules every session starts with. The file is watched and can be edited while running.
+type RulesConfig struct {
+	List []rules.Rule `yaml:"list"`
+	File string       `yaml:"file"` // YAML list of more rules, applied after the ones above
+}
+
+// MinimapConfig is where the map every session explored is kept, one file per server.
+type MinimapConfig struct {
+	Dir string `yaml:"dir"` // Empty forgets the map when the session ends
+}
+
+type ModulesConfig struct {
+	Fishing   bool            `yaml:"fishing"`
+	Lighthack LighthackConfig `yaml:"lighthack"`
+	Healer    bool            `yaml:"healer"`
+	Cavebot   bool            `yaml:"cavebot"`
+	Targeting bool            `yaml:"targeting"`
+	Looter    bool            `yaml:"looter"`
+	Alarms    bool            `yaml:"alarms"`
+}
+
+type LighthackConfig struct {
+	Enabled bool  `yaml:"enabled"`
+	Level   uint8 `yaml:"level"`
+	Color   uint8 `yaml:"color"`
+}
+
+func Default() Config {
+	botDefaults := bot.DefaultSettings()
+	return Config{
+		Login: LoginConfig{
+			Listen:  ":7171",
+			Backend: "world.fibula.app:7171",
+			MOTD:    "Welcome to z07 Proxy!",
+		},
+		Game: GameConfig{
+			Listen:  ":7172",
+			Backend: "world.fibula.app:7172",
+		},
+		Advertise: AdvertiseConfig{
+			IP:   "127.0.0.1", // Only a client on this machine, any other needs the LAN address
+			Port: 7172,
+		},
+		Items: "data/772/items.json",
+		UI:    UIConfig{Addr: ":8080"},
+		Capture: CaptureConfig{
+			Dir:         "captures",
+			MaxFileSize: 64 << 20,
+		},
+		Minimap: MinimapConfig{Dir: "maps"},
+		Modules: ModulesConfig{
+			Lighthack: LighthackConfig{
+				Level: botDefaults.LighthackLevel,
+				Color: botDefaults.LighthackColor,
+			},
+		},
+	}
+}
+
+// override is a setting reachable from both a flag and an environment variable.
+type override struct {
+	name  string // Flag name, the variable is Z07_ followed by the upper case name
+	usage string
+	set   func(c *Config, v string) error
+}
+
+func (o override) env() string {
+	return "Z07_" + strings.ToUpper(strings.ReplaceAll(o.name, "-", "_"))
+}
+
+func setString(field func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+var overrides = []override{
+	{"login-listen", "Address the login proxy listens on", setString(func(c *Config) *string { return &c.Login.Listen })},
+	{"login-backend", "Login server to forward to", setString(func(c *Config) *string { return &c.Login.Backend })},
+	{"motd", "Message of the day shown by the client", setString(func(c *Config) *string { return &c.Login.MOTD })},
+	{"game-listen", "Address the game proxy listens on", setString(func(c *Config) *string { return &c.Game.Listen })},
+	{"game-backend", "Game server to forward to", setString(func(c *Config) *string { return &c.Game.Backend })},
+	{"advertise-ip", "IPv4 of this proxy as seen by the client", setString(func(c *Config) *string { return &c.Advertise.IP })},
+	{"advertise-port", "Game port of this proxy as seen by the client", func(c *Config, v string) error {
+		port, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return fmt.Errorf("not a port: %q", v)
+		}
+		c.Advertise.Port = uint16(port)
+		return nil
+	}},
+	{"world-name", "World name in the character list, empty keeps the real one", setString(func(c *Config) *string { return &c.Advertise.WorldName })},
+	{"rsa-server-modulus-file", "File with the decimal RSA modulus of the target server", setString(func(c *Config) *string { return &c.RSA.ServerModulusFile })},
+	{"items", "Path to items.json", setString(func(c *Config) *string { return &c.Items })},
+	{"items-xml", "Path to an OpenTibia items.xml with item names", setString(func(c *Config) *string { return &c.ItemNames.XML })},
+	{"items-otb", "Path to the items.otb matching items-xml", setString(func(c *Config) *string { return &c.ItemNames.OTB })},
+	{"ui-addr", "Address of the web dashboard", setString(func(c *Config) *string { return &c.UI.Addr })},
+	{"capture", "Record every session from the start", func(c *Config, v string) error {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("not a boolean: %q", v)
+		}
+		c.Capture.Enabled = enabled
+		return nil
+	}},
+	{"capture-dir", "Directory for session captures", setString(func(c *Config) *string { return &c.Capture.Dir })},
+	{"minimap-dir", "Directory for the explored maps, empty keeps none", setString(func(c *Config) *string { return &c.Minimap.Dir })},
+}
+
+// Load reads the configuration for the given command line arguments and validates it.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fs := flag.NewFlagSet("z07", flag.ContinueOnError)
+	path := fs.String("config", "", "Path to the YAML configuration (default "+DefaultPath+", env Z07_CONFIG)")
+	values := make(map[string]*string, len(overrides))
+	for _, o := range overrides {
+		values[o.name] = fs.String(o.name, "", fmt.Sprintf("%s (env %s)", o.usage, o.env()))
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+
+	configPath, explicit := *path, *path != ""
+	if !explicit {
+		if env := getenv("Z07_CONFIG"); env != "" {
+			configPath, explicit = env, true
+		} else {
+			configPath = DefaultPath
+		}
+	}
+	if err := cfg.readFile(configPath, explicit); err != nil {
+		return Config{}, err
+	}
+
+	var errs []error
+	for _, o := range overrides {
+		if v := getenv(o.env()); v != "" {
+			if err := o.set(&cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", o.env(), err))
+			}
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for _, o := range overrides {
+			if o.name == f.Name {
+				if err := o.set(&cfg, *values[o.name]); err != nil {
+					errs = append(errs, fmt.Errorf("-%s: %w", o.name, err))
+				}
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) readFile(path string, required bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true) // A typo in a key should not silently keep the default
+	if err := dec.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once, named by its key in the file.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	type listener struct{ key, addr string }
+	listeners := []listener{{"game.listen", c.Game.Listen}}
+	for i, login := range c.AllLogins() {
+		key := "login"
+		if i > 0 {
+			key = fmt.Sprintf("logins[%d]", i-1)
+		}
+		check(key+".listen", validateAddr(login.Listen, false))
+		check(key+".backend", validateAddr(login.Backend, true))
+		listeners = append(listeners, listener{key + ".listen", login.Listen})
+	}
+	check("game.listen", validateAddr(c.Game.Listen, false))
+	check("game.backend", validateAddr(c.Game.Backend, true))
+	check("ui.addr", validateAddr(c.UI.Addr, false))
+	listeners = append(listeners, listener{"ui.addr", c.UI.Addr})
+	for i, l := range listeners {
+		for _, other := range listeners[:i] {
+			if sameListener(l.addr, other.addr) {
+				check(l.key, fmt.Errorf("%q is already used by %s", l.addr, other.key))
+				break
+			}
+		}
+	}
+
+	_, err := protocol.StringToIP(c.Advertise.IP)
+	check("advertise.ip", err)
+	if c.Advertise.Port == 0 {
+		check("advertise.port", errors.New("must be set"))
+	}
+
+	if c.Items == "" {
+		check("items", errors.New("must be set"))
+	} else if _, err := os.Stat(c.Items); err != nil {
+		check("items", err)
+	}
+
+	if c.ItemNames.XML != "" {
+		_, err := os.Stat(c.ItemNames.XML)
+		check("item_names.xml", err)
+	}
+	if c.ItemNames.OTB != "" {
+		if c.ItemNames.XML == "" {
+			check("item_names.otb", errors.New("needs item_names.xml"))
+		} else if _, err := os.Stat(c.ItemNames.OTB); err != nil {
+			check("item_names.otb", err)
+		}
+	}
+
+	_, err = c.RSA.Keys()
+	check("rsa", err)
+
+	if c.Capture.Dir == "" {
+		check("capture.dir", errors.New("must be set"))
+	}
+	if c.Capture.MaxFileSize < 0 {
+		check("capture.max_file_size", errors.New("must not be negative"))
+	}
+	if c.Injection.QueueSize < 0 {
+		check("injection.queue_size", errors.New("must not be negative"))
+	}
+	if c.Injection.MinInterval < 0 {
+		check("injection.min_interval", errors.New("must not be negative"))
+	}
+	if err := rules.NewEngine().Set(c.Rules.List); err != nil {
+		check("rules.list", err)
+	}
+	if c.Rules.File != "" {
+		if list, err := rules.LoadFile(c.Rules.File); err != nil {
+			check("rules.file", err)
+		} else if err := rules.NewEngine().Set(list); err != nil {
+			check("rules.file", err)
+		}
+	}
+	if c.Modules.Lighthack.Level > 16 {
+		check("modules.lighthack.level", fmt.Errorf("%d is above the maximum of 16", c.Modules.Lighthack.Level))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateAddr(addr string, needHost bool) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if needHost && host == "" {
+		return fmt.Errorf("%q has no host", addr)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("%q has an invalid port", addr)
+	}
+	return nil
+}
+
+// sameListener reports whether two listen addresses would fight over a port, an empty or unspecified host takes every address.
+func sameListener(a, b string) bool {
+	hostA, portA, errA := net.SplitHostPort(a)
+	hostB, portB, errB := net.SplitHostPort(b)
+	if errA != nil || errB != nil || portA != portB || portA == "0" {
+		return false
+	}
+	return hostA == hostB || anyHost(hostA) || anyHost(hostB)
+}
+
+func anyHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "" || (ip != nil && ip.IsUnspecified())
+}
+
+// Keys builds the RSA keys, falling back to the built-in ones for anything not configured.
+func (r RSAConfig) Keys() (crypto.RSAKeySet, error) {
+	serverModulus, err := keyNumber("server_modulus", r.ServerModulus, r.ServerModulusFile, crypto.TargetServerRSA)
+	if err != nil {
+		return crypto.RSAKeySet{}, err
+	}
+	proxyModulus, err := keyNumber("proxy_modulus", r.ProxyModulus, r.ProxyModulusFile, crypto.OTPublicRSA)
+	if err != nil {
+		return crypto.RSAKeySet{}, err
+	}
+	proxyExponent, err := keyNumber("proxy_private_exponent", r.ProxyPrivateExponent, r.ProxyPrivateExponentFile, crypto.OTPrivateRSA)
+	if err != nil {
+		return crypto.RSAKeySet{}, err
+	}
+	return crypto.NewKeySet(proxyModulus, proxyExponent, serverModulus)
+}
+
+func keyNumber(key, inline, file, fallback string) (string, error) {
+	switch {
+	case inline != "" && file != "":
+		return "", fmt.Errorf("%s and %s_file are both set", key, key)
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("%s_file: %w", key, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case inline != "":
+		return strings.TrimSpace(inline), nil
+	default:
+		return fallback, nil
+	}
+}
+
+// AllLogins returns every login proxy to start. Extra ones without a MOTD share the one of the main login.
+func (c *Config) AllLogins() []LoginConfig {
+	logins := []LoginConfig{c.Login}
+	for _, login := range c.Logins {
+		if login.MOTD == "" {
+			login.MOTD = c.Login.MOTD
+		}
+		logins = append(logins, login)
+	}
+	return logins
+}
+
+// OutboxOptions size the outbound queues of a game session from the injection settings.
+func (c *Config) OutboxOptions() protocol.OutboxOptions {
+	return protocol.OutboxOptions{
+		Capacity:          c.Injection.QueueSize,
+		MinInjectInterval: c.Injection.MinInterval,
+	}
+}
+
+// BotSettings are the defaults every game session starts with.
+func (c *Config) BotSettings() bot.Settings {
+	return bot.Settings{
+		Fishing:        c.Modules.Fishing,
+		Lighthack:      c.Modules.Lighthack.Enabled,
+		LighthackLevel: c.Modules.Lighthack.Level,
+		LighthackColor: c.Modules.Lighthack.Color,
+		Healer:         c.Modules.Healer,
+		Cavebot:        c.Modules.Cavebot,
+		Targeting:      c.Modules.Targeting,
+		Looter:         c.Modules.Looter,
+		Alarms:         c.Modules.Alarms,
+	}
+}
@@ -0,0 +1,197 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"z07/internal/config"
+	"z07/internal/protocol/crypto"
+	"z07/internal/rules"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestLoad(t *testing.T) {
+	items := writeFile(t, "items.json", "[]")
+
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := config.Load([]string{"-items", items}, env(nil))
+		require.NoError(t, err)
+		require.Equal(t, "world.fibula.app:7172", cfg.Game.Backend)
+		require.Equal(t, uint16(7172), cfg.Advertise.Port)
+		require.Equal(t, "127.0.0.1", cfg.Advertise.IP, "Clients on other machines need the LAN address")
+		require.Equal(t, ":8080", cfg.UI.Addr)
+		require.Equal(t, "maps", cfg.Minimap.Dir)
+	})
+
+	t.Run("File, environment and flags in increasing priority", func(t *testing.T) {
+		path := writeFile(t, "z07.yaml", `
+login:
+  motd: From file
+game:
+  backend: file.example:7172
+advertise:
+  ip: 10.0.0.1
+  world_name: FileWorld
+modules:
+  healer: true
+  targeting: true
+  lighthack:
+    enabled: true
+    level: 7
+items: `+items+`
+`)
+		cfg, err := config.Load(
+			[]string{"-config", path, "-world-name", "FlagWorld"},
+			env(map[string]string{"Z07_GAME_BACKEND": "env.example:7172", "Z07_WORLD_NAME": "EnvWorld"}),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "From file", cfg.Login.MOTD)
+		require.Equal(t, "env.example:7172", cfg.Game.Backend)
+		require.Equal(t, "FlagWorld", cfg.Advertise.WorldName)
+		require.Equal(t, "10.0.0.1", cfg.Advertise.IP)
+
+		settings := cfg.BotSettings()
+		require.True(t, settings.Healer)
+		require.True(t, settings.Targeting)
+		require.True(t, settings.Lighthack)
+		require.Equal(t, uint8(7), settings.LighthackLevel)
+		require.Equal(t, uint8(0xD7), settings.LighthackColor, "Keys missing from the file keep their default")
+	})
+
+	t.Run("More login proxies", func(t *testing.T) {
+		path := writeFile(t, "z07.yaml", `
+logins:
+  - listen: ":7271"
+    backend: second.example:7171
+  - listen: ":7371"
+    backend: third.example:7171
+    motd: Third
+items: `+items+`
+`)
+		cfg, err := config.Load([]string{"-config", path}, env(nil))
+		require.NoError(t, err)
+
+		logins := cfg.AllLogins()
+		require.Len(t, logins, 3)
+		require.Equal(t, "world.fibula.app:7171", logins[0].Backend)
+		require.Equal(t, "second.example:7171", logins[1].Backend)
+		require.Equal(t, cfg.Login.MOTD, logins[1].MOTD)
+		require.Equal(t, "Third", logins[2].MOTD)
+	})
+
+	t.Run("Injection limits", func(t *testing.T) {
+		path := writeFile(t, "z07.yaml", "injection:\n  queue_size: 32\n  min_interval: 50ms\nitems: "+items+"\n")
+		cfg, err := config.Load([]string{"-config", path}, env(nil))
+		require.NoError(t, err)
+
+		opts := cfg.OutboxOptions()
+		require.Equal(t, 32, opts.Capacity)
+		require.Equal(t, 50*time.Millisecond, opts.MinInjectInterval)
+	})
+
+	t.Run("Packet rules", func(t *testing.T) {
+		path := writeFile(t, "z07.yaml", `
+rules:
+  list:
+    - id: hide-effect
+      enabled: true
+      direction: s2c
+      opcode: 0x83
+      action: drop
+items: `+items+`
+`)
+		cfg, err := config.Load([]string{"-config", path}, env(nil))
+		require.NoError(t, err)
+		require.Len(t, cfg.Rules.List, 1)
+		require.Equal(t, uint8(0x83), cfg.Rules.List[0].Opcode)
+	})
+
+	t.Run("Minimap can be switched off", func(t *testing.T) {
+		path := writeFile(t, "z07.yaml", "minimap:\n  dir: \"\"\nitems: "+items+"\n")
+		cfg, err := config.Load([]string{"-config", path}, env(nil))
+		require.NoError(t, err)
+		require.Empty(t, cfg.Minimap.Dir)
+	})
+
+	t.Run("Config path from the environment", func(t *testing.T) {
+		path := writeFile(t, "custom.yaml", "login:\n  motd: Hi\nitems: "+items+"\n")
+		cfg, err := config.Load(nil, env(map[string]string{"Z07_CONFIG": path}))
+		require.NoError(t, err)
+		require.Equal(t, "Hi", cfg.Login.MOTD)
+	})
+
+	t.Run("Missing explicit file", func(t *testing.T) {
+		_, err := config.Load([]string{"-config", filepath.Join(t.TempDir(), "nope.yaml")}, env(nil))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("Unknown keys are rejected", func(t *testing.T) {
+		path := writeFile(t, "z07.yaml", "game:\n  backnd: x:1\n")
+		_, err := config.Load([]string{"-config", path}, env(nil))
+		require.ErrorContains(t, err, "backnd")
+	})
+
+	t.Run("Bad override", func(t *testing.T) {
+		_, err := config.Load([]string{"-items", items}, env(map[string]string{"Z07_ADVERTISE_PORT": "70000"}))
+		require.ErrorContains(t, err, "Z07_ADVERTISE_PORT")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	cfg := config.Default()
+	cfg.Items = writeFile(t, "items.json", "[]")
+	require.NoError(t, cfg.Validate())
+
+	cfg.Game.Backend = ":7172"
+	cfg.Login.Listen = "7171"
+	cfg.Advertise.IP = "::1"
+	cfg.Logins = []config.LoginConfig{{Listen: ":7172", Backend: "other.example:7171"}} // Taken by the game proxy
+	cfg.Modules.Lighthack.Level = 20
+	cfg.Rules.List = []rules.Rule{{ID: "broken", Direction: rules.ServerToClient, Action: "explode"}}
+	cfg.ItemNames.OTB = "items.otb" // Without items.xml
+	cfg.UI.Addr = "127.0.0.1:7172"  // The game proxy listens on every address
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	for _, key := range []string{"game.backend", "login.listen", "advertise.ip", "logins[0].listen", "modules.lighthack.level", "rules.list", "item_names.otb", "ui.addr"} {
+		require.ErrorContains(t, err, key)
+	}
+}
+
+func TestRSAConfig_Keys(t *testing.T) {
+	t.Run("Built-in keys", func(t *testing.T) {
+		keys, err := config.RSAConfig{}.Keys()
+		require.NoError(t, err)
+		require.Equal(t, crypto.RSA.GameServerPublicKey.N, keys.GameServerPublicKey.N)
+	})
+
+	t.Run("Server modulus from a file", func(t *testing.T) {
+		path := writeFile(t, "rsa_key.txt", "3233\n")
+		keys, err := config.RSAConfig{ServerModulusFile: path}.Keys()
+		require.NoError(t, err)
+		require.Equal(t, int64(3233), keys.GameServerPublicKey.N.Int64())
+	})
+
+	t.Run("Inline and file together", func(t *testing.T) {
+		_, err := config.RSAConfig{ServerModulus: "3233", ServerModulusFile: "rsa_key.txt"}.Keys()
+		require.ErrorContains(t, err, "both set")
+	})
+
+	t.Run("Not a number", func(t *testing.T) {
+		_, err := config.RSAConfig{ProxyModulus: "0xABC"}.Keys()
+		require.ErrorContains(t, err, "proxy private key")
+	})
+}
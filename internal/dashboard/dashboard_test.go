@@ -0,0 +1,123 @@
+package dashboard_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"z07/internal/bot"
+	"z07/internal/dashboard"
+	"z07/internal/game/state"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func newSession(id, character string, started time.Time) dashboard.Session {
+	gs := state.New()
+	gs.SetPlayerName(character)
+	return dashboard.Session{ID: id, Character: character, Started: started, Bot: bot.NewBot(gs, nil, nil)}
+}
+
+func TestRegistry(t *testing.T) {
+	now := time.Now()
+	registry := dashboard.NewRegistry()
+	registry.Register(newSession("b", "Druid", now))
+	registry.Register(newSession("a", "Knight", now.Add(-time.Minute)))
+
+	sessions := registry.List()
+	require.Len(t, sessions, 2)
+	require.Equal(t, "Knight", sessions[0].Character, "Oldest first")
+
+	latest, ok := registry.Latest()
+	require.True(t, ok)
+	require.Equal(t, "b", latest.ID)
+
+	registry.Deregister("b")
+	_, ok = registry.Get("b")
+	require.False(t, ok)
+	require.Len(t, registry.List(), 1)
+
+	var none *dashboard.Registry
+	none.Register(newSession("c", "Sorcerer", now))
+	require.Empty(t, none.List())
+}
+
+func TestServer(t *testing.T) {
+	registry := dashboard.NewRegistry()
+	knight := newSession("knight", "Knight", time.Now().Add(-time.Minute))
+	druid := newSession("druid", "Druid", time.Now())
+	registry.Register(knight)
+	registry.Register(druid)
+	defer knight.Bot.Stop()
+	defer druid.Bot.Stop()
+
+	srv := httptest.NewServer((&dashboard.Server{Registry: registry}).Handler())
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	t.Run("Sessions are listed", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/sessions")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var sessions []struct {
+			ID        string `json:"id"`
+			Character string `json:"character"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&sessions))
+		require.Len(t, sessions, 2)
+		require.Equal(t, "knight", sessions[0].ID)
+		require.Equal(t, "Druid", sessions[1].Character)
+	})
+
+	snapshotName := func(t *testing.T, url string) string {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var snap bot.BotSnapshot
+		require.NoError(t, conn.ReadJSON(&snap))
+		return snap.Name
+	}
+
+	t.Run("Websocket of the chosen session", func(t *testing.T) {
+		require.Equal(t, "Knight", snapshotName(t, wsURL+"?session=knight"))
+	})
+
+	t.Run("Latest session by default", func(t *testing.T) {
+		require.Equal(t, "Druid", snapshotName(t, wsURL))
+	})
+
+	t.Run("Unknown session", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL+"?session=gone", nil)
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Only pages from this machine", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {"http://localhost:5173"}})
+		require.NoError(t, err)
+		conn.Close()
+
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {"https://example.com"}})
+		require.Error(t, err)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/sessions", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "http://127.0.0.1:5173")
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, "http://127.0.0.1:5173", resp.Header.Get("Access-Control-Allow-Origin"))
+
+		req.Header.Set("Origin", "https://example.com")
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
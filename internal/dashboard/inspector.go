@@ -0,0 +1,244 @@
+package dashboard
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"z07/internal/bot"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	inspectorQueue      = 1024 // Messages waiting to be decoded
+	inspectorSubscriber = 512  // Decoded packets waiting for one browser
+)
+
+const (
+	DirectionC2S = "c2s"
+	DirectionS2C = "s2c"
+)
+
+// InspectedPacket is one packet of a message as the inspector page shows it.
+type InspectedPacket struct {
+	Seq       uint64          `json:"seq"`
+	Time      int64           `json:"time"` // Unix milliseconds
+	Direction string          `json:"direction"`
+	Opcode    uint8           `json:"opcode"`
+	Name      string          `json:"name"`
+	Fields    json.RawMessage `json:"fields,omitempty"`
+	Hex       string          `json:"hex"`
+	Error     string          `json:"error,omitempty"`
+	Fixture   string          `json:"fixture"` // Ready to paste into a Go test
+}
+
+type inspectedMessage struct {
+	direction string
+	time      time.Time
+	payload   []byte
+	pos       domain.Position // Where server packets are parsed from
+}
+
+// Inspector decodes the traffic of a session for whoever is watching. Without a watcher recording costs an
+// atomic load, and a slow watcher loses packets instead of slowing down the session.
+type Inspector struct {
+	watchers atomic.Int32
+	queue    chan inspectedMessage
+	done     chan struct{}
+	stopOnce sync.Once
+
+	mu   sync.Mutex
+	subs map[chan InspectedPacket]struct{}
+	seq  uint64
+}
+
+func NewInspector() *Inspector {
+	in := &Inspector{
+		queue: make(chan inspectedMessage, inspectorQueue),
+		done:  make(chan struct{}),
+		subs:  make(map[chan InspectedPacket]struct{}),
+	}
+	go in.run()
+	return in
+}
+
+// Watched tells whether recording is worth it right now.
+func (in *Inspector) Watched() bool {
+	return in != nil && in.watchers.Load() > 0
+}
+
+// RecordC2S queues a client message for decoding.
+func (in *Inspector) RecordC2S(payload []byte) {
+	in.record(inspectedMessage{direction: DirectionC2S, payload: payload})
+}
+
+// RecordS2C queues a server message, pos is the player position the message is parsed from.
+func (in *Inspector) RecordS2C(payload []byte, pos domain.Position) {
+	in.record(inspectedMessage{direction: DirectionS2C, payload: payload, pos: pos})
+}
+
+func (in *Inspector) record(msg inspectedMessage) {
+	if !in.Watched() {
+		return
+	}
+	msg.time = time.Now()
+	msg.payload = append([]byte(nil), msg.payload...)
+	select {
+	case in.queue <- msg:
+	default: // Nobody can read that fast anyway
+	}
+}
+
+// Subscribe returns a stream of decoded packets, cancel ends it.
+func (in *Inspector) Subscribe() (<-chan InspectedPacket, func()) {
+	ch := make(chan InspectedPacket, inspectorSubscriber)
+	in.mu.Lock()
+	in.subs[ch] = struct{}{}
+	in.mu.Unlock()
+	in.watchers.Add(1)
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			in.watchers.Add(-1)
+			in.mu.Lock()
+			delete(in.subs, ch)
+			in.mu.Unlock()
+		})
+	}
+}
+
+func (in *Inspector) Close() {
+	if in == nil {
+		return
+	}
+	in.stopOnce.Do(func() { close(in.done) })
+}
+
+func (in *Inspector) run() {
+	for {
+		select {
+		case <-in.done:
+			return
+		case msg := <-in.queue:
+			for _, p := range Inspect(msg.direction, msg.payload, msg.pos) {
+				in.publish(msg.time, p)
+			}
+		}
+	}
+}
+
+func (in *Inspector) publish(at time.Time, p InspectedPacket) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.seq++
+	p.Seq = in.seq
+	p.Time = at.UnixMilli()
+	for ch := range in.subs {
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// Inspect splits a message into its packets and decodes each of them. A packet that fails to parse is
+// reported with the rest of the message, nothing after it can be told apart.
+func Inspect(direction string, payload []byte, pos domain.Position) []InspectedPacket {
+	var out []InspectedPacket
+	add := func(start, end int, packet any) {
+		out = append(out, inspected(direction, payload[start:end], packet))
+	}
+
+	var stop int
+	var err error
+	if direction == DirectionS2C {
+		stop, err = packets.WalkS2C(payload, packets.ParsingContext{PlayerPosition: pos}, func(start, end int, p packets.S2CPacket) bool {
+			add(start, end, p)
+			return true
+		})
+	} else {
+		stop, err = packets.WalkC2S(payload, func(start, end int, p packets.C2SPacket) { add(start, end, p) })
+	}
+	if err != nil {
+		p := inspected(direction, payload[stop:], nil)
+		p.Error = err.Error()
+		out = append(out, p)
+	}
+	return out
+}
+
+func inspected(direction string, raw []byte, packet any) InspectedPacket {
+	p := InspectedPacket{
+		Direction: direction,
+		Hex:       hex.EncodeToString(raw),
+	}
+	if len(raw) > 0 {
+		p.Opcode = raw[0]
+		if direction == DirectionS2C {
+			p.Name = packets.S2COpcode(p.Opcode).String()
+		} else {
+			p.Name = packets.C2SOpcode(p.Opcode).String()
+		}
+	}
+	p.Fixture = bot.FormatForTest(fmt.Sprintf("%s %s 0x%02X", strings.ToUpper(direction), p.Name, p.Opcode), raw)
+
+	if packet != nil {
+		fields, err := json.Marshal(packet)
+		if err != nil {
+			// Map descriptions are keyed by position, which JSON cannot express.
+			fields, _ = json.Marshal(fmt.Sprintf("%+v", packet))
+		}
+		p.Fields = fields
+	}
+	return p
+}
+
+// The Server already checked the origin, it lets in the dev server of the page as well.
+var inspectorUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// ServeWS streams decoded packets to one browser until it goes away or the session ends.
+func (in *Inspector) ServeWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := inspectorUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	stream, cancel := in.Subscribe()
+	defer cancel()
+
+	// Nothing is expected from the browser, reading only notices when it leaves.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case <-in.done:
+			return
+		case p := <-stream:
+			if err := conn.WriteJSON(p); err != nil {
+				return
+			}
+		}
+	}
+}
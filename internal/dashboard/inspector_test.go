@@ -0,0 +1,97 @@
+package dashboard_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"z07/internal/dashboard"
+	"z07/internal/game/domain"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	sayHello    = []byte{0x96, 0x01, 0x05, 0x00, 'h', 'e', 'l', 'l', 'o'}
+	magicEffect = []byte{0x83, 0x64, 0x00, 0x64, 0x00, 0x07, 0x0D}
+	ping        = []byte{0x1E}
+)
+
+func TestInspect(t *testing.T) {
+	pos := domain.Position{X: 100, Y: 100, Z: 7}
+
+	t.Run("Every packet of a message", func(t *testing.T) {
+		got := dashboard.Inspect(dashboard.DirectionS2C, append(append([]byte{}, magicEffect...), ping...), pos)
+		require.Len(t, got, 2)
+
+		require.Equal(t, "MagicEffect", got[0].Name)
+		require.Equal(t, uint8(0x83), got[0].Opcode)
+		require.Equal(t, "8364006400070d", got[0].Hex)
+		require.JSONEq(t, `{"Pos":{"X":100,"Y":100,"Z":7},"Type":13}`, string(got[0].Fields))
+		require.Empty(t, got[0].Error)
+
+		require.Equal(t, "Ping", got[1].Name)
+		require.Equal(t, "// S2C Ping 0x1E\nrawData := []byte{0x1E}", got[1].Fixture)
+	})
+
+	t.Run("Client packet", func(t *testing.T) {
+		got := dashboard.Inspect(dashboard.DirectionC2S, sayHello, pos)
+		require.Len(t, got, 1)
+		require.Equal(t, "Say", got[0].Name)
+
+		var fields struct{ Text string }
+		require.NoError(t, json.Unmarshal(got[0].Fields, &fields))
+		require.Equal(t, "hello", fields.Text)
+	})
+
+	t.Run("Parse error keeps the rest of the message", func(t *testing.T) {
+		got := dashboard.Inspect(dashboard.DirectionS2C, []byte{0x1E, 0xFE, 0x01, 0x02}, pos)
+		require.Len(t, got, 2)
+		require.Equal(t, "Unknown(0xFE)", got[1].Name)
+		require.Equal(t, "fe0102", got[1].Hex)
+		require.Contains(t, got[1].Error, "unknown opcode 0xFE")
+		require.Nil(t, got[1].Fields)
+	})
+}
+
+func TestInspector_Stream(t *testing.T) {
+	registry := dashboard.NewRegistry()
+	session := newSession("knight", "Knight", time.Now())
+	session.Inspector = dashboard.NewInspector()
+	defer session.Inspector.Close()
+	defer session.Bot.Stop()
+	registry.Register(session)
+	registry.Register(newSession("druid", "Druid", time.Now())) // Latest, but without an inspector
+
+	srv := httptest.NewServer((&dashboard.Server{Registry: registry}).Handler())
+	defer srv.Close()
+	inspectorURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/inspector"
+
+	// Unwatched traffic is not even queued.
+	session.Inspector.RecordC2S(ping)
+	require.False(t, session.Inspector.Watched())
+
+	_, _, err := websocket.DefaultDialer.Dial(inspectorURL, nil)
+	require.Error(t, err, "The latest session has no inspector")
+
+	conn, _, err := websocket.DefaultDialer.Dial(inspectorURL+"?session=knight", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, session.Inspector.Watched, time.Second, time.Millisecond)
+
+	session.Inspector.RecordC2S(sayHello)
+	session.Inspector.RecordS2C(magicEffect, domain.Position{})
+
+	var first, second dashboard.InspectedPacket
+	require.NoError(t, conn.ReadJSON(&first))
+	require.NoError(t, conn.ReadJSON(&second))
+	require.Equal(t, dashboard.DirectionC2S, first.Direction)
+	require.Equal(t, "Say", first.Name)
+	require.Equal(t, "MagicEffect", second.Name)
+	require.Equal(t, first.Seq+1, second.Seq)
+
+	conn.Close()
+	require.Eventually(t, func() bool { return !session.Inspector.Watched() }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,90 @@
+// Package dashboard serves the web UI of every active game session from a single HTTP server.
+package dashboard
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"z07/internal/bot"
+)
+
+// Session is a logged in character as shown in the session picker.
+type Session struct {
+	ID        string
+	Character string
+	Started   time.Time
+	Bot       *bot.Bot
+	Inspector *Inspector // nil hides the packet inspector of the session
+}
+
+// Registry tracks the sessions of the whole process. A nil registry ignores every call.
+type Registry struct {
+	mu       sync.RWMutex
+	sessions map[string]Session
+}
+
+func NewRegistry() *Registry {
+	return &Registry{sessions: make(map[string]Session)}
+}
+
+func (r *Registry) Register(s Session) {
+	if r == nil {
+		return
+	}
+	if s.Started.IsZero() {
+		s.Started = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[s.ID] = s
+}
+
+func (r *Registry) Deregister(id string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, id)
+}
+
+func (r *Registry) Get(id string) (Session, bool) {
+	if r == nil {
+		return Session{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.sessions[id]
+	return s, ok
+}
+
+// List returns the active sessions, oldest first.
+func (r *Registry) List() []Session {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	sessions := make([]Session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].Started.Equal(sessions[j].Started) {
+			return sessions[i].ID < sessions[j].ID
+		}
+		return sessions[i].Started.Before(sessions[j].Started)
+	})
+	return sessions
+}
+
+// Latest is the most recently started session, the one a page without a chosen session follows.
+func (r *Registry) Latest() (Session, bool) {
+	sessions := r.List()
+	if len(sessions) == 0 {
+		return Session{}, false
+	}
+	return sessions[len(sessions)-1], true
+}
@@ -0,0 +1,136 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Server is the one HTTP server of the process, it routes every websocket to the bot of the chosen session.
+type Server struct {
+	Addr     string
+	Registry *Registry
+}
+
+type sessionInfo struct {
+	ID        string `json:"id"`
+	Character string `json:"character"`
+	Started   int64  `json:"started"` // Unix milliseconds
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sessions", s.handleSessions)
+	mux.HandleFunc("/ws", s.handleWS)
+	mux.HandleFunc("/inspector", s.handleInspector)
+	return s.checkOrigin(mux)
+}
+
+// checkOrigin keeps other web pages open in the browser away from the bots, they could inject packets
+// through the packet rules. The page is served by its own dev server during development, so any page
+// from this machine is let in, and so is one from the host the dashboard listens on. Requests without
+// an origin do not come from a web page.
+func (s *Server) checkOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !s.allowedOrigin(origin) {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) allowedOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	uiHost, _, err := net.SplitHostPort(s.Addr)
+	return err == nil && uiHost != "" && host == uiHost
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	sessions := s.Registry.List()
+	infos := make([]sessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infos = append(infos, sessionInfo{
+			ID:        session.ID,
+			Character: session.Character,
+			Started:   session.Started.UnixMilli(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(infos); err != nil {
+		log.Printf("[UI] Failed to write sessions: %v", err)
+	}
+}
+
+// handleWS serves /ws?session=<id>. Without a session it picks the latest one.
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.session(r)
+	if !ok {
+		http.Error(w, "no such session", http.StatusNotFound)
+		return
+	}
+	session.Bot.HandleWS(w, r)
+}
+
+// handleInspector serves /inspector?session=<id>, the decoded traffic of the session.
+func (s *Server) handleInspector(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.session(r)
+	if !ok || session.Inspector == nil {
+		http.Error(w, "no such session", http.StatusNotFound)
+		return
+	}
+	session.Inspector.ServeWS(w, r)
+}
+
+func (s *Server) session(r *http.Request) (Session, bool) {
+	if id := r.URL.Query().Get("session"); id != "" {
+		return s.Registry.Get(id)
+	}
+	return s.Registry.Latest()
+}
+
+// Run serves until the context is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:    s.Addr,
+		Handler: s.Handler(),
+	}
+
+	go func() {
+		<-ctx.Done()
+		// Do not hang forever if a browser tab stays connected.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("[UI] Shutdown error: %v", err)
+		}
+	}()
+
+	log.Printf("[UI] Dashboard live at %s", s.Addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// SpeakClass is the talk type of a say packet, in both directions.
+type SpeakClass uint8
+
+const (
+	SpeakSay         SpeakClass = 0x01
+	SpeakWhisper     SpeakClass = 0x02
+	SpeakYell        SpeakClass = 0x03
+	SpeakPrivate     SpeakClass = 0x04
+	SpeakChannelY    SpeakClass = 0x05 // Yellow channel text
+	SpeakRVRChannel  SpeakClass = 0x06 // Rule violation report
+	SpeakRVRAnswer   SpeakClass = 0x07 // Gamemaster answer to a report
+	SpeakRVRContinue SpeakClass = 0x08 // Reporter follow-up
+	SpeakBroadcast   SpeakClass = 0x09
+	SpeakChannelR1   SpeakClass = 0x0A // Red channel text (#c)
+	SpeakPrivateRed  SpeakClass = 0x0B // Red private message from a gamemaster (@name@)
+	SpeakChannelO    SpeakClass = 0x0C // Orange channel text
+	SpeakChannelR2   SpeakClass = 0x0E // Anonymous red channel text (#d)
+	SpeakMonsterSay  SpeakClass = 0x10
+	SpeakMonsterYell SpeakClass = 0x11
+)
+
+var speakClassNames = map[SpeakClass]string{
+	SpeakSay:         "say",
+	SpeakWhisper:     "whisper",
+	SpeakYell:        "yell",
+	SpeakPrivate:     "private",
+	SpeakChannelY:    "channel",
+	SpeakRVRChannel:  "report",
+	SpeakRVRAnswer:   "report answer",
+	SpeakRVRContinue: "report",
+	SpeakBroadcast:   "broadcast",
+	SpeakChannelR1:   "channel",
+	SpeakPrivateRed:  "private",
+	SpeakChannelO:    "channel",
+	SpeakChannelR2:   "channel",
+	SpeakMonsterSay:  "monster",
+	SpeakMonsterYell: "monster",
+}
+
+func (s SpeakClass) String() string {
+	if name, ok := speakClassNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func (s SpeakClass) IsPrivate() bool {
+	return s == SpeakPrivate || s == SpeakPrivateRed || s == SpeakRVRAnswer
+}
+
+// IsGamemaster reports whether only gamemasters and counsellors can talk in this class.
+func (s SpeakClass) IsGamemaster() bool {
+	return s == SpeakPrivateRed || s == SpeakRVRAnswer || s == SpeakBroadcast || s == SpeakChannelR1 || s == SpeakChannelR2
+}
+
+func (s SpeakClass) IsChannel() bool {
+	return s == SpeakChannelY || s == SpeakChannelR1 || s == SpeakChannelO || s == SpeakChannelR2
+}
+
+// MessageType is the class of a server text message (0xB4), it decides where the client shows it.
+type MessageType uint8
+
+const (
+	MessageConsoleRed    MessageType = 0x12 // Red text in the console
+	MessageEventOrange   MessageType = 0x13 // Orange text in the console, e.g. raid announcements
+	MessageConsoleOrange MessageType = 0x14
+	MessageWarning       MessageType = 0x15 // Red text in the game window
+	MessageEventAdvance  MessageType = 0x16 // White text in the game window, e.g. level up
+	MessageEventDefault  MessageType = 0x17 // White text at the bottom, e.g. loot
+	MessageStatusDefault MessageType = 0x18 // White text at the bottom and in the console
+	MessageInfoDescr     MessageType = 0x19 // Green text, e.g. look descriptions
+	MessageStatusSmall   MessageType = 0x1A // White text at the bottom only, e.g. "You are exhausted."
+	MessageConsoleBlue   MessageType = 0x1B
+)
+
+func (s SpeakClass) HasPosition() bool {
+	switch s {
+	case SpeakSay, SpeakWhisper, SpeakYell, SpeakMonsterSay, SpeakMonsterYell:
+		return true
+	default:
+		return false
+	}
+}
+
+// ChatMessage is one entry of the chat history, a creature talking or a server text message.
+type ChatMessage struct {
+	Seq  uint64 // Increases by one for every recorded message
+	Time time.Time
+
+	Author string // Empty for server messages
+	Level  uint16 // Only known for the player itself, the 7.72 talk packet has no level
+
+	// Class is set for talk, Type for server text messages.
+	Class     SpeakClass
+	Type      MessageType
+	ChannelID uint16
+	Pos       Position // Where it was said, for classes shown on the map
+
+	Text string
+}
+
+func (m ChatMessage) IsServerMessage() bool {
+	return m.Author == "" && m.Class == 0
+}
+
+// IsLoot reports whether this is the server message listing the content of a killed monster.
+func (m ChatMessage) IsLoot() bool {
+	return m.IsServerMessage() && strings.HasPrefix(m.Text, "Loot of ")
+}
@@ -0,0 +1,16 @@
+package domain
+
+type FightMode uint8
+
+const (
+	FightOffensive FightMode = 1
+	FightBalanced  FightMode = 2
+	FightDefensive FightMode = 3
+)
+
+type ChaseMode uint8
+
+const (
+	ChaseStand  ChaseMode = 0
+	ChaseFollow ChaseMode = 1
+)
@@ -0,0 +1,83 @@
+package domain
+
+import "time"
+
+/**
+Creature IDs are allocated by the server in fixed ranges,
+which is the only way the 7.72 protocol tells players, monsters and NPCs apart.
+*/
+
+const (
+	playerIdFirst  uint32 = 0x10000000
+	monsterIdFirst uint32 = 0x40000000
+	npcIdFirst     uint32 = 0x80000000
+)
+
+type Skull uint8
+
+const (
+	SkullNone   Skull = 0
+	SkullYellow Skull = 1
+	SkullGreen  Skull = 2
+	SkullWhite  Skull = 3
+	SkullRed    Skull = 4
+)
+
+type PartyShield uint8
+
+const (
+	ShieldNone        PartyShield = 0
+	ShieldWhiteYellow PartyShield = 1 // Leader invited us
+	ShieldWhiteBlue   PartyShield = 2 // We invited them
+	ShieldBlue        PartyShield = 3 // Party member
+	ShieldYellow      PartyShield = 4 // Party leader
+)
+
+type Outfit struct {
+	LookType uint16
+	Head     uint8
+	Body     uint8
+	Legs     uint8
+	Feet     uint8
+
+	// LookItem is set instead of the colours when LookType is 0 (e.g. Chameleon Rune).
+	LookItem uint16
+}
+
+type Creature struct {
+	ID   uint32
+	Name string
+	Pos  Position
+
+	HealthPercent uint8
+	Direction     Direction
+	Outfit        Outfit
+	LightLevel    uint8
+	LightColor    uint8
+	Speed         uint16
+	Skull         Skull
+	Shield        PartyShield
+
+	// LastAttack is when it last attacked the player, the server flashes a square around the attacker.
+	LastAttack time.Time
+
+	// Visible is false once the server removed the creature from the map.
+	// The client still remembers it until the server asks to forget its ID.
+	Visible bool
+}
+
+func (c Creature) IsPlayer() bool {
+	return c.ID >= playerIdFirst && c.ID < monsterIdFirst
+}
+
+func (c Creature) IsMonster() bool {
+	return c.ID >= monsterIdFirst && c.ID < npcIdFirst
+}
+
+func (c Creature) IsNPC() bool {
+	return c.ID >= npcIdFirst
+}
+
+func (c Creature) IsPartyMember() bool {
+	return c.Shield == ShieldBlue || c.Shield == ShieldYellow
+}
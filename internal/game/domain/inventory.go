@@ -44,3 +44,6 @@ func (s EquipmentSlot) String() string {
 		return "UnknownSlot"
 	}
 }
+
+// MaxStackCount is the most items one stack holds.
+const MaxStackCount = 100
@@ -0,0 +1,178 @@
+package domain
+
+import "slices"
+
+// Stack priorities of the client, a tile stack is sorted by them from the bottom up.
+const (
+	StackGround   = iota
+	StackClip     // Ground borders
+	StackBottom   // e.g. walls
+	StackTop      // e.g. door frames, always drawn on top
+	StackCreature // Creatures standing on the tile
+	StackCommon   // Everything else
+)
+
+// MaxTileThings is how many things the client keeps per tile, the server never refers to the ones above.
+const MaxTileThings = 10
+
+// Thing is one entry of a tile stack, either an item or a creature.
+type Thing struct {
+	Item       Item
+	CreatureID uint32 // Set for a creature, Item is empty then
+	Priority   int    // Places the thing in the stack, see ItemPriority
+}
+
+// ItemThing wraps an item with its stack priority, the caller knows it from the dat.
+func ItemThing(item Item, priority int) Thing {
+	return Thing{Item: item, Priority: priority}
+}
+
+func CreatureThing(creatureId uint32) Thing {
+	return Thing{CreatureID: creatureId, Priority: StackCreature}
+}
+
+func (t Thing) IsCreature() bool {
+	return t.CreatureID != 0
+}
+
+// ItemPriority is the stack priority the dat flags of an item give it.
+func ItemPriority(isGround bool, topOrder uint8) int {
+	switch {
+	case isGround:
+		return StackGround
+	case topOrder >= StackClip && topOrder <= StackTop:
+		return int(topOrder)
+	default:
+		return StackCommon
+	}
+}
+
+// Tile holds what lies on a position in the order the client keeps it, so the index of a thing
+// is the stack position the server expects.
+type Tile struct {
+	Position Position
+	Things   []Thing
+}
+
+// NewTile builds a tile from things that are already in stack order, e.g. from a map description.
+func NewTile(pos Position, things ...Thing) *Tile {
+	return &Tile{Position: pos, Things: slices.Clone(things)}
+}
+
+// Clone copies the stack, the state never changes a tile a snapshot may still hold.
+func (t *Tile) Clone() *Tile {
+	return &Tile{Position: t.Position, Things: slices.Clone(t.Things)}
+}
+
+// Items returns the items of the stack from the bottom up, creatures left out.
+func (t Tile) Items() []Item {
+	items := make([]Item, 0, len(t.Things))
+	for _, thing := range t.Things {
+		if !thing.IsCreature() {
+			items = append(items, thing.Item)
+		}
+	}
+	return items
+}
+
+// Creatures returns the creatures standing on the tile, the most recent arrival first.
+func (t Tile) Creatures() []uint32 {
+	var ids []uint32
+	for _, thing := range t.Things {
+		if thing.IsCreature() {
+			ids = append(ids, thing.CreatureID)
+		}
+	}
+	return ids
+}
+
+// Ground is the bottom item when it is a ground.
+func (t Tile) Ground() (Item, bool) {
+	if len(t.Things) == 0 || t.Things[0].Priority != StackGround {
+		return Item{}, false
+	}
+	return t.Things[0].Item, true
+}
+
+// TopUseItem is the item the client uses when the tile is clicked: the newest common item,
+// else the lowest wall or top item, else the ground.
+func (t Tile) TopUseItem() (Item, uint8, bool) {
+	if len(t.Things) == 0 {
+		return Item{}, 0, false
+	}
+	for i, thing := range t.Things {
+		if thing.Priority == StackCommon {
+			return thing.Item, uint8(i), true
+		}
+	}
+	for i, thing := range t.Things {
+		if p := thing.Priority; p == StackBottom || p == StackTop {
+			return thing.Item, uint8(i), true
+		}
+	}
+	if t.Things[0].IsCreature() {
+		return Item{}, 0, false
+	}
+	return t.Things[0].Item, 0, true
+}
+
+// StackPosOf finds the creature in the stack.
+func (t Tile) StackPosOf(creatureId uint32) (uint8, bool) {
+	for i, thing := range t.Things {
+		if thing.CreatureID == creatureId {
+			return uint8(i), true
+		}
+	}
+	return 0, false
+}
+
+// Add puts a thing where the client would: ground, borders, walls and top items above the ones
+// of the same kind, creatures and common items below them, i.e. newest first. Things pushed past
+// MaxTileThings are forgotten, false when that is the new thing itself.
+func (t *Tile) Add(thing Thing) (uint8, bool) {
+	priority := thing.Priority
+	after := priority <= StackTop
+
+	pos := len(t.Things)
+	for i, other := range t.Things {
+		otherPriority := other.Priority
+		if (after && otherPriority > priority) || (!after && otherPriority >= priority) {
+			pos = i
+			break
+		}
+	}
+	t.Things = slices.Insert(t.Things, pos, thing)
+
+	if len(t.Things) > MaxTileThings {
+		t.Things = t.Things[:MaxTileThings]
+	}
+	return uint8(pos), pos < MaxTileThings
+}
+
+// Remove takes the thing at the stack position off the tile.
+func (t *Tile) Remove(stackpos uint8) (Thing, bool) {
+	if int(stackpos) >= len(t.Things) {
+		return Thing{}, false
+	}
+	thing := t.Things[stackpos]
+	t.Things = slices.Delete(t.Things, int(stackpos), int(stackpos)+1)
+	return thing, true
+}
+
+// RemoveCreature takes the creature off the tile wherever it stands in the stack.
+func (t *Tile) RemoveCreature(creatureId uint32) bool {
+	stackpos, ok := t.StackPosOf(creatureId)
+	if ok {
+		t.Remove(stackpos)
+	}
+	return ok
+}
+
+// Replace swaps the thing at the stack position in place, the way the client transforms an item.
+func (t *Tile) Replace(stackpos uint8, thing Thing) bool {
+	if int(stackpos) >= len(t.Things) {
+		return false
+	}
+	t.Things[stackpos] = thing
+	return true
+}
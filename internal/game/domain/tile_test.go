@@ -0,0 +1,99 @@
+package domain_test
+
+import (
+	"testing"
+	"z07/internal/game/domain"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	grass = 63001
+	wall  = 63002
+	arch  = 63003
+	coin  = 63004
+)
+
+var priorities = map[uint16]int{
+	grass: domain.ItemPriority(true, 0),
+	wall:  domain.ItemPriority(false, domain.StackBottom),
+	arch:  domain.ItemPriority(false, domain.StackTop),
+	coin:  domain.ItemPriority(false, 0),
+}
+
+func item(id uint16) domain.Thing {
+	return domain.ItemThing(domain.Item{ID: id}, priorities[id])
+}
+
+func TestTile_Add(t *testing.T) {
+	tile := domain.NewTile(domain.Position{X: 100, Y: 100, Z: 7})
+
+	for _, thing := range []domain.Thing{
+		item(coin),
+		domain.CreatureThing(1),
+		item(arch),
+		item(wall),
+		domain.CreatureThing(2),
+		item(grass),
+		item(coin),
+	} {
+		tile.Add(thing)
+	}
+
+	require.Equal(t, []domain.Thing{
+		item(grass),
+		item(wall),
+		item(arch),
+		domain.CreatureThing(2), // Newest creature first
+		domain.CreatureThing(1),
+		item(coin),
+		item(coin),
+	}, tile.Things)
+	require.Equal(t, []uint32{2, 1}, tile.Creatures())
+
+	ground, ok := tile.Ground()
+	require.True(t, ok)
+	require.Equal(t, uint16(grass), ground.ID)
+}
+
+func TestTile_AddBeyondLimit(t *testing.T) {
+	tile := domain.NewTile(domain.Position{}, item(grass))
+	for range domain.MaxTileThings - 1 {
+		tile.Add(item(coin))
+	}
+
+	// A new common item still goes in at the bottom of its kind, the oldest one drops off.
+	stackpos, ok := tile.Add(domain.ItemThing(domain.Item{ID: coin, Count: 7}, priorities[coin]))
+	require.True(t, ok)
+	require.Equal(t, uint8(1), stackpos)
+	require.Len(t, tile.Things, domain.MaxTileThings)
+
+	// A wall would be placed above the limit, the client never sees it.
+	for range 3 {
+		tile.Add(item(wall))
+	}
+	_, ok = tile.Add(item(wall))
+	require.True(t, ok, "Walls go below common items")
+	require.Len(t, tile.Things, domain.MaxTileThings)
+}
+
+func TestTile_TopUseItem(t *testing.T) {
+	tile := domain.NewTile(domain.Position{}, item(grass), item(wall))
+
+	used, stackpos, ok := tile.TopUseItem()
+	require.True(t, ok)
+	require.Equal(t, uint16(wall), used.ID, "Without common items the wall is used")
+	require.Equal(t, uint8(1), stackpos)
+
+	tile.Add(domain.CreatureThing(1))
+	tile.Add(item(coin))
+	used, stackpos, _ = tile.TopUseItem()
+	require.Equal(t, uint16(coin), used.ID)
+	require.Equal(t, uint8(3), stackpos)
+
+	removed, ok := tile.Remove(3)
+	require.True(t, ok)
+	require.Equal(t, item(coin), removed)
+	_, ok = tile.Remove(3)
+	require.False(t, ok)
+}
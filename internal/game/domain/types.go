@@ -27,6 +27,10 @@ func (p Position) IsInContainer() bool {
 	return p.X == 0xFFFF && p.Y >= 64
 }
 
+func (p Position) GetContainerIndex() uint8 {
+	return uint8(p.Y - 64)
+}
+
 func NewInventoryPosition(slot EquipmentSlot) Position {
 	return Position{X: 0xFFFF, Y: uint16(slot), Z: 0}
 }
@@ -43,7 +47,11 @@ type Container struct {
 
 	// State
 	Capacity  uint8 // Total slots available (e.g. 20).
-	HasParent bool  // Useful to know if this is inside another container.
+	HasParent bool  // Set by the server when it lies inside another container, the client then offers "up one level".
+
+	// Hierarchy, known when the proxy saw the request that opened the container.
+	Source Position   // Where the container item lay: an equipment slot, a slot of another window or a map tile.
+	Parent *Container // The container it was opened from as it was then, without its items. Nil at the top.
 
 	// Contents
 	Items []Item
@@ -55,6 +63,15 @@ type Item struct {
 	HasCount bool  // Helper to know if we should Encode the Count byte
 }
 
+// Amount is how many items a stack holds, 1 for any other item. Whether the item stacks comes from the dat,
+// fluids and runes carry a count as well, it is their type or charges.
+func (i Item) Amount(stackable bool) int {
+	if stackable {
+		return max(int(i.Count), 1)
+	}
+	return 1
+}
+
 func (i Item) String() string {
 	if i.HasCount || i.Count > 1 {
 		return fmt.Sprintf("ID: %d (x%d)", i.ID, i.Count)
@@ -63,31 +80,147 @@ func (i Item) String() string {
 	return fmt.Sprintf("ID: %d", i.ID)
 }
 
-type Tile struct {
-	Position Position
-	Items    []Item
-}
+type Direction uint8
+
+const (
+	North     Direction = 0
+	East      Direction = 1
+	South     Direction = 2
+	West      Direction = 3
+	SouthWest Direction = 4
+	SouthEast Direction = 5
+	NorthWest Direction = 6
+	NorthEast Direction = 7
+)
 
-func (t Tile) TopItem() Item {
-	if len(t.Items) == 0 {
-		return Item{}
+// Offset returns the map delta of a single step in this direction.
+func (d Direction) Offset() (dx, dy int) {
+	switch d {
+	case North:
+		return 0, -1
+	case East:
+		return 1, 0
+	case South:
+		return 0, 1
+	case West:
+		return -1, 0
+	case SouthWest:
+		return -1, 1
+	case SouthEast:
+		return 1, 1
+	case NorthWest:
+		return -1, -1
+	case NorthEast:
+		return 1, -1
+	default:
+		return 0, 0
 	}
-	return t.Items[len(t.Items)-1]
 }
 
-type Direction uint8
+func (d Direction) IsDiagonal() bool {
+	return d >= SouthWest && d <= NorthEast
+}
 
-const (
-	North Direction = 0
-	East  Direction = 1
-	South Direction = 2
-	West  Direction = 3
-)
+// Translate returns the position one step away in the given direction.
+func (p Position) Translate(d Direction) Position {
+	dx, dy := d.Offset()
+	return Position{X: uint16(int(p.X) + dx), Y: uint16(int(p.Y) + dy), Z: p.Z}
+}
+
+// DistanceTo is the number of steps between two positions on the same floor, diagonals included.
+func (p Position) DistanceTo(other Position) int {
+	return max(absDiff(p.X, other.X), absDiff(p.Y, other.Y))
+}
+
+func absDiff(a, b uint16) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
 
 type Player struct {
 	ID   uint32
 	Name string
 	Pos  Position
+
+	Stats  Stats
+	Skills [SkillLast + 1]Skill
+	Icons  Icons
+
+	TargetCancels uint32 // Counts the attacks the server cancelled, e.g. when the target went out of reach
+}
+
+type Stats struct {
+	Health            uint16
+	MaxHealth         uint16
+	Capacity          uint16 // Free capacity in oz.
+	Experience        uint32
+	Level             uint16
+	LevelPercent      uint8
+	Mana              uint16
+	MaxMana           uint16
+	MagicLevel        uint8
+	MagicLevelPercent uint8
+	Soul              uint8
+}
+
+func (s Stats) HealthPercent() int {
+	return percent(s.Health, s.MaxHealth)
+}
+
+func (s Stats) ManaPercent() int {
+	return percent(s.Mana, s.MaxMana)
+}
+
+func percent(value, maxValue uint16) int {
+	if maxValue == 0 {
+		return 0
+	}
+	return int(value) * 100 / int(maxValue)
+}
+
+// Icons is the condition bitmask shown in the client's status bar.
+type Icons uint8
+
+const (
+	IconPoisoned    Icons = 1 << 0
+	IconBurning     Icons = 1 << 1
+	IconElectrified Icons = 1 << 2
+	IconDrunk       Icons = 1 << 3
+	IconManaShield  Icons = 1 << 4
+	IconParalysed   Icons = 1 << 5
+	IconHaste       Icons = 1 << 6
+	IconSwords      Icons = 1 << 7 // In combat, logging out and entering protection zones is blocked.
+)
+
+func (i Icons) Has(icon Icons) bool {
+	return i&icon != 0
+}
+
+var iconNames = []struct {
+	icon Icons
+	name string
+}{
+	{IconPoisoned, "poisoned"},
+	{IconBurning, "burning"},
+	{IconElectrified, "electrified"},
+	{IconDrunk, "drunk"},
+	{IconManaShield, "manaShield"},
+	{IconParalysed, "paralysed"},
+	{IconHaste, "haste"},
+	{IconSwords, "swords"},
+}
+
+// Names lists the active conditions, e.g. for the web UI.
+func (i Icons) Names() []string {
+	names := []string{}
+	for _, in := range iconNames {
+		if i.Has(in.icon) {
+			names = append(names, in.name)
+		}
+	}
+	return names
 }
 
 type SkillType uint8
@@ -108,6 +241,31 @@ const (
 	SkillLast  = Fishing
 )
 
+func (s SkillType) String() string {
+	switch s {
+	case Fist:
+		return "Fist"
+	case Club:
+		return "Club"
+	case Sword:
+		return "Sword"
+	case Axe:
+		return "Axe"
+	case Distance:
+		return "Distance"
+	case Shield:
+		return "Shielding"
+	case Fishing:
+		return "Fishing"
+	case Maglevel:
+		return "Magic Level"
+	case Level:
+		return "Level"
+	default:
+		return "UnknownSkill"
+	}
+}
+
 type Skill struct {
 	Level   uint8
 	Percent uint8
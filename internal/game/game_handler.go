@@ -1,29 +1,59 @@
 package game
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"time"
+	"z07/internal/bot"
+	"z07/internal/capture"
+	"z07/internal/dashboard"
 	"z07/internal/game/domain"
 	"z07/internal/game/packets"
 	"z07/internal/game/state"
+	"z07/internal/minimap"
 	"z07/internal/protocol"
 	"z07/internal/proxy"
+	"z07/internal/rules"
 )
 
 type GameHandler struct {
-	TargetAddr         string
+	TargetAddr string // Backend of characters without a known route
+	// Routes holds the real world of every character that went through the login proxy
+	Routes             *proxy.WorldRoutes
 	SessionInitializer func(string, protocol.Connection) (*packets.LoginRequest, protocol.Connection, error)
 	// Hook for testing or monitoring
 	OnSessionStart func(s *GameSession)
+	// Registry lists the session on the dashboard while it is connected
+	Registry *dashboard.Registry
+	// BotSettings are the module defaults of every session, nil keeps bot.DefaultSettings.
+	BotSettings *bot.Settings
+	// Recorder captures the decrypted traffic of every session, the login packet with the credentials is never recorded.
+	Recorder *capture.Recorder
+	// PacketRules filter and rewrite the traffic of every session, nil leaves each session with an empty set.
+	PacketRules *rules.Engine
+	// Outbox tunes the queues that serialize forwarded and injected packets on both connections.
+	Outbox protocol.OutboxOptions
+	// Minimap keeps what every session explored, one map per server. Nil forgets the map with the session.
+	Minimap *minimap.Store
 }
 
 func NewGameHandler(target string) *GameHandler {
-	return &GameHandler{
-		TargetAddr: target,
-		SessionInitializer: func(addr string, conn protocol.Connection) (*packets.LoginRequest, protocol.Connection, error) {
-			return proxy.InitSession("Game", conn, addr, packets.ParseLoginRequest)
-		},
+	h := &GameHandler{TargetAddr: target}
+	h.SessionInitializer = func(addr string, conn protocol.Connection) (*packets.LoginRequest, protocol.Connection, error) {
+		return proxy.InitRoutedSession("Game", conn, func(req *packets.LoginRequest) string {
+			return h.backendFor(req, addr)
+		}, packets.ParseLoginRequest)
 	}
+	return h
+}
+
+// backendFor picks the world the character was listed on, or the fallback when the login proxy never saw it.
+func (h *GameHandler) backendFor(req *packets.LoginRequest, fallback string) string {
+	if addr, ok := h.Routes.Lookup(req.AccountNumber, req.CharacterName); ok {
+		return addr
+	}
+	return fallback
 }
 
 func (h *GameHandler) Handle(client protocol.Connection) {
@@ -34,26 +64,59 @@ func (h *GameHandler) Handle(client protocol.Connection) {
 		log.Printf("Game: Failed to initialize session for %s: %v", client.RemoteAddr(), err)
 		return
 	}
-	defer protoServerConn.Close()
 
 	gameState := state.New()
 	gameState.SetPlayerName(loginPkt.CharacterName)
 
-	session := newGameSession(client, protoServerConn, gameState)
+	// From here on the forwarding loops and the bot write to the same connections, only the outboxes may touch them.
+	clientOut := protocol.NewOutbox(client, h.Outbox)
+	defer clientOut.Close()
+	serverOut := protocol.NewOutbox(protoServerConn, h.Outbox)
+	defer serverOut.Close()
+
+	session := newGameSession(clientOut, serverOut, gameState)
+	session.Capture = h.Recorder.Start("game-" + session.ID)
+	defer session.Capture.Close()
+	session.Bot.SetCapture(session.Capture)
+	session.Inspector = dashboard.NewInspector()
+	defer session.Inspector.Close()
+	if h.PacketRules != nil {
+		session.Bot.SetRules(h.PacketRules)
+	}
+	if h.BotSettings != nil {
+		session.Bot.Configure(*h.BotSettings)
+	}
+	h.Registry.Register(dashboard.Session{
+		ID:        session.ID,
+		Character: loginPkt.CharacterName,
+		Bot:       session.Bot,
+		Inspector: session.Inspector,
+	})
 	if h.OnSessionStart != nil {
 		h.OnSessionStart(session)
 	}
 
+	go session.loopStateSync()
 	go session.loopS2C()
 	go session.loopC2S()
 	go session.Bot.Start()
 
 	disconnectErr := <-session.ErrChan
 	log.Printf("[Game] Connection closed: %v", disconnectErr)
+	h.Registry.Deregister(session.ID)
 	session.Bot.Stop()
+
+	if h.Minimap != nil {
+		server := h.backendFor(loginPkt, h.TargetAddr)
+		if err := h.Minimap.Save(server, minimap.FromTiles(gameState.Tiles())); err != nil {
+			log.Printf("[Game] Failed to save the explored map of %s: %v", server, err)
+		}
+	}
 }
 
 func (g *GameSession) loopS2C() {
+	defer close(g.serverMessages)
+
 	for {
 		// 1. Read Raw
 		rawMsg, err := g.ServerConn.ReadMessage()
@@ -61,6 +124,7 @@ func (g *GameSession) loopS2C() {
 			g.ErrChan <- fmt.Errorf("S2C Read: %w", err)
 			return
 		}
+		g.Capture.Record(capture.ServerToClient, rawMsg)
 
 		patchedMsg, err := g.Bot.InterceptS2CPacket(rawMsg)
 		if err != nil {
@@ -73,7 +137,18 @@ func (g *GameSession) loopS2C() {
 			return
 		}
 
-		go g.processPacketsFromServer(rawMsg)
+		// State is updated off the proxy path, but in the order the messages arrived.
+		g.serverMessages <- rawMsg
+	}
+}
+
+func (g *GameSession) loopStateSync() {
+	for rawMsg := range g.serverMessages {
+		// The state is exactly where the message starts from, so the inspector parses it like the state does.
+		if g.Inspector.Watched() {
+			g.Inspector.RecordS2C(rawMsg, g.State.PlayerPos())
+		}
+		g.processPacketsFromServer(rawMsg)
 	}
 }
 
@@ -93,24 +168,65 @@ func (g *GameSession) loopC2S() {
 			g.ErrChan <- fmt.Errorf("C2S Write: %w", err)
 			return
 		}
+		// What the server received, bot changes included.
+		g.Capture.Record(capture.ClientToServer, patchedMsg)
+		g.Inspector.RecordC2S(patchedMsg)
 	}
 }
 
 func (g *GameSession) processPacketsFromServer(rawMsg []byte) {
+	if err := g.applyServerMessage(rawMsg); err != nil {
+		// Everything parsed before the failure is already applied, only the tail of this message is lost.
+		var unknown *packets.UnknownOpcodeError
+		if errors.As(err, &unknown) {
+			log.Printf("[Game] Skipping %d bytes after %v", len(rawMsg)-unknown.Offset-1, err)
+		} else {
+			log.Printf("[Game] Failed to parse packet: %v", err)
+		}
+	}
+}
+
+// PacketError locates the packet of a server message that could not be parsed.
+type PacketError struct {
+	Offset int // Position of the opcode byte in the message
+	Err    error
+}
+
+func (e *PacketError) Error() string {
+	return fmt.Sprintf("packet at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *PacketError) Unwrap() error {
+	return e.Err
+}
+
+// applyServerMessage applies every packet of a message in order and stops at the first one that fails to parse.
+func (g *GameSession) applyServerMessage(rawMsg []byte) error {
 	packetReader := protocol.NewPacketReader(rawMsg)
 	for packetReader.Remaining() > 0 {
+		offset := packetReader.Offset()
 
 		ctx := packets.ParsingContext{
 			PlayerPosition: g.State.CaptureFrame().Player.Pos,
 		}
 
 		packet, err := packets.ReadAndParseS2C(packetReader, ctx)
+		if err == nil {
+			// Not every parser checks for a truncated message itself.
+			err = packetReader.Err()
+		}
 		if err != nil {
-			log.Printf("[Game] Failed to parse packet: %v", err)
-			break
+			return &PacketError{Offset: offset, Err: err}
 		}
 		g.processPacketFromServer(packet)
 	}
+	return nil
+}
+
+// ApplyServerMessage feeds a decrypted server message to the state, the same way a live session does.
+func ApplyServerMessage(gs *state.GameState, rawMsg []byte) error {
+	session := &GameSession{State: gs}
+	return session.applyServerMessage(rawMsg)
 }
 
 func (g *GameSession) processPacketFromServer(packet packets.S2CPacket) {
@@ -121,25 +237,34 @@ func (g *GameSession) processPacketFromServer(packet packets.S2CPacket) {
 	case *packets.MapDescriptionMsg:
 		g.State.SetPlayerPos(p.PlayerPos)
 		g.State.SetTiles(p.Tiles)
+		g.trackCreatures(p.Creatures)
 	case *packets.MoveCreatureMsg:
-		// log.Printf("[Game] MoveCreatureMsg %v", p)
+		g.handleMoveCreature(p)
 	case *packets.MagicEffect:
 		// log.Printf("[Game] MagicEffect %v", p)
 	case *packets.RemoveTileThingMsg:
-		// log.Printf("[Game] RemoveTileThingMsg %v", p)
+		g.State.RemoveTileThing(p.Pos, p.StackPos)
 	case *packets.RemoveTileCreatureMsg:
-		// log.Printf("[Game] RemoveTileCreatureMsg %v", p)
+		g.State.HideCreature(p.CreatureID)
 	case *packets.WorldLightMsg:
 	case *packets.CreatureLightMsg:
-		// log.Printf("[Game] CreatureLightMsg %v", p)
+		g.State.SetCreatureLight(p.CreatureID, p.LightLevel, p.Color)
 	case *packets.CreatureHealthMsg:
-		// log.Printf("[Game] CreatureHealthMsg %v", p)
+		g.State.SetCreatureHealth(p.CreatureID, p.Hppc)
+	case *packets.CreatureTurnMsg:
+		g.State.SetCreatureDirection(p.CreatureID, p.Direction)
 	case *packets.PlayerIconsMsg:
-		log.Printf("[Game] PlayerIconsMsg %v", p)
+		g.State.SetPlayerIcons(p.Icons)
 	case *packets.ServerClosedMsg:
 		log.Printf("[Game] ServerClosedMsg %v", p)
 	case *packets.AddTileThingMsg:
-		log.Printf("[Game] AddTileThingMsg %v", p)
+		if p.Creature != nil {
+			// Placed before the registry learns the new position, the creature is taken off its old tile.
+			g.State.AddTileCreature(p.Pos, p.Creature.Creature.ID)
+			g.trackCreatures([]packets.CreatureInMap{*p.Creature})
+		} else {
+			g.State.AddTileItem(p.Pos, p.Item)
+		}
 	case *packets.AddInventoryItemMsg:
 		g.State.SetEquipment(p.Slot, p.Item)
 	case *packets.RemoveInventoryItemMsg:
@@ -157,11 +282,50 @@ func (g *GameSession) processPacketFromServer(packet packets.S2CPacket) {
 	case *packets.UpdateTileItemMsg:
 		g.State.UpdateTileItem(p.Position, p.Stackpos, p.Item)
 	case *packets.PlayerSkillsMsg:
-		log.Printf("[Game] PlayerSkillsMsg %v", p)
+		g.State.SetPlayerSkills(p.Skills)
 	case *packets.PlayerStatsMsg:
-		log.Printf("[Game] PlayerStatsMsg %v", p)
+		g.State.SetPlayerStats(p.Stats)
 	case *packets.LoginQueueMsg:
 		log.Printf("[Game] LoginQueueMsg %v", p)
+	case *packets.FloorChangeMsg:
+		g.State.SetPlayerPos(p.PlayerPos)
+		g.State.SetTiles(p.Tiles)
+		g.trackCreatures(p.Creatures)
+	case *packets.UpdateTileMsg:
+		g.State.SetTile(p.Pos, p.Tile)
+		g.trackCreatures(p.Creatures)
+	case *packets.CreatureOutfitMsg:
+		g.State.SetCreatureOutfit(p.CreatureID, p.Outfit)
+	case *packets.CreatureSpeedMsg:
+		g.State.SetCreatureSpeed(p.CreatureID, p.Speed)
+	case *packets.CreatureSkullMsg:
+		g.State.SetCreatureSkull(p.CreatureID, p.Skull)
+	case *packets.CancelTargetMsg:
+		g.State.CancelTarget()
+	case *packets.CreatureSquareMsg:
+		g.State.SetCreatureAttacking(p.CreatureID, time.Now())
+	case *packets.CreatureShieldMsg:
+		g.State.SetCreatureShield(p.CreatureID, p.Shield)
+	case *packets.SayMsg:
+		g.State.AddChatMessage(domain.ChatMessage{
+			Author:    p.Name,
+			Class:     p.Class,
+			ChannelID: p.ChannelID,
+			Pos:       p.Pos,
+			Text:      p.Text,
+		})
+	case *packets.TextMessageMsg:
+		g.State.AddChatMessage(domain.ChatMessage{
+			Type: p.Type,
+			Text: p.Text,
+		})
+
+	// Parsed to keep the rest of the message readable, nothing to track yet.
+	case *packets.CancelWalkMsg, *packets.AnimatedTextMsg, *packets.DistanceShootMsg,
+		*packets.ChannelListMsg, *packets.OpenChannelMsg, *packets.OpenPrivateChannelMsg, *packets.CloseChannelMsg,
+		*packets.RuleViolationMsg, *packets.VipAddMsg, *packets.VipStatusMsg,
+		*packets.TradeItemsMsg, *packets.TradeCloseMsg, *packets.OutfitWindowMsg,
+		*packets.LoginAsAdminMsg, *packets.FYIBoxMsg, *packets.TextWindowMsg, *packets.HouseTextWindowMsg:
 
 	default:
 		log.Printf("[Game] Unhandled game packet type: %T", p)
@@ -172,12 +336,31 @@ func (g *GameSession) processPacketFromServer(packet packets.S2CPacket) {
 func (g *GameSession) handleContainerOpen(p *packets.OpenContainerMsg) {
 	// 1. Translate Packet -> Domain
 	container := domain.Container{
-		ID:       p.ContainerID,
-		ItemID:   p.ContainerItem.ID,
-		Name:     p.ContainerName,
-		Capacity: p.Capacity,
-		Items:    p.Items,
+		ID:        p.ContainerID,
+		ItemID:    p.ContainerItem.ID,
+		Name:      p.ContainerName,
+		Capacity:  p.Capacity,
+		HasParent: p.HasParent,
+		Items:     p.Items,
 	}
 
 	g.State.OpenContainer(container)
 }
+
+func (g *GameSession) trackCreatures(creatures []packets.CreatureInMap) {
+	for _, c := range creatures {
+		if c.Known {
+			g.State.UpdateKnownCreature(c.Creature)
+		} else {
+			g.State.AddUnknownCreature(c.Creature, c.RemoveID)
+		}
+	}
+}
+
+func (g *GameSession) handleMoveCreature(p *packets.MoveCreatureMsg) {
+	if p.KnownSourcePosition {
+		g.State.MoveCreatureFrom(p.FromPos, uint8(p.FromStackPos), p.ToPos)
+	} else {
+		g.State.MoveCreature(p.CreatureID, p.ToPos)
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"net"
 	"testing"
 	"time"
+	"z07/internal/dashboard"
+	"z07/internal/game/domain"
 	"z07/internal/game/packets"
 	"z07/internal/game/state"
+	"z07/internal/minimap"
 	"z07/internal/protocol"
+	"z07/internal/proxy"
 
 	"github.com/stretchr/testify/require"
 )
@@ -94,3 +98,59 @@ func TestHandle_InitSessionFailure(t *testing.T) {
 		t.Fatal("Handle should have exited immediately on auth failure")
 	}
 }
+
+func TestGameHandler_BackendFor(t *testing.T) {
+	handler := NewGameHandler("fallback:7172")
+	req := &packets.LoginRequest{AccountNumber: 1001, CharacterName: "Knight"}
+
+	require.Equal(t, "fallback:7172", handler.backendFor(req, handler.TargetAddr), "No routes configured")
+
+	handler.Routes = proxy.NewWorldRoutes()
+	require.Equal(t, "fallback:7172", handler.backendFor(req, handler.TargetAddr), "Character never listed")
+
+	handler.Routes.Remember(1001, "Knight", "10.0.0.1:7172")
+	require.Equal(t, "10.0.0.1:7172", handler.backendFor(req, handler.TargetAddr))
+}
+
+func TestHandle_Registry(t *testing.T) {
+	registry := dashboard.NewRegistry()
+	var registered []dashboard.Session
+
+	handler := &GameHandler{
+		Registry: registry,
+		SessionInitializer: func(addr string, conn protocol.Connection) (*packets.LoginRequest, protocol.Connection, error) {
+			return &packets.LoginRequest{CharacterName: "TestPlayer"}, &MockConn{}, nil
+		},
+		OnSessionStart: func(s *GameSession) {
+			registered = registry.List()
+		},
+	}
+	handler.Handle(&MockConn{})
+
+	require.Len(t, registered, 1)
+	require.Equal(t, "TestPlayer", registered[0].Character)
+	require.Equal(t, "127.0.0.1:12345", registered[0].ID)
+	require.Empty(t, registry.List(), "Session is deregistered once the connection closes")
+}
+
+func TestHandle_SavesExploredMap(t *testing.T) {
+	store := minimap.NewStore(t.TempDir())
+	pos := domain.Position{X: 100, Y: 100, Z: 7}
+
+	handler := &GameHandler{
+		TargetAddr: "world.example:7172",
+		Minimap:    store,
+		SessionInitializer: func(addr string, conn protocol.Connection) (*packets.LoginRequest, protocol.Connection, error) {
+			return &packets.LoginRequest{CharacterName: "TestPlayer"}, &MockConn{}, nil
+		},
+		OnSessionStart: func(s *GameSession) {
+			s.State.SetTiles(map[domain.Position]*domain.Tile{pos: state.NewTile(pos, domain.Item{ID: 100})})
+		},
+	}
+	handler.Handle(&MockConn{})
+
+	known, err := store.Load("world.example:7172")
+	require.NoError(t, err)
+	_, ok := known.Tile(pos)
+	require.True(t, ok, "The map is kept once the session ends")
+}
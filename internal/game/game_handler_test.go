@@ -2,8 +2,10 @@ package game
 
 import (
 	"testing"
+	"z07/internal/assets"
 	"z07/internal/game/domain"
 	"z07/internal/game/packets"
+	"z07/internal/game/packets/packetstest"
 	"z07/internal/game/state"
 
 	"github.com/stretchr/testify/require"
@@ -63,6 +65,45 @@ func TestProcessPacketFromServer(t *testing.T) {
 		require.Equal(t, uint16(3350), item.ID)
 	})
 
+	t.Run("Handle PlayerStatsMsg", func(t *testing.T) {
+		pkt := &packets.PlayerStatsMsg{
+			Stats: domain.Stats{Health: 150, MaxHealth: 200, Mana: 30, MaxMana: 60, Level: 8},
+		}
+
+		session.processPacketFromServer(pkt)
+
+		stats := gameState.CaptureFrame().Player.Stats
+		require.Equal(t, pkt.Stats, stats)
+	})
+
+	t.Run("Handle PlayerSkillsMsg", func(t *testing.T) {
+		pkt := &packets.PlayerSkillsMsg{}
+		pkt.Skills[domain.Fishing] = domain.Skill{Level: 42, Percent: 7}
+
+		session.processPacketFromServer(pkt)
+
+		skills := gameState.CaptureFrame().Player.Skills
+		require.Equal(t, domain.Skill{Level: 42, Percent: 7}, skills[domain.Fishing])
+	})
+
+	t.Run("Handle PlayerIconsMsg", func(t *testing.T) {
+		pkt := &packets.PlayerIconsMsg{Icons: domain.IconPoisoned | domain.IconHaste}
+
+		session.processPacketFromServer(pkt)
+
+		icons := gameState.CaptureFrame().Player.Icons
+		require.True(t, icons.Has(domain.IconPoisoned))
+		require.False(t, icons.Has(domain.IconParalysed))
+		require.Equal(t, []string{"poisoned", "haste"}, icons.Names())
+	})
+
+	t.Run("Handle CancelTargetMsg", func(t *testing.T) {
+		session.processPacketFromServer(&packets.CancelTargetMsg{})
+		session.processPacketFromServer(&packets.CancelTargetMsg{})
+
+		require.Equal(t, uint32(2), gameState.CaptureFrame().Player.TargetCancels)
+	})
+
 	t.Run("Handle SetPlayerPos", func(t *testing.T) {
 		targetPos := domain.Position{X: 32368, Y: 32234, Z: 7}
 
@@ -76,3 +117,367 @@ func TestProcessPacketFromServer(t *testing.T) {
 		require.Equal(t, targetPos, currentPos, "Player position in state should match the packet position")
 	})
 }
+
+func TestProcessPacketFromServer_Creatures(t *testing.T) {
+	gameState := state.New()
+	session := &GameSession{
+		State: gameState,
+	}
+
+	ratPos := domain.Position{X: 100, Y: 100, Z: 7}
+
+	t.Run("Unknown creature is registered", func(t *testing.T) {
+		session.processPacketFromServer(&packets.MapDescriptionMsg{
+			PlayerPos: domain.Position{X: 102, Y: 100, Z: 7},
+			Creatures: []packets.CreatureInMap{
+				{Creature: domain.Creature{ID: 0x40000001, Name: "Rat", Pos: ratPos, HealthPercent: 100, Visible: true}},
+			},
+		})
+
+		rat, ok := gameState.CaptureFrame().Creatures[0x40000001]
+		require.True(t, ok)
+		require.Equal(t, "Rat", rat.Name)
+		require.Equal(t, ratPos, rat.Pos)
+	})
+
+	t.Run("Known creature keeps its name", func(t *testing.T) {
+		session.processPacketFromServer(&packets.AddTileThingMsg{
+			Pos: ratPos,
+			Creature: &packets.CreatureInMap{
+				Known:    true,
+				Creature: domain.Creature{ID: 0x40000001, Pos: ratPos, HealthPercent: 90, Visible: true},
+			},
+		})
+
+		rat := gameState.CaptureFrame().Creatures[0x40000001]
+		require.Equal(t, "Rat", rat.Name)
+		require.Equal(t, uint8(90), rat.HealthPercent)
+	})
+
+	t.Run("MoveCreatureMsg by position", func(t *testing.T) {
+		to := domain.Position{X: 101, Y: 100, Z: 7}
+		session.processPacketFromServer(&packets.MoveCreatureMsg{
+			FromPos:             ratPos,
+			ToPos:               to,
+			KnownSourcePosition: true,
+		})
+
+		snap := gameState.CaptureFrame()
+		require.Equal(t, to, snap.Creatures[0x40000001].Pos)
+		require.Len(t, snap.CreaturesOnScreen(), 1)
+	})
+
+	t.Run("CreatureHealthMsg", func(t *testing.T) {
+		session.processPacketFromServer(&packets.CreatureHealthMsg{CreatureID: 0x40000001, Hppc: 15})
+
+		require.Equal(t, uint8(15), gameState.CaptureFrame().Creatures[0x40000001].HealthPercent)
+	})
+
+	t.Run("RemoveTileCreatureMsg hides the creature", func(t *testing.T) {
+		session.processPacketFromServer(&packets.RemoveTileCreatureMsg{CreatureID: 0x40000001})
+
+		snap := gameState.CaptureFrame()
+		require.Contains(t, snap.Creatures, uint32(0x40000001), "removed creatures stay known")
+		require.Empty(t, snap.CreaturesOnScreen())
+	})
+
+	t.Run("RemoveID evicts a known creature", func(t *testing.T) {
+		session.processPacketFromServer(&packets.AddTileThingMsg{
+			Pos: ratPos,
+			Creature: &packets.CreatureInMap{
+				RemoveID: 0x40000001,
+				Creature: domain.Creature{ID: 0x40000002, Name: "Cave Rat", Pos: ratPos, Visible: true},
+			},
+		})
+
+		snap := gameState.CaptureFrame()
+		require.NotContains(t, snap.Creatures, uint32(0x40000001))
+		require.Equal(t, "Cave Rat", snap.FindCreatureByName("cave rat").Name)
+	})
+	t.Run("Creature appearance updates", func(t *testing.T) {
+		outfit := domain.Outfit{LookType: 21}
+		session.processPacketFromServer(&packets.CreatureOutfitMsg{CreatureID: 0x40000002, Outfit: outfit})
+		session.processPacketFromServer(&packets.CreatureSpeedMsg{CreatureID: 0x40000002, Speed: 220})
+		session.processPacketFromServer(&packets.CreatureSkullMsg{CreatureID: 0x40000002, Skull: domain.SkullWhite})
+		session.processPacketFromServer(&packets.CreatureShieldMsg{CreatureID: 0x40000002, Shield: domain.ShieldBlue})
+
+		rat := gameState.CaptureFrame().Creatures[0x40000002]
+		require.Equal(t, outfit, rat.Outfit)
+		require.Equal(t, uint16(220), rat.Speed)
+		require.Equal(t, domain.SkullWhite, rat.Skull)
+		require.True(t, rat.IsPartyMember())
+	})
+}
+
+func TestProcessPacketsFromServer_UnknownOpcode(t *testing.T) {
+	gameState := state.New()
+	session := &GameSession{
+		State: gameState,
+	}
+
+	// Player stats are applied even though an unknown packet follows in the same message.
+	msg := []byte{
+		0xA2, 0x01, // Icons: poisoned
+		0xFE, 0x00, 0x00, // Unknown
+	}
+	session.processPacketsFromServer(msg)
+
+	require.True(t, gameState.CaptureFrame().Player.Icons.Has(domain.IconPoisoned))
+}
+
+func TestApplyServerMessage_WindowsAndDialogs(t *testing.T) {
+	gm := make([]byte, 33)
+	gm[0] = 0x0B // Login as admin, a flag for every violation reason
+	frames := map[string][]byte{
+		"LoginAsAdmin":    gm,
+		"FYIBox":          {0x15, 0x02, 0x00, 'h', 'i'},
+		"TextWindow":      {0x96, 0x01, 0x00, 0x00, 0x00, 0x4A, 0x07, 0xE8, 0x03, 0x02, 0x00, 'h', 'i'},
+		"HouseTextWindow": {0x97, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03, 0x00, 'B', 'o', 'b'},
+	}
+	for name, packet := range frames {
+		t.Run(name, func(t *testing.T) {
+			gameState := state.New()
+			msg := append(append([]byte{}, packet...), 0xA2, 0x01) // Icons: poisoned
+
+			require.NoError(t, ApplyServerMessage(gameState, msg))
+			require.True(t, gameState.CaptureFrame().Player.Icons.Has(domain.IconPoisoned), "The packet after it is read")
+		})
+	}
+}
+
+func TestProcessPacketFromServer_Chat(t *testing.T) {
+	gameState := state.New()
+	gameState.SetPlayerName("Knight")
+	gameState.SetPlayerStats(domain.Stats{Level: 42})
+	session := &GameSession{
+		State: gameState,
+	}
+
+	pos := domain.Position{X: 100, Y: 100, Z: 7}
+	session.processPacketFromServer(&packets.SayMsg{Name: "Knight", Class: domain.SpeakSay, Pos: pos, Text: "hi"})
+	session.processPacketFromServer(&packets.SayMsg{Name: "Druid", Class: domain.SpeakChannelY, ChannelID: 5, Text: "trade HI"})
+	session.processPacketFromServer(&packets.TextMessageMsg{Type: domain.MessageEventDefault, Text: "Loot of a rat: 4 gold coins"})
+	session.processPacketFromServer(&packets.TextMessageMsg{Type: domain.MessageStatusSmall, Text: "You are exhausted."})
+
+	t.Run("Everything is recorded in order", func(t *testing.T) {
+		history := gameState.ChatHistory(state.ChatQuery{})
+		require.Len(t, history, 4)
+		require.Equal(t, uint64(1), history[0].Seq)
+		require.Equal(t, 42, int(history[0].Level), "Own messages carry the player level")
+		require.Equal(t, pos, history[0].Pos)
+		require.Zero(t, history[1].Level)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		channel := uint16(5)
+		require.Len(t, gameState.ChatHistory(state.ChatQuery{Text: "hi"}), 2)
+		require.Len(t, gameState.ChatHistory(state.ChatQuery{Author: "druid"}), 1)
+		require.Len(t, gameState.ChatHistory(state.ChatQuery{ChannelID: &channel}), 1)
+		require.Len(t, gameState.ChatHistory(state.ChatQuery{Classes: []domain.SpeakClass{domain.SpeakSay}}), 1)
+		require.Len(t, gameState.ChatHistory(state.ChatQuery{Server: true}), 2)
+
+		loot := gameState.ChatHistory(state.ChatQuery{LootOnly: true})
+		require.Len(t, loot, 1)
+		require.True(t, loot[0].IsLoot())
+	})
+
+	t.Run("AfterSeq and Limit", func(t *testing.T) {
+		history := gameState.ChatHistory(state.ChatQuery{AfterSeq: 2})
+		require.Len(t, history, 2)
+		require.Equal(t, uint64(3), history[0].Seq)
+
+		history = gameState.ChatHistory(state.ChatQuery{Limit: 1})
+		require.Len(t, history, 1)
+		require.Equal(t, "You are exhausted.", history[0].Text)
+	})
+
+	t.Run("Oldest messages are dropped", func(t *testing.T) {
+		for i := 0; i < 2000; i++ {
+			session.processPacketFromServer(&packets.SayMsg{Name: "Rat", Class: domain.SpeakMonsterSay, Text: "Meep!"})
+		}
+		history := gameState.ChatHistory(state.ChatQuery{})
+		require.Len(t, history, 2000)
+		require.Equal(t, uint64(5), history[0].Seq)
+		require.Empty(t, gameState.ChatHistory(state.ChatQuery{LootOnly: true}))
+	})
+}
+
+const (
+	testGround = 62001
+	testBorder = 62002 // Clip
+	testWall   = 62003 // Bottom
+	testLoot   = 62004
+	testBag    = 62005
+)
+
+func init() {
+	assets.Register(
+		assets.ItemType{ID: testGround, IsGround: true},
+		assets.ItemType{ID: testBorder, TopOrder: domain.StackClip},
+		assets.ItemType{ID: testWall, TopOrder: domain.StackBottom},
+		assets.ItemType{ID: testLoot},
+		assets.ItemType{ID: testBag, IsContainer: true},
+	)
+}
+
+func TestProcessPacketFromServer_TileStack(t *testing.T) {
+	gameState := state.New()
+	session := &GameSession{State: gameState}
+
+	here := domain.Position{X: 100, Y: 100, Z: 7}
+	east := domain.Position{X: 101, Y: 100, Z: 7}
+	item := func(id uint16) domain.Thing { return packets.ItemThing(domain.Item{ID: id}) }
+	stack := func(pos domain.Position) []domain.Thing {
+		return gameState.CaptureFrame().WorldMap[pos].Things
+	}
+
+	session.processPacketFromServer(&packets.MapDescriptionMsg{
+		PlayerPos: domain.Position{X: 102, Y: 100, Z: 7},
+		Tiles: map[domain.Position]*domain.Tile{
+			here: state.NewTile(here, domain.Item{ID: testGround}, domain.Item{ID: testBorder}, domain.Item{ID: testLoot}),
+			east: state.NewTile(east, domain.Item{ID: testGround}),
+		},
+	})
+
+	t.Run("Items go where the client puts them", func(t *testing.T) {
+		session.processPacketFromServer(&packets.AddTileThingMsg{Pos: here, Item: domain.Item{ID: testBag}})
+		session.processPacketFromServer(&packets.AddTileThingMsg{Pos: here, Item: domain.Item{ID: testWall}})
+
+		require.Equal(t, []domain.Thing{item(testGround), item(testBorder), item(testWall), item(testBag), item(testLoot)}, stack(here))
+	})
+
+	t.Run("Creatures stand between walls and common items", func(t *testing.T) {
+		session.processPacketFromServer(&packets.AddTileThingMsg{
+			Pos: here,
+			Creature: &packets.CreatureInMap{
+				Creature: domain.Creature{ID: 0x40000001, Name: "Rat", Pos: here, Visible: true},
+			},
+		})
+
+		require.Equal(t, domain.CreatureThing(0x40000001), stack(here)[3])
+		used, stackpos, ok := gameState.CaptureFrame().WorldMap[here].TopUseItem()
+		require.True(t, ok)
+		require.Equal(t, uint16(testBag), used.ID)
+		require.Equal(t, uint8(4), stackpos, "The creature counts")
+	})
+
+	t.Run("Transformed items keep their place", func(t *testing.T) {
+		before := gameState.CaptureFrame().WorldMap[here]
+		session.processPacketFromServer(&packets.UpdateTileItemMsg{Position: here, Stackpos: 4, Item: domain.Item{ID: testLoot}})
+
+		require.Equal(t, item(testLoot), stack(here)[4])
+		require.Equal(t, item(testBag), before.Things[4], "Snapshots keep the tile they were taken with")
+	})
+
+	t.Run("Moving creatures change both stacks", func(t *testing.T) {
+		session.processPacketFromServer(&packets.MoveCreatureMsg{
+			FromPos:             here,
+			FromStackPos:        3,
+			ToPos:               east,
+			KnownSourcePosition: true,
+		})
+
+		require.Equal(t, []domain.Thing{item(testGround), item(testBorder), item(testWall), item(testLoot), item(testLoot)}, stack(here))
+		require.Equal(t, []domain.Thing{item(testGround), domain.CreatureThing(0x40000001)}, stack(east))
+		require.Equal(t, east, gameState.CaptureFrame().Creatures[0x40000001].Pos)
+	})
+
+	t.Run("Removed things", func(t *testing.T) {
+		session.processPacketFromServer(&packets.RemoveTileThingMsg{Pos: here, StackPos: 2})
+		require.Equal(t, []domain.Thing{item(testGround), item(testBorder), item(testLoot), item(testLoot)}, stack(here))
+
+		session.processPacketFromServer(&packets.RemoveTileCreatureMsg{CreatureID: 0x40000001})
+		require.Equal(t, []domain.Thing{item(testGround)}, stack(east))
+	})
+}
+
+func TestApplyServerMessage_FloorChange(t *testing.T) {
+	const tilesPerFloor = 18 * 14
+	gameState := state.New()
+	gameState.SetPlayerPos(domain.Position{X: 100, Y: 100, Z: 7})
+
+	// Falling through a hole at 100,100: the player moves, the floors below come into view
+	// and the east and south slices bring the view back in line with the new position.
+	msg := []byte{0x6D, 0x64, 0x00, 0x64, 0x00, 0x07, 0x01, 0x64, 0x00, 0x64, 0x00, 0x08}
+	msg = append(msg, 0xBF)
+	msg = append(msg, packetstest.SkipTiles(3*tilesPerFloor)...) // Floors 8 to 10
+	msg = append(msg, 0x66)
+	msg = append(msg, packetstest.SkipTiles(2*14)...) // Floors 6 and 7 of the east column
+	msg = append(msg, 0x64, 0x00)                     // Ground on the first tile of floor 8
+	msg = append(msg, packetstest.SkipTiles(3*14)...)
+	msg = append(msg, 0x67)
+	msg = append(msg, packetstest.SkipTiles(5*18)...)
+
+	require.NoError(t, ApplyServerMessage(gameState, msg))
+
+	snap := gameState.CaptureFrame()
+	require.Equal(t, domain.Position{X: 100, Y: 100, Z: 8}, snap.Player.Pos)
+	require.Contains(t, snap.WorldMap, domain.Position{X: 109, Y: 93, Z: 8}, "The east column is read relative to the new floor")
+}
+
+func TestProcessPacketFromServer_ContainerTree(t *testing.T) {
+	const backpack, bag = 62101, 62102
+	gameState := state.New()
+	session := &GameSession{State: gameState}
+	open := func(window uint8, id uint16, hasParent bool, items ...domain.Item) {
+		session.processPacketFromServer(&packets.OpenContainerMsg{
+			ContainerID:   window,
+			ContainerItem: domain.Item{ID: id},
+			Capacity:      20,
+			HasParent:     hasParent,
+			Items:         items,
+		})
+	}
+	bpSlot := domain.NewInventoryPosition(domain.SlotBackpack)
+
+	gameState.ExpectContainer(0, backpack, bpSlot)
+	open(0, backpack, false, domain.Item{ID: 3031}, domain.Item{ID: bag})
+
+	t.Run("Opened from the equipment", func(t *testing.T) {
+		c := gameState.CaptureFrame().Containers[0]
+		require.Equal(t, bpSlot, c.Source)
+		require.Nil(t, c.Parent)
+	})
+
+	gameState.ExpectContainer(0, bag, domain.NewContainerPosition(0, 1))
+	open(0, bag, true)
+
+	t.Run("Nested in the same window", func(t *testing.T) {
+		frame := gameState.CaptureFrame()
+		chain := frame.ContainerChain(0)
+		require.Len(t, chain, 2)
+		require.Equal(t, uint16(backpack), chain[0].ItemID)
+		require.Empty(t, chain[0].Items, "The parent is not a live window")
+		require.Equal(t, domain.NewContainerPosition(0, 1), chain[1].Source)
+		require.Equal(t, 0, frame.FindContainerByPath([]int{1}))
+		require.Equal(t, -1, frame.ParentWindow(0), "The parent is no longer open")
+	})
+
+	gameState.ExpectParentContainer(0)
+	open(0, backpack, false, domain.Item{ID: 3031}, domain.Item{ID: bag})
+
+	t.Run("Up one level", func(t *testing.T) {
+		frame := gameState.CaptureFrame()
+		require.Equal(t, bpSlot, frame.Containers[0].Source)
+		require.Nil(t, frame.Containers[0].Parent)
+		require.Equal(t, 0, frame.FindContainerByPath(nil))
+	})
+
+	gameState.ExpectContainer(1, bag, domain.NewContainerPosition(0, 1))
+	open(1, bag, true)
+
+	t.Run("Nested in a new window", func(t *testing.T) {
+		frame := gameState.CaptureFrame()
+		require.Equal(t, 0, frame.ParentWindow(1))
+		require.Equal(t, 1, frame.FindContainerByPath([]int{1}))
+	})
+
+	t.Run("Unrequested containers stay outside the tree", func(t *testing.T) {
+		gameState.ExpectContainer(2, bag, domain.NewContainerPosition(0, 1))
+		open(2, backpack, true)
+
+		c := gameState.CaptureFrame().Containers[2]
+		require.Equal(t, domain.Position{}, c.Source)
+		require.Nil(t, c.Parent)
+	})
+}
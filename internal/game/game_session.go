@@ -2,6 +2,8 @@ package game
 
 import (
 	"z07/internal/bot"
+	"z07/internal/capture"
+	"z07/internal/dashboard"
 	"z07/internal/game/state"
 	"z07/internal/protocol"
 )
@@ -13,6 +15,10 @@ type GameSession struct {
 	ClientConn protocol.Connection
 	ServerConn protocol.Connection
 	ErrChan    chan error
+	Capture    *capture.Session // Nil when the handler has no recorder
+	Inspector  *dashboard.Inspector
+
+	serverMessages chan []byte // Raw S2C messages waiting to be applied to State
 }
 
 func newGameSession(client protocol.Connection, server protocol.Connection, gameState *state.GameState) *GameSession {
@@ -22,6 +28,8 @@ func newGameSession(client protocol.Connection, server protocol.Connection, game
 		ClientConn: client,
 		ServerConn: server,
 		ErrChan:    make(chan error, 100),
-		Bot:        bot.NewBot(gameState, client, server),
+
+		serverMessages: make(chan []byte, 1024),
+		Bot:            bot.NewBot(gameState, client, server),
 	}
 }
@@ -2,6 +2,7 @@ package packets
 
 import (
 	"errors"
+	"fmt"
 	"z07/internal/game/domain"
 	"z07/internal/protocol"
 	"z07/internal/protocol/crypto"
@@ -95,7 +96,15 @@ func ParseLookRequest(pr *protocol.PacketReader) (*LookRequest, error) {
 	lr.ItemId = pr.ReadUint16()
 	lr.StackPos = pr.ReadUint8()
 
-	return lr, nil
+	return lr, pr.Err()
+}
+
+func (lr *LookRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SLookRequest))
+
+	writePosition(pw, lr.Pos)
+	pw.WriteUint16(lr.ItemId)
+	pw.WriteUint8(lr.StackPos)
 }
 
 type UseItemWithCrosshairRequest struct {
@@ -119,7 +128,7 @@ func ParseUseItemWithCrosshairRequest(pr *protocol.PacketReader) (*UseItemWithCr
 	ur.ToItemId = pr.ReadUint16()
 	ur.ToStackPos = pr.ReadUint8()
 
-	return ur, nil
+	return ur, pr.Err()
 }
 
 func (ur *UseItemWithCrosshairRequest) Encode(pw *protocol.PacketWriter) {
@@ -133,3 +142,482 @@ func (ur *UseItemWithCrosshairRequest) Encode(pw *protocol.PacketWriter) {
 	pw.WriteUint16(ur.ToItemId)
 	pw.WriteUint8(ur.ToStackPos)
 }
+
+type UseItemOnCreatureRequest struct {
+	FromPos      domain.Position
+	FromItemId   uint16
+	FromStackPos uint8
+	CreatureID   uint32
+}
+
+func ParseUseItemOnCreatureRequest(pr *protocol.PacketReader) (*UseItemOnCreatureRequest, error) {
+	ur := &UseItemOnCreatureRequest{}
+
+	ur.FromPos = readPosition(pr)
+	ur.FromItemId = pr.ReadUint16()
+	ur.FromStackPos = pr.ReadUint8()
+	ur.CreatureID = pr.ReadUint32()
+
+	return ur, pr.Err()
+}
+
+func (ur *UseItemOnCreatureRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SUseItemOnCreature))
+
+	writePosition(pw, ur.FromPos)
+	pw.WriteUint16(ur.FromItemId)
+	pw.WriteUint8(ur.FromStackPos)
+	pw.WriteUint32(ur.CreatureID)
+}
+
+type SayRequest struct {
+	Class     domain.SpeakClass
+	Receiver  string // Only for private messages
+	ChannelID uint16 // Only for channel messages
+	Text      string
+}
+
+func ParseSayRequest(pr *protocol.PacketReader) (*SayRequest, error) {
+	sr := &SayRequest{}
+
+	sr.Class = domain.SpeakClass(pr.ReadUint8())
+	switch {
+	case sr.Class.IsPrivate():
+		sr.Receiver = pr.ReadString()
+	case sr.Class.IsChannel():
+		sr.ChannelID = pr.ReadUint16()
+	}
+	sr.Text = pr.ReadString()
+
+	return sr, pr.Err()
+}
+
+func (sr *SayRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SSay))
+
+	pw.WriteUint8(uint8(sr.Class))
+	switch {
+	case sr.Class.IsPrivate():
+		pw.WriteString(sr.Receiver)
+	case sr.Class.IsChannel():
+		pw.WriteUint16(sr.ChannelID)
+	}
+	pw.WriteString(sr.Text)
+}
+
+var walkOpcodes = map[domain.Direction]C2SOpcode{
+	domain.North:     C2SWalkNorth,
+	domain.East:      C2SWalkEast,
+	domain.South:     C2SWalkSouth,
+	domain.West:      C2SWalkWest,
+	domain.NorthEast: C2SWalkNorthEast,
+	domain.SouthEast: C2SWalkSouthEast,
+	domain.SouthWest: C2SWalkSouthWest,
+	domain.NorthWest: C2SWalkNorthWest,
+}
+
+// WalkRequest is a single step. Each direction has its own opcode and no payload.
+type WalkRequest struct {
+	Direction domain.Direction
+}
+
+func ParseWalkRequest(opcode C2SOpcode) (*WalkRequest, error) {
+	for direction, walkOpcode := range walkOpcodes {
+		if walkOpcode == opcode {
+			return &WalkRequest{Direction: direction}, nil
+		}
+	}
+	return nil, fmt.Errorf("not a walk opcode 0x%02X", opcode)
+}
+
+func (wr *WalkRequest) Encode(pw *protocol.PacketWriter) {
+	opcode, ok := walkOpcodes[wr.Direction]
+	if !ok {
+		pw.SetError(fmt.Errorf("invalid walk direction %d", wr.Direction))
+		return
+	}
+	pw.WriteUint8(byte(opcode))
+}
+
+// The auto-walk packet numbers the directions counter-clockwise starting east,
+// which does not match the domain.Direction values used everywhere else.
+var autoWalkSteps = map[domain.Direction]uint8{
+	domain.East:      1,
+	domain.NorthEast: 2,
+	domain.North:     3,
+	domain.NorthWest: 4,
+	domain.West:      5,
+	domain.SouthWest: 6,
+	domain.South:     7,
+	domain.SouthEast: 8,
+}
+
+// AutoWalkRequest is a whole route. The client sends it after a map click.
+type AutoWalkRequest struct {
+	Steps []domain.Direction
+}
+
+func ParseAutoWalkRequest(pr *protocol.PacketReader) (*AutoWalkRequest, error) {
+	ar := &AutoWalkRequest{}
+
+	count := pr.ReadUint8()
+	ar.Steps = make([]domain.Direction, 0, count)
+	for i := uint8(0); i < count; i++ {
+		step := pr.ReadUint8()
+		found := false
+		for direction, value := range autoWalkSteps {
+			if value == step {
+				ar.Steps = append(ar.Steps, direction)
+				found = true
+				break
+			}
+		}
+		if !found && pr.Err() == nil {
+			return nil, fmt.Errorf("invalid auto-walk step %d", step)
+		}
+	}
+
+	return ar, pr.Err()
+}
+
+func (ar *AutoWalkRequest) Encode(pw *protocol.PacketWriter) {
+	if len(ar.Steps) > 255 {
+		pw.SetError(fmt.Errorf("auto-walk route too long: %d steps", len(ar.Steps)))
+		return
+	}
+
+	pw.WriteUint8(byte(C2SAutoWalk))
+	pw.WriteUint8(uint8(len(ar.Steps)))
+	for _, direction := range ar.Steps {
+		step, ok := autoWalkSteps[direction]
+		if !ok {
+			pw.SetError(fmt.Errorf("invalid walk direction %d", direction))
+			return
+		}
+		pw.WriteUint8(step)
+	}
+}
+
+type UseItemRequest struct {
+	Pos      domain.Position
+	ItemId   uint16
+	StackPos uint8
+	Index    uint8 // The container window the item opens in, if it is a container.
+}
+
+func ParseUseItemRequest(pr *protocol.PacketReader) (*UseItemRequest, error) {
+	ur := &UseItemRequest{}
+
+	ur.Pos = readPosition(pr)
+	ur.ItemId = pr.ReadUint16()
+	ur.StackPos = pr.ReadUint8()
+	ur.Index = pr.ReadUint8()
+
+	return ur, pr.Err()
+}
+
+func (ur *UseItemRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SUseItem))
+
+	writePosition(pw, ur.Pos)
+	pw.WriteUint16(ur.ItemId)
+	pw.WriteUint8(ur.StackPos)
+	pw.WriteUint8(ur.Index)
+}
+
+var turnOpcodes = map[domain.Direction]C2SOpcode{
+	domain.North: C2STurnNorth,
+	domain.East:  C2STurnEast,
+	domain.South: C2STurnSouth,
+	domain.West:  C2STurnWest,
+}
+
+// TurnRequest changes the facing direction. Like walking, each direction has its own opcode.
+type TurnRequest struct {
+	Direction domain.Direction
+}
+
+func ParseTurnRequest(opcode C2SOpcode) (*TurnRequest, error) {
+	for direction, turnOpcode := range turnOpcodes {
+		if turnOpcode == opcode {
+			return &TurnRequest{Direction: direction}, nil
+		}
+	}
+	return nil, fmt.Errorf("not a turn opcode 0x%02X", opcode)
+}
+
+func (tr *TurnRequest) Encode(pw *protocol.PacketWriter) {
+	opcode, ok := turnOpcodes[tr.Direction]
+	if !ok {
+		pw.SetError(fmt.Errorf("invalid turn direction %d", tr.Direction))
+		return
+	}
+	pw.WriteUint8(byte(opcode))
+}
+
+// StopAutoWalkRequest interrupts an auto-walk route.
+type StopAutoWalkRequest struct{}
+
+func (sr *StopAutoWalkRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SStopAutoWalk))
+}
+
+// CancelMoveRequest is sent on Escape, it stops attacking and following.
+type CancelMoveRequest struct{}
+
+func (cr *CancelMoveRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SCancelMove))
+}
+
+// MoveThingRequest drags an item (or a creature) from one place to another.
+type MoveThingRequest struct {
+	FromPos      domain.Position
+	ItemId       uint16
+	FromStackPos uint8
+	ToPos        domain.Position
+	Count        uint8
+}
+
+func ParseMoveThingRequest(pr *protocol.PacketReader) (*MoveThingRequest, error) {
+	mr := &MoveThingRequest{}
+
+	mr.FromPos = readPosition(pr)
+	mr.ItemId = pr.ReadUint16()
+	mr.FromStackPos = pr.ReadUint8()
+	mr.ToPos = readPosition(pr)
+	mr.Count = pr.ReadUint8()
+
+	return mr, pr.Err()
+}
+
+func (mr *MoveThingRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SMoveThing))
+
+	writePosition(pw, mr.FromPos)
+	pw.WriteUint16(mr.ItemId)
+	pw.WriteUint8(mr.FromStackPos)
+	writePosition(pw, mr.ToPos)
+	pw.WriteUint8(mr.Count)
+}
+
+type CloseContainerRequest struct {
+	ContainerID uint8
+}
+
+func ParseCloseContainerRequest(pr *protocol.PacketReader) (*CloseContainerRequest, error) {
+	return &CloseContainerRequest{ContainerID: pr.ReadUint8()}, pr.Err()
+}
+
+func (cr *CloseContainerRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SCloseContainer))
+	pw.WriteUint8(cr.ContainerID)
+}
+
+// UpContainerRequest opens the parent of a container in the same window.
+type UpContainerRequest struct {
+	ContainerID uint8
+}
+
+func ParseUpContainerRequest(pr *protocol.PacketReader) (*UpContainerRequest, error) {
+	return &UpContainerRequest{ContainerID: pr.ReadUint8()}, pr.Err()
+}
+
+func (ur *UpContainerRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SUpContainer))
+	pw.WriteUint8(ur.ContainerID)
+}
+
+type RequestChannelsRequest struct{}
+
+func (rr *RequestChannelsRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SRequestChannels))
+}
+
+type OpenChannelRequest struct {
+	ChannelID uint16
+}
+
+func ParseOpenChannelRequest(pr *protocol.PacketReader) (*OpenChannelRequest, error) {
+	return &OpenChannelRequest{ChannelID: pr.ReadUint16()}, pr.Err()
+}
+
+func (or *OpenChannelRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SOpenChannel))
+	pw.WriteUint16(or.ChannelID)
+}
+
+type CloseChannelRequest struct {
+	ChannelID uint16
+}
+
+func ParseCloseChannelRequest(pr *protocol.PacketReader) (*CloseChannelRequest, error) {
+	return &CloseChannelRequest{ChannelID: pr.ReadUint16()}, pr.Err()
+}
+
+func (cr *CloseChannelRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SCloseChannel))
+	pw.WriteUint16(cr.ChannelID)
+}
+
+type OpenPrivateChannelRequest struct {
+	Receiver string
+}
+
+func ParseOpenPrivateChannelRequest(pr *protocol.PacketReader) (*OpenPrivateChannelRequest, error) {
+	return &OpenPrivateChannelRequest{Receiver: pr.ReadString()}, pr.Err()
+}
+
+func (or *OpenPrivateChannelRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SOpenPrivateChannel))
+	pw.WriteString(or.Receiver)
+}
+
+type SetFightModesRequest struct {
+	FightMode  domain.FightMode
+	ChaseMode  domain.ChaseMode
+	SecureMode bool // Prevents attacking unmarked players
+}
+
+func ParseSetFightModesRequest(pr *protocol.PacketReader) (*SetFightModesRequest, error) {
+	fr := &SetFightModesRequest{}
+
+	fr.FightMode = domain.FightMode(pr.ReadUint8())
+	fr.ChaseMode = domain.ChaseMode(pr.ReadUint8())
+	fr.SecureMode = pr.ReadBool()
+
+	return fr, pr.Err()
+}
+
+func (fr *SetFightModesRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SSetFightModes))
+	pw.WriteUint8(uint8(fr.FightMode))
+	pw.WriteUint8(uint8(fr.ChaseMode))
+	pw.WriteBool(fr.SecureMode)
+}
+
+// AttackRequest targets a creature, ID 0 cancels the attack.
+type AttackRequest struct {
+	CreatureID uint32
+}
+
+func ParseAttackRequest(pr *protocol.PacketReader) (*AttackRequest, error) {
+	return &AttackRequest{CreatureID: pr.ReadUint32()}, pr.Err()
+}
+
+func (ar *AttackRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SAttack))
+	pw.WriteUint32(ar.CreatureID)
+}
+
+// FollowRequest follows a creature, ID 0 stops following.
+type FollowRequest struct {
+	CreatureID uint32
+}
+
+func ParseFollowRequest(pr *protocol.PacketReader) (*FollowRequest, error) {
+	return &FollowRequest{CreatureID: pr.ReadUint32()}, pr.Err()
+}
+
+func (fr *FollowRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SFollow))
+	pw.WriteUint32(fr.CreatureID)
+}
+
+// PartyRequest covers the party actions that only carry the other player's creature ID.
+type PartyRequest struct {
+	Action     C2SOpcode // C2SPartyInvite, C2SPartyJoin, C2SPartyRevokeInvite or C2SPartyPassLeadership
+	CreatureID uint32
+}
+
+func ParsePartyRequest(opcode C2SOpcode, pr *protocol.PacketReader) (*PartyRequest, error) {
+	return &PartyRequest{Action: opcode, CreatureID: pr.ReadUint32()}, pr.Err()
+}
+
+func (p *PartyRequest) Encode(pw *protocol.PacketWriter) {
+	switch p.Action {
+	case C2SPartyInvite, C2SPartyJoin, C2SPartyRevokeInvite, C2SPartyPassLeadership:
+	default:
+		pw.SetError(fmt.Errorf("invalid party action 0x%02X", p.Action))
+		return
+	}
+	pw.WriteUint8(byte(p.Action))
+	pw.WriteUint32(p.CreatureID)
+}
+
+type PartyLeaveRequest struct{}
+
+func (p *PartyLeaveRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SPartyLeave))
+}
+
+// RequestOutfitRequest asks the server for the outfit window.
+type RequestOutfitRequest struct{}
+
+func (rr *RequestOutfitRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SRequestOutfit))
+}
+
+// SetOutfitRequest is sent from the outfit window. Items as outfits are not selectable, so LookItem is never set.
+type SetOutfitRequest struct {
+	Outfit domain.Outfit
+}
+
+func ParseSetOutfitRequest(pr *protocol.PacketReader) (*SetOutfitRequest, error) {
+	sr := &SetOutfitRequest{}
+
+	sr.Outfit.LookType = pr.ReadUint16()
+	sr.Outfit.Head = pr.ReadUint8()
+	sr.Outfit.Body = pr.ReadUint8()
+	sr.Outfit.Legs = pr.ReadUint8()
+	sr.Outfit.Feet = pr.ReadUint8()
+
+	return sr, pr.Err()
+}
+
+func (sr *SetOutfitRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SSetOutfit))
+	pw.WriteUint16(sr.Outfit.LookType)
+	pw.WriteUint8(sr.Outfit.Head)
+	pw.WriteUint8(sr.Outfit.Body)
+	pw.WriteUint8(sr.Outfit.Legs)
+	pw.WriteUint8(sr.Outfit.Feet)
+}
+
+type AddVipRequest struct {
+	Name string
+}
+
+func ParseAddVipRequest(pr *protocol.PacketReader) (*AddVipRequest, error) {
+	return &AddVipRequest{Name: pr.ReadString()}, pr.Err()
+}
+
+func (ar *AddVipRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SAddVip))
+	pw.WriteString(ar.Name)
+}
+
+type RemoveVipRequest struct {
+	PlayerID uint32
+}
+
+func ParseRemoveVipRequest(pr *protocol.PacketReader) (*RemoveVipRequest, error) {
+	return &RemoveVipRequest{PlayerID: pr.ReadUint32()}, pr.Err()
+}
+
+func (rr *RemoveVipRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SRemoveVip))
+	pw.WriteUint32(rr.PlayerID)
+}
+
+// PingRequest answers a server ping.
+type PingRequest struct{}
+
+func (p *PingRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SPing))
+}
+
+type LogoutRequest struct{}
+
+func (lr *LogoutRequest) Encode(pw *protocol.PacketWriter) {
+	pw.WriteUint8(byte(C2SLogout))
+}
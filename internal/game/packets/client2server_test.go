@@ -0,0 +1,106 @@
+package packets_test
+
+import (
+	"testing"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoWalkRequest(t *testing.T) {
+	input := []byte{0x64, 0x03, 0x01, 0x03, 0x08}
+
+	pr := protocol.NewPacketReader(input)
+	packet, err := packets.ReadAndParseC2S(pr)
+	require.NoError(t, err)
+
+	expected := &packets.AutoWalkRequest{Steps: []domain.Direction{domain.East, domain.North, domain.SouthEast}}
+	require.Equal(t, expected, packet)
+
+	pw := protocol.NewPacketWriter()
+	expected.Encode(pw)
+	encoded, err := pw.GetBytes()
+	require.NoError(t, err)
+	require.Equal(t, input, encoded)
+}
+
+func TestC2SPackets_RoundTrip(t *testing.T) {
+	pos := domain.Position{X: 32000, Y: 31000, Z: 7}
+	container := domain.NewContainerPosition(1, 3)
+
+	tests := []struct {
+		name   string
+		packet protocol.Encodable
+	}{
+		{"Logout", &packets.LogoutRequest{}},
+		{"Ping", &packets.PingRequest{}},
+		{"AutoWalk", &packets.AutoWalkRequest{Steps: []domain.Direction{domain.West, domain.SouthWest, domain.South}}},
+		{"Walk", &packets.WalkRequest{Direction: domain.NorthWest}},
+		{"StopAutoWalk", &packets.StopAutoWalkRequest{}},
+		{"Turn", &packets.TurnRequest{Direction: domain.West}},
+		{"MoveThing", &packets.MoveThingRequest{FromPos: container, ItemId: 3031, ToPos: pos, Count: 100}},
+		{"UseItem", &packets.UseItemRequest{Pos: pos, ItemId: 1948, StackPos: 1}},
+		{"UseItemWithCrosshair", &packets.UseItemWithCrosshairRequest{FromPos: container, FromItemId: 3003, ToPos: pos, ToItemId: 386, ToStackPos: 2}},
+		{"UseItemOnCreature", &packets.UseItemOnCreatureRequest{FromPos: container, FromItemId: 3160, CreatureID: 0x10000001}},
+		{"CloseContainer", &packets.CloseContainerRequest{ContainerID: 2}},
+		{"UpContainer", &packets.UpContainerRequest{ContainerID: 3}},
+		{"Look", &packets.LookRequest{Pos: pos, ItemId: 100, StackPos: 0}},
+		{"Say", &packets.SayRequest{Class: domain.SpeakSay, Text: "hi"}},
+		{"Say private", &packets.SayRequest{Class: domain.SpeakPrivate, Receiver: "Bubble", Text: "hi"}},
+		{"Say channel", &packets.SayRequest{Class: domain.SpeakChannelY, ChannelID: 5, Text: "hi"}},
+		{"Say report answer", &packets.SayRequest{Class: domain.SpeakRVRAnswer, Receiver: "Bubble", Text: "hi"}},
+		{"RequestChannels", &packets.RequestChannelsRequest{}},
+		{"OpenChannel", &packets.OpenChannelRequest{ChannelID: 5}},
+		{"CloseChannel", &packets.CloseChannelRequest{ChannelID: 5}},
+		{"OpenPrivateChannel", &packets.OpenPrivateChannelRequest{Receiver: "Bubble"}},
+		{"SetFightModes", &packets.SetFightModesRequest{FightMode: domain.FightDefensive, ChaseMode: domain.ChaseFollow, SecureMode: true}},
+		{"Attack", &packets.AttackRequest{CreatureID: 0x40000001}},
+		{"Follow", &packets.FollowRequest{CreatureID: 0x10000002}},
+		{"PartyInvite", &packets.PartyRequest{Action: packets.C2SPartyInvite, CreatureID: 0x10000002}},
+		{"PartyPassLeadership", &packets.PartyRequest{Action: packets.C2SPartyPassLeadership, CreatureID: 0x10000002}},
+		{"PartyLeave", &packets.PartyLeaveRequest{}},
+		{"CancelMove", &packets.CancelMoveRequest{}},
+		{"RequestOutfit", &packets.RequestOutfitRequest{}},
+		{"SetOutfit", &packets.SetOutfitRequest{Outfit: domain.Outfit{LookType: 128, Head: 78, Body: 69, Legs: 58, Feet: 76}}},
+		{"AddVip", &packets.AddVipRequest{Name: "Bubble"}},
+		{"RemoveVip", &packets.RemoveVipRequest{PlayerID: 0x10000002}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pw := protocol.NewPacketWriter()
+			tt.packet.Encode(pw)
+			encoded, err := pw.GetBytes()
+			require.NoError(t, err)
+
+			pr := protocol.NewPacketReader(encoded)
+			parsed, err := packets.ReadAndParseC2S(pr)
+			require.NoError(t, err)
+			require.Equal(t, tt.packet, parsed)
+			require.Zero(t, pr.Remaining(), "trailing bytes")
+		})
+	}
+}
+
+func TestC2SPackets_EncodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet protocol.Encodable
+	}{
+		{"Turn diagonal", &packets.TurnRequest{Direction: domain.NorthEast}},
+		{"Walk invalid", &packets.WalkRequest{Direction: 9}},
+		{"Party without action", &packets.PartyRequest{CreatureID: 1}},
+		{"AutoWalk too long", &packets.AutoWalkRequest{Steps: make([]domain.Direction, 256)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pw := protocol.NewPacketWriter()
+			tt.packet.Encode(pw)
+			_, err := pw.GetBytes()
+			require.Error(t, err)
+		})
+	}
+}
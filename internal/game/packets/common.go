@@ -32,3 +32,9 @@ func readItem(pr *protocol.PacketReader) domain.Item {
 
 	return item
 }
+
+// ItemThing puts an item on a tile stack with the priority the dat gives it.
+func ItemThing(item domain.Item) domain.Thing {
+	thing := assets.Get(item.ID)
+	return domain.ItemThing(item, domain.ItemPriority(thing.IsGround, thing.TopOrder))
+}
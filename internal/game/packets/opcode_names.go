@@ -0,0 +1,125 @@
+package packets
+
+import "fmt"
+
+var s2cOpcodeNames = map[S2COpcode]string{
+	S2CLoginSuccessful:      "LoginSuccessful",
+	S2CLoginAsAdmin:         "LoginAsAdmin",
+	S2CServerClosed:         "ServerClosed",
+	S2CFYIBox:               "FYIBox",
+	S2CSLoginQueue:          "LoginQueue",
+	S2CPing:                 "Ping",
+	S2CMapDescription:       "MapDescription",
+	S2CMapSliceNorth:        "MapSliceNorth",
+	S2CMapSliceEast:         "MapSliceEast",
+	S2CMapSliceSouth:        "MapSliceSouth",
+	S2CMapSliceWest:         "MapSliceWest",
+	S2CUpdateTile:           "UpdateTile",
+	S2CAddTileThing:         "AddTileThing",
+	S2CUpdateTileItem:       "UpdateTileItem",
+	S2CRemoveTileThing:      "RemoveTileThing",
+	S2CMoveCreature:         "MoveCreature",
+	S2COpenContainer:        "OpenContainer",
+	S2CCloseContainer:       "CloseContainer",
+	S2CAddContainerItem:     "AddContainerItem",
+	S2CUpdateContainerItem:  "UpdateContainerItem",
+	S2CRemoveContainerItem:  "RemoveContainerItem",
+	S2CAddInventoryItem:     "AddInventoryItem",
+	S2CRemoveInventoryItem:  "RemoveInventoryItem",
+	S2CTradeOwn:             "TradeOwn",
+	S2CTradeCounter:         "TradeCounter",
+	S2CTradeClose:           "TradeClose",
+	S2CWorldLight:           "WorldLight",
+	S2CMagicEffect:          "MagicEffect",
+	S2CAnimatedText:         "AnimatedText",
+	S2CDistanceShoot:        "DistanceShoot",
+	S2CCreatureSquare:       "CreatureSquare",
+	S2CCreatureHealth:       "CreatureHealth",
+	S2CCreatureLight:        "CreatureLight",
+	S2CCreatureOutfit:       "CreatureOutfit",
+	S2CCreatureSpeed:        "CreatureSpeed",
+	S2CCreatureSkull:        "CreatureSkull",
+	S2CCreatureShield:       "CreatureShield",
+	S2CTextWindow:           "TextWindow",
+	S2CHouseTextWindow:      "HouseTextWindow",
+	S2CPlayerStats:          "PlayerStats",
+	S2CPlayerSkills:         "PlayerSkills",
+	S2CPlayerIcons:          "PlayerIcons",
+	S2CCancelTarget:         "CancelTarget",
+	S2CSay:                  "Say",
+	S2CChannelList:          "ChannelList",
+	S2COpenChannel:          "OpenChannel",
+	S2COpenPrivateChannel:   "OpenPrivateChannel",
+	S2CRuleViolationChannel: "RuleViolationChannel",
+	S2CRuleViolationRemove:  "RuleViolationRemove",
+	S2CRuleViolationCancel:  "RuleViolationCancel",
+	S2CRuleViolationLock:    "RuleViolationLock",
+	S2COpenOwnChannel:       "OpenOwnChannel",
+	S2CCloseChannel:         "CloseChannel",
+	S2CTextMessage:          "TextMessage",
+	S2CCancelWalk:           "CancelWalk",
+	S2CFloorChangeUp:        "FloorChangeUp",
+	S2CFloorChangeDown:      "FloorChangeDown",
+	S2COutfitWindow:         "OutfitWindow",
+	S2CVipAdd:               "VipAdd",
+	S2CVipLogin:             "VipLogin",
+	S2CVipLogout:            "VipLogout",
+}
+
+var c2sOpcodeNames = map[C2SOpcode]string{
+	C2SLogout:               "Logout",
+	C2SPing:                 "Ping",
+	C2SAutoWalk:             "AutoWalk",
+	C2SWalkNorth:            "WalkNorth",
+	C2SWalkEast:             "WalkEast",
+	C2SWalkSouth:            "WalkSouth",
+	C2SWalkWest:             "WalkWest",
+	C2SStopAutoWalk:         "StopAutoWalk",
+	C2SWalkNorthEast:        "WalkNorthEast",
+	C2SWalkSouthEast:        "WalkSouthEast",
+	C2SWalkSouthWest:        "WalkSouthWest",
+	C2SWalkNorthWest:        "WalkNorthWest",
+	C2STurnNorth:            "TurnNorth",
+	C2STurnEast:             "TurnEast",
+	C2STurnSouth:            "TurnSouth",
+	C2STurnWest:             "TurnWest",
+	C2SMoveThing:            "MoveThing",
+	C2SUseItem:              "UseItem",
+	C2SUseItemWithCrosshair: "UseItemWithCrosshair",
+	C2SUseItemOnCreature:    "UseItemOnCreature",
+	C2SCloseContainer:       "CloseContainer",
+	C2SUpContainer:          "UpContainer",
+	C2SLookRequest:          "LookRequest",
+	C2SSay:                  "Say",
+	C2SRequestChannels:      "RequestChannels",
+	C2SOpenChannel:          "OpenChannel",
+	C2SCloseChannel:         "CloseChannel",
+	C2SOpenPrivateChannel:   "OpenPrivateChannel",
+	C2SSetFightModes:        "SetFightModes",
+	C2SAttack:               "Attack",
+	C2SFollow:               "Follow",
+	C2SPartyInvite:          "PartyInvite",
+	C2SPartyJoin:            "PartyJoin",
+	C2SPartyRevokeInvite:    "PartyRevokeInvite",
+	C2SPartyPassLeadership:  "PartyPassLeadership",
+	C2SPartyLeave:           "PartyLeave",
+	C2SCancelMove:           "CancelMove",
+	C2SRequestOutfit:        "RequestOutfit",
+	C2SSetOutfit:            "SetOutfit",
+	C2SAddVip:               "AddVip",
+	C2SRemoveVip:            "RemoveVip",
+}
+
+func (o S2COpcode) String() string {
+	if name, ok := s2cOpcodeNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(0x%02X)", uint8(o))
+}
+
+func (o C2SOpcode) String() string {
+	if name, ok := c2sOpcodeNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(0x%02X)", uint8(o))
+}
@@ -7,38 +7,109 @@ type S2COpcode uint8
 type C2SOpcode uint8
 
 const (
-	S2CLoginSuccessful     S2COpcode = 0x0A
-	S2CLoginAsAdmin        S2COpcode = 0x0B
-	S2CServerClosed        S2COpcode = 0x14
-	S2CSLoginQueue         S2COpcode = 0x16
-	S2CPing                S2COpcode = 0x1E
-	S2CMapDescription      S2COpcode = 0x64
-	S2CMapSliceNorth       S2COpcode = 0x65
-	S2CMapSliceEast        S2COpcode = 0x66
-	S2CMapSliceSouth       S2COpcode = 0x67
-	S2CMapSliceWest        S2COpcode = 0x68
-	S2CAddTileThing        S2COpcode = 0x6A
-	S2CUpdateTileItem      S2COpcode = 0x6B
-	S2CRemoveTileThing     S2COpcode = 0x6C
-	S2CMoveCreature        S2COpcode = 0x6D
-	S2COpenContainer       S2COpcode = 0x6E
-	S2CCloseContainer      S2COpcode = 0x6F
-	S2CAddContainerItem    S2COpcode = 0x70
-	S2CUpdateContainerItem S2COpcode = 0x71
-	S2CRemoveContainerItem S2COpcode = 0x72
-	S2CAddInventoryItem    S2COpcode = 0x78
-	S2CRemoveInventoryItem S2COpcode = 0x79
-	S2CWorldLight          S2COpcode = 0x82
-	S2CMagicEffect         S2COpcode = 0x83
-	S2CCreatureHealth      S2COpcode = 0x8C
-	S2CCreatureLight       S2COpcode = 0x8D
-	S2CPlayerStats         S2COpcode = 0xA0
-	S2CPlayerSkills        S2COpcode = 0xA1
-	S2CPlayerIcons         S2COpcode = 0xA2
-	S2CSay                 S2COpcode = 0xAA
+	S2CLoginSuccessful      S2COpcode = 0x0A
+	S2CLoginAsAdmin         S2COpcode = 0x0B
+	S2CServerClosed         S2COpcode = 0x14
+	S2CFYIBox               S2COpcode = 0x15
+	S2CSLoginQueue          S2COpcode = 0x16
+	S2CPing                 S2COpcode = 0x1E
+	S2CMapDescription       S2COpcode = 0x64
+	S2CMapSliceNorth        S2COpcode = 0x65
+	S2CMapSliceEast         S2COpcode = 0x66
+	S2CMapSliceSouth        S2COpcode = 0x67
+	S2CMapSliceWest         S2COpcode = 0x68
+	S2CUpdateTile           S2COpcode = 0x69
+	S2CAddTileThing         S2COpcode = 0x6A
+	S2CUpdateTileItem       S2COpcode = 0x6B
+	S2CRemoveTileThing      S2COpcode = 0x6C
+	S2CMoveCreature         S2COpcode = 0x6D
+	S2COpenContainer        S2COpcode = 0x6E
+	S2CCloseContainer       S2COpcode = 0x6F
+	S2CAddContainerItem     S2COpcode = 0x70
+	S2CUpdateContainerItem  S2COpcode = 0x71
+	S2CRemoveContainerItem  S2COpcode = 0x72
+	S2CAddInventoryItem     S2COpcode = 0x78
+	S2CRemoveInventoryItem  S2COpcode = 0x79
+	S2CTradeOwn             S2COpcode = 0x7D
+	S2CTradeCounter         S2COpcode = 0x7E
+	S2CTradeClose           S2COpcode = 0x7F
+	S2CWorldLight           S2COpcode = 0x82
+	S2CMagicEffect          S2COpcode = 0x83
+	S2CAnimatedText         S2COpcode = 0x84
+	S2CDistanceShoot        S2COpcode = 0x85
+	S2CCreatureSquare       S2COpcode = 0x86
+	S2CCreatureHealth       S2COpcode = 0x8C
+	S2CCreatureLight        S2COpcode = 0x8D
+	S2CCreatureOutfit       S2COpcode = 0x8E
+	S2CCreatureSpeed        S2COpcode = 0x8F
+	S2CCreatureSkull        S2COpcode = 0x90
+	S2CCreatureShield       S2COpcode = 0x91
+	S2CTextWindow           S2COpcode = 0x96
+	S2CHouseTextWindow      S2COpcode = 0x97
+	S2CPlayerStats          S2COpcode = 0xA0
+	S2CPlayerSkills         S2COpcode = 0xA1
+	S2CPlayerIcons          S2COpcode = 0xA2
+	S2CCancelTarget         S2COpcode = 0xA3
+	S2CSay                  S2COpcode = 0xAA
+	S2CChannelList          S2COpcode = 0xAB
+	S2COpenChannel          S2COpcode = 0xAC
+	S2COpenPrivateChannel   S2COpcode = 0xAD
+	S2CRuleViolationChannel S2COpcode = 0xAE
+	S2CRuleViolationRemove  S2COpcode = 0xAF
+	S2CRuleViolationCancel  S2COpcode = 0xB0
+	S2CRuleViolationLock    S2COpcode = 0xB1
+	S2COpenOwnChannel       S2COpcode = 0xB2
+	S2CCloseChannel         S2COpcode = 0xB3
+	S2CTextMessage          S2COpcode = 0xB4
+	S2CCancelWalk           S2COpcode = 0xB5
+	S2CFloorChangeUp        S2COpcode = 0xBE
+	S2CFloorChangeDown      S2COpcode = 0xBF
+	S2COutfitWindow         S2COpcode = 0xC8
+	S2CVipAdd               S2COpcode = 0xD2
+	S2CVipLogin             S2COpcode = 0xD3
+	S2CVipLogout            S2COpcode = 0xD4
 )
 
 const (
+	C2SLogout               C2SOpcode = 0x14
+	C2SPing                 C2SOpcode = 0x1E
+	C2SAutoWalk             C2SOpcode = 0x64
+	C2SWalkNorth            C2SOpcode = 0x65
+	C2SWalkEast             C2SOpcode = 0x66
+	C2SWalkSouth            C2SOpcode = 0x67
+	C2SWalkWest             C2SOpcode = 0x68
+	C2SStopAutoWalk         C2SOpcode = 0x69
+	C2SWalkNorthEast        C2SOpcode = 0x6A
+	C2SWalkSouthEast        C2SOpcode = 0x6B
+	C2SWalkSouthWest        C2SOpcode = 0x6C
+	C2SWalkNorthWest        C2SOpcode = 0x6D
+	C2STurnNorth            C2SOpcode = 0x6F
+	C2STurnEast             C2SOpcode = 0x70
+	C2STurnSouth            C2SOpcode = 0x71
+	C2STurnWest             C2SOpcode = 0x72
+	C2SMoveThing            C2SOpcode = 0x78
+	C2SUseItem              C2SOpcode = 0x82
 	C2SUseItemWithCrosshair C2SOpcode = 0x83
+	C2SUseItemOnCreature    C2SOpcode = 0x84
+	C2SCloseContainer       C2SOpcode = 0x87
+	C2SUpContainer          C2SOpcode = 0x88
 	C2SLookRequest          C2SOpcode = 0x8C
+	C2SSay                  C2SOpcode = 0x96
+	C2SRequestChannels      C2SOpcode = 0x97
+	C2SOpenChannel          C2SOpcode = 0x98
+	C2SCloseChannel         C2SOpcode = 0x99
+	C2SOpenPrivateChannel   C2SOpcode = 0x9A
+	C2SSetFightModes        C2SOpcode = 0xA0
+	C2SAttack               C2SOpcode = 0xA1
+	C2SFollow               C2SOpcode = 0xA2
+	C2SPartyInvite          C2SOpcode = 0xA3
+	C2SPartyJoin            C2SOpcode = 0xA4
+	C2SPartyRevokeInvite    C2SOpcode = 0xA5
+	C2SPartyPassLeadership  C2SOpcode = 0xA6
+	C2SPartyLeave           C2SOpcode = 0xA7
+	C2SCancelMove           C2SOpcode = 0xBE
+	C2SRequestOutfit        C2SOpcode = 0xD2
+	C2SSetOutfit            C2SOpcode = 0xD3
+	C2SAddVip               C2SOpcode = 0xDC
+	C2SRemoveVip            C2SOpcode = 0xDD
 )
@@ -1,7 +1,11 @@
 package packets
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"os"
+	"strconv"
 	"testing"
 	"z07/internal/tools/sortcon"
 
@@ -20,3 +24,31 @@ func TestOpcodesAreSortedOnDisk(t *testing.T) {
 	assert.Equal(t, string(content), string(sortedContent),
 		"opcodes.go is not sorted. Please run 'go generate ./...' to fix it.")
 }
+
+func TestOpcodesHaveNames(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "opcodes.go", nil, 0)
+	if err != nil {
+		t.Fatalf("could not parse opcodes.go: %v", err)
+	}
+
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			vs := spec.(*ast.ValueSpec)
+			value, err := strconv.ParseUint(vs.Values[0].(*ast.BasicLit).Value, 0, 8)
+			assert.NoError(t, err)
+
+			var name string
+			switch vs.Type.(*ast.Ident).Name {
+			case "S2COpcode":
+				name = S2COpcode(value).String()
+			case "C2SOpcode":
+				name = C2SOpcode(value).String()
+			}
+			assert.NotContains(t, name, "Unknown", "%s has no name in opcode_names.go", vs.Names[0].Name)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+// Package packetstest builds raw server messages for tests of the packages that parse them.
+package packetstest
+
+// SkipTiles encodes an RLE run over n tiles of a map description. A run right after a tile also counts that tile.
+func SkipTiles(n int) []byte {
+	var out []byte
+	for n > 0 {
+		run := min(n, 256)
+		out = append(out, byte(run-1), 0xFF)
+		n -= run
+	}
+	return out
+}
@@ -22,6 +22,17 @@ type InjectablePacket interface {
 	protocol.Encodable
 }
 
+// UnknownOpcodeError is returned for an opcode without a parser.
+// Packets carry no length, so nothing after it in the same message can be read.
+type UnknownOpcodeError struct {
+	Opcode uint8
+	Offset int // Position of the opcode byte in the message
+}
+
+func (e *UnknownOpcodeError) Error() string {
+	return fmt.Sprintf("unknown opcode 0x%02X at offset %d", e.Opcode, e.Offset)
+}
+
 func ReadAndParseS2C(reader *protocol.PacketReader, ctx ParsingContext) (S2CPacket, error) {
 	if reader.Remaining() == 0 {
 		return nil, io.EOF
@@ -42,6 +53,10 @@ func ParseS2CPacket(opcode S2COpcode, pr *protocol.PacketReader, ctx ParsingCont
 	switch opcode {
 	case S2CLoginSuccessful:
 		return ParseLoginResultMessage(pr)
+	case S2CLoginAsAdmin:
+		return ParseLoginAsAdminMsg(pr)
+	case S2CFYIBox:
+		return ParseFYIBoxMsg(pr)
 	case S2CSLoginQueue:
 		return ParseLoginQueueMsg(pr)
 	case S2CMapDescription:
@@ -94,19 +109,130 @@ func ParseS2CPacket(opcode S2COpcode, pr *protocol.PacketReader, ctx ParsingCont
 		return ParsePlayerSkillMsg(pr)
 	case S2CPlayerStats:
 		return ParsePlayerStatsMsg(pr)
+	case S2CUpdateTile:
+		return ParseUpdateTileMsg(pr)
+	case S2CFloorChangeUp:
+		return ParseFloorChangeUp(pr, ctx)
+	case S2CFloorChangeDown:
+		return ParseFloorChangeDown(pr, ctx)
+	case S2CTradeOwn:
+		return ParseTradeItemsMsg(pr, true)
+	case S2CTradeCounter:
+		return ParseTradeItemsMsg(pr, false)
+	case S2CTradeClose:
+		return &TradeCloseMsg{}, nil
+	case S2CAnimatedText:
+		return ParseAnimatedTextMsg(pr)
+	case S2CDistanceShoot:
+		return ParseDistanceShootMsg(pr)
+	case S2CCreatureSquare:
+		return ParseCreatureSquareMsg(pr)
+	case S2CCreatureOutfit:
+		return ParseCreatureOutfitMsg(pr)
+	case S2CCreatureSpeed:
+		return ParseCreatureSpeedMsg(pr)
+	case S2CCreatureSkull:
+		return ParseCreatureSkullMsg(pr)
+	case S2CCreatureShield:
+		return ParseCreatureShieldMsg(pr)
+	case S2CTextWindow:
+		return ParseTextWindowMsg(pr)
+	case S2CHouseTextWindow:
+		return ParseHouseTextWindowMsg(pr)
+	case S2CCancelTarget:
+		return &CancelTargetMsg{}, nil
+	case S2CSay:
+		return ParseSayMsg(pr)
+	case S2CChannelList:
+		return ParseChannelListMsg(pr)
+	case S2COpenChannel:
+		return ParseOpenChannelMsg(pr, false)
+	case S2COpenOwnChannel:
+		return ParseOpenChannelMsg(pr, true)
+	case S2COpenPrivateChannel:
+		return ParseOpenPrivateChannelMsg(pr)
+	case S2CCloseChannel:
+		return ParseCloseChannelMsg(pr)
+	case S2CRuleViolationChannel, S2CRuleViolationRemove, S2CRuleViolationCancel, S2CRuleViolationLock:
+		return ParseRuleViolationMsg(opcode, pr)
+	case S2CTextMessage:
+		return ParseTextMessageMsg(pr)
+	case S2CCancelWalk:
+		return ParseCancelWalkMsg(pr)
+	case S2COutfitWindow:
+		return ParseOutfitWindowMsg(pr)
+	case S2CVipAdd:
+		return ParseVipAddMsg(pr)
+	case S2CVipLogin:
+		return ParseVipStatusMsg(pr, true)
+	case S2CVipLogout:
+		return ParseVipStatusMsg(pr, false)
 
 	default:
-		return nil, fmt.Errorf("unknown opcode 0x%02X", opcode)
+		return nil, &UnknownOpcodeError{Opcode: uint8(opcode), Offset: pr.Offset() - 1}
 	}
 }
 
 func ParseC2SPacket(opcode C2SOpcode, pr *protocol.PacketReader) (C2SPacket, error) {
 	switch opcode {
-	case C2SLookRequest:
-		return ParseLookRequest(pr)
+	case C2SLogout:
+		return &LogoutRequest{}, nil
+	case C2SPing:
+		return &PingRequest{}, nil
+	case C2SAutoWalk:
+		return ParseAutoWalkRequest(pr)
+	case C2SWalkNorth, C2SWalkEast, C2SWalkSouth, C2SWalkWest,
+		C2SWalkNorthEast, C2SWalkSouthEast, C2SWalkSouthWest, C2SWalkNorthWest:
+		return ParseWalkRequest(opcode)
+	case C2SStopAutoWalk:
+		return &StopAutoWalkRequest{}, nil
+	case C2STurnNorth, C2STurnEast, C2STurnSouth, C2STurnWest:
+		return ParseTurnRequest(opcode)
+	case C2SMoveThing:
+		return ParseMoveThingRequest(pr)
+	case C2SUseItem:
+		return ParseUseItemRequest(pr)
 	case C2SUseItemWithCrosshair:
 		return ParseUseItemWithCrosshairRequest(pr)
+	case C2SUseItemOnCreature:
+		return ParseUseItemOnCreatureRequest(pr)
+	case C2SCloseContainer:
+		return ParseCloseContainerRequest(pr)
+	case C2SUpContainer:
+		return ParseUpContainerRequest(pr)
+	case C2SLookRequest:
+		return ParseLookRequest(pr)
+	case C2SSay:
+		return ParseSayRequest(pr)
+	case C2SRequestChannels:
+		return &RequestChannelsRequest{}, nil
+	case C2SOpenChannel:
+		return ParseOpenChannelRequest(pr)
+	case C2SCloseChannel:
+		return ParseCloseChannelRequest(pr)
+	case C2SOpenPrivateChannel:
+		return ParseOpenPrivateChannelRequest(pr)
+	case C2SSetFightModes:
+		return ParseSetFightModesRequest(pr)
+	case C2SAttack:
+		return ParseAttackRequest(pr)
+	case C2SFollow:
+		return ParseFollowRequest(pr)
+	case C2SPartyInvite, C2SPartyJoin, C2SPartyRevokeInvite, C2SPartyPassLeadership:
+		return ParsePartyRequest(opcode, pr)
+	case C2SPartyLeave:
+		return &PartyLeaveRequest{}, nil
+	case C2SCancelMove:
+		return &CancelMoveRequest{}, nil
+	case C2SRequestOutfit:
+		return &RequestOutfitRequest{}, nil
+	case C2SSetOutfit:
+		return ParseSetOutfitRequest(pr)
+	case C2SAddVip:
+		return ParseAddVipRequest(pr)
+	case C2SRemoveVip:
+		return ParseRemoveVipRequest(pr)
 	default:
-		return nil, fmt.Errorf("unknown opcode 0x%02X", opcode)
+		return nil, &UnknownOpcodeError{Opcode: uint8(opcode), Offset: pr.Offset() - 1}
 	}
 }
@@ -0,0 +1,151 @@
+package packets
+
+import (
+	"z07/internal/game/domain"
+	"z07/internal/protocol"
+)
+
+// SayMsg is a creature talking, in the game window or in a channel.
+type SayMsg struct {
+	StatementID uint32
+	Name        string
+	Class       domain.SpeakClass
+	Pos         domain.Position // Only for classes shown on the map
+	ChannelID   uint16          // Only for channel classes
+	ReportTime  uint32          // Only for rule violation reports
+	Text        string
+}
+
+func ParseSayMsg(pr *protocol.PacketReader) (*SayMsg, error) {
+	sm := &SayMsg{}
+
+	sm.StatementID = pr.ReadUint32()
+	sm.Name = pr.ReadString()
+	sm.Class = domain.SpeakClass(pr.ReadUint8())
+	switch {
+	case sm.Class.HasPosition():
+		sm.Pos = readPosition(pr)
+	case sm.Class.IsChannel():
+		sm.ChannelID = pr.ReadUint16()
+	case sm.Class == domain.SpeakRVRChannel:
+		sm.ReportTime = pr.ReadUint32()
+	}
+	sm.Text = pr.ReadString()
+
+	return sm, pr.Err()
+}
+
+type TextMessageMsg struct {
+	Type domain.MessageType
+	Text string
+}
+
+func ParseTextMessageMsg(pr *protocol.PacketReader) (*TextMessageMsg, error) {
+	tm := &TextMessageMsg{}
+
+	tm.Type = domain.MessageType(pr.ReadUint8())
+	tm.Text = pr.ReadString()
+
+	return tm, pr.Err()
+}
+
+type Channel struct {
+	ID   uint16
+	Name string
+}
+
+type ChannelListMsg struct {
+	Channels []Channel
+}
+
+func ParseChannelListMsg(pr *protocol.PacketReader) (*ChannelListMsg, error) {
+	count := pr.ReadUint8()
+
+	cl := &ChannelListMsg{Channels: make([]Channel, 0, count)}
+	for i := 0; i < int(count); i++ {
+		cl.Channels = append(cl.Channels, Channel{
+			ID:   pr.ReadUint16(),
+			Name: pr.ReadString(),
+		})
+	}
+
+	return cl, pr.Err()
+}
+
+// OpenChannelMsg opens a channel tab. Own is set for a private chat channel the player created.
+type OpenChannelMsg struct {
+	Channel
+	Own bool
+}
+
+func ParseOpenChannelMsg(pr *protocol.PacketReader, own bool) (*OpenChannelMsg, error) {
+	oc := &OpenChannelMsg{Own: own}
+
+	oc.ID = pr.ReadUint16()
+	oc.Name = pr.ReadString()
+
+	return oc, pr.Err()
+}
+
+type OpenPrivateChannelMsg struct {
+	Name string
+}
+
+func ParseOpenPrivateChannelMsg(pr *protocol.PacketReader) (*OpenPrivateChannelMsg, error) {
+	return &OpenPrivateChannelMsg{Name: pr.ReadString()}, pr.Err()
+}
+
+type CloseChannelMsg struct {
+	ChannelID uint16
+}
+
+func ParseCloseChannelMsg(pr *protocol.PacketReader) (*CloseChannelMsg, error) {
+	return &CloseChannelMsg{ChannelID: pr.ReadUint16()}, pr.Err()
+}
+
+// RuleViolationMsg covers the gamemaster report channel updates.
+// Name is the reporter, empty for the channel open and lock updates.
+type RuleViolationMsg struct {
+	Opcode    S2COpcode
+	ChannelID uint16 // Only for S2CRuleViolationChannel
+	Name      string // Only for S2CRuleViolationRemove and S2CRuleViolationCancel
+}
+
+func ParseRuleViolationMsg(opcode S2COpcode, pr *protocol.PacketReader) (*RuleViolationMsg, error) {
+	rv := &RuleViolationMsg{Opcode: opcode}
+
+	switch opcode {
+	case S2CRuleViolationChannel:
+		rv.ChannelID = pr.ReadUint16()
+	case S2CRuleViolationRemove, S2CRuleViolationCancel:
+		rv.Name = pr.ReadString()
+	}
+
+	return rv, pr.Err()
+}
+
+type VipAddMsg struct {
+	PlayerID uint32
+	Name     string
+	Online   bool
+}
+
+func ParseVipAddMsg(pr *protocol.PacketReader) (*VipAddMsg, error) {
+	va := &VipAddMsg{}
+
+	va.PlayerID = pr.ReadUint32()
+	va.Name = pr.ReadString()
+	va.Online = pr.ReadBool()
+
+	return va, pr.Err()
+}
+
+// VipStatusMsg is a VIP logging in or out.
+type VipStatusMsg struct {
+	PlayerID uint32
+	Online   bool
+}
+
+func ParseVipStatusMsg(pr *protocol.PacketReader, online bool) (*VipStatusMsg, error) {
+	return &VipStatusMsg{PlayerID: pr.ReadUint32(), Online: online}, pr.Err()
+}
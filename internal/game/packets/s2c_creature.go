@@ -0,0 +1,105 @@
+package packets
+
+import (
+	"z07/internal/game/domain"
+	"z07/internal/protocol"
+)
+
+type CreatureOutfitMsg struct {
+	CreatureID uint32
+	Outfit     domain.Outfit
+}
+
+func ParseCreatureOutfitMsg(pr *protocol.PacketReader) (*CreatureOutfitMsg, error) {
+	co := &CreatureOutfitMsg{}
+
+	co.CreatureID = pr.ReadUint32()
+	co.Outfit = readOutfit(pr)
+
+	return co, pr.Err()
+}
+
+type CreatureSpeedMsg struct {
+	CreatureID uint32
+	Speed      uint16
+}
+
+func ParseCreatureSpeedMsg(pr *protocol.PacketReader) (*CreatureSpeedMsg, error) {
+	cs := &CreatureSpeedMsg{}
+
+	cs.CreatureID = pr.ReadUint32()
+	cs.Speed = pr.ReadUint16()
+
+	return cs, pr.Err()
+}
+
+type CreatureSkullMsg struct {
+	CreatureID uint32
+	Skull      domain.Skull
+}
+
+func ParseCreatureSkullMsg(pr *protocol.PacketReader) (*CreatureSkullMsg, error) {
+	cs := &CreatureSkullMsg{}
+
+	cs.CreatureID = pr.ReadUint32()
+	cs.Skull = domain.Skull(pr.ReadUint8())
+
+	return cs, pr.Err()
+}
+
+type CreatureShieldMsg struct {
+	CreatureID uint32
+	Shield     domain.PartyShield
+}
+
+func ParseCreatureShieldMsg(pr *protocol.PacketReader) (*CreatureShieldMsg, error) {
+	cs := &CreatureShieldMsg{}
+
+	cs.CreatureID = pr.ReadUint32()
+	cs.Shield = domain.PartyShield(pr.ReadUint8())
+
+	return cs, pr.Err()
+}
+
+// CreatureSquareMsg flashes a coloured square around a creature, e.g. when it attacks the player.
+type CreatureSquareMsg struct {
+	CreatureID uint32
+	Color      uint8
+}
+
+func ParseCreatureSquareMsg(pr *protocol.PacketReader) (*CreatureSquareMsg, error) {
+	cs := &CreatureSquareMsg{}
+
+	cs.CreatureID = pr.ReadUint32()
+	cs.Color = pr.ReadUint8()
+
+	return cs, pr.Err()
+}
+
+// CancelWalkMsg rejects a step, the player keeps facing Direction.
+type CancelWalkMsg struct {
+	Direction domain.Direction
+}
+
+func ParseCancelWalkMsg(pr *protocol.PacketReader) (*CancelWalkMsg, error) {
+	return &CancelWalkMsg{Direction: domain.Direction(pr.ReadUint8())}, pr.Err()
+}
+
+// CancelTargetMsg clears the attack or follow target, e.g. when the creature left the screen.
+type CancelTargetMsg struct{}
+
+type OutfitWindowMsg struct {
+	Current       domain.Outfit
+	FirstLookType uint16
+	LastLookType  uint16
+}
+
+func ParseOutfitWindowMsg(pr *protocol.PacketReader) (*OutfitWindowMsg, error) {
+	ow := &OutfitWindowMsg{}
+
+	ow.Current = readOutfit(pr)
+	ow.FirstLookType = pr.ReadUint16()
+	ow.LastLookType = pr.ReadUint16()
+
+	return ow, pr.Err()
+}
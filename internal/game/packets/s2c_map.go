@@ -10,14 +10,28 @@ const (
 	ClientViewportX = 8
 	ClientViewportY = 6
 
-	TileDataCreatureKnown   = 0x62 // 97
-	TileDataCreatureUnknown = 0x61 // 98
+	TileDataCreatureKnown   = 0x62 // 98
+	TileDataCreatureUnknown = 0x61 // 97
 	TileDataTurnCreature    = 0x63 // 99
+
+	seaFloor          = 7
+	undergroundFloor  = 8
+	awareUndergroundZ = 2 // Floors visible above and below when underground
+	maxFloor          = 15
 )
 
 type MapDescriptionMsg struct {
 	PlayerPos domain.Position
 	Tiles     map[domain.Position]*domain.Tile
+	Creatures []CreatureInMap
+}
+
+// CreatureInMap is a creature description embedded in a tile.
+// A known creature (0x62) only carries its ID, so Name is empty in that case.
+type CreatureInMap struct {
+	Known    bool
+	RemoveID uint32 // The ID the client should forget to free a slot in its known creatures list.
+	Creature domain.Creature
 }
 
 func ParseMove(pr *protocol.PacketReader, ctx ParsingContext, direction domain.Direction) (*MapDescriptionMsg, error) {
@@ -54,14 +68,109 @@ func ParseMove(pr *protocol.PacketReader, ctx ParsingContext, direction domain.D
 		width = 1
 	}
 
-	tiles, err := parseMapDescription(pr, x, y, z, width, height)
+	tiles, creatures, err := parseMapDescription(pr, x, y, z, width, height)
 	if err != nil {
 		return nil, err
 	}
 	msg.Tiles = tiles
+	msg.Creatures = creatures
+	return msg, nil
+}
+
+// FloorChangeMsg follows a MoveCreature when the player itself went up or down a floor.
+// It only carries the floors that became visible, the rest of the view is already known.
+type FloorChangeMsg struct {
+	Up bool
+	MapDescriptionMsg
+}
+
+// ParseFloorChangeUp reads the floors that come into view after going up.
+// Surfacing to floor 7 reveals all floors above, otherwise only the one two floors up.
+func ParseFloorChangeUp(pr *protocol.PacketReader, ctx ParsingContext) (*FloorChangeMsg, error) {
+	pos := ctx.PlayerPosition
+	pos.Z--
+
+	msg := &FloorChangeMsg{Up: true}
+	x := int(pos.X) - ClientViewportX
+	y := int(pos.Y) - ClientViewportY
+	width, height := ClientViewportX*2+2, ClientViewportY*2+2
+
+	var err error
+	switch {
+	case pos.Z == seaFloor:
+		msg.Tiles, msg.Creatures, err = parseFloors(pr, x, y, width, height, seaFloor-awareUndergroundZ, 0, seaFloor+1)
+	case pos.Z > seaFloor:
+		z := int(pos.Z) - awareUndergroundZ
+		msg.Tiles, msg.Creatures, err = parseFloors(pr, x, y, width, height, z, z, z+3)
+	default:
+		msg.Tiles = make(map[domain.Position]*domain.Tile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The camera follows the perspective shift of the new floor.
+	pos.X++
+	pos.Y++
+	msg.PlayerPos = pos
+	return msg, nil
+}
+
+// ParseFloorChangeDown reads the floors that come into view after going down.
+// Entering the underground reveals floors 8 to 10, deeper only the one two floors down.
+func ParseFloorChangeDown(pr *protocol.PacketReader, ctx ParsingContext) (*FloorChangeMsg, error) {
+	pos := ctx.PlayerPosition
+	pos.Z++
+
+	msg := &FloorChangeMsg{Up: false}
+	x := int(pos.X) - ClientViewportX
+	y := int(pos.Y) - ClientViewportY
+	width, height := ClientViewportX*2+2, ClientViewportY*2+2
+
+	var err error
+	switch {
+	case pos.Z == undergroundFloor:
+		msg.Tiles, msg.Creatures, err = parseFloors(pr, x, y, width, height, undergroundFloor, undergroundFloor+awareUndergroundZ, seaFloor)
+	case pos.Z > undergroundFloor && pos.Z < maxFloor-1:
+		z := int(pos.Z) + awareUndergroundZ
+		msg.Tiles, msg.Creatures, err = parseFloors(pr, x, y, width, height, z, z, z-3)
+	default:
+		msg.Tiles = make(map[domain.Position]*domain.Tile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pos.X--
+	pos.Y--
+	msg.PlayerPos = pos
 	return msg, nil
 }
 
+// UpdateTileMsg replaces a whole tile. Tile is nil when the tile became empty.
+type UpdateTileMsg struct {
+	Pos       domain.Position
+	Tile      *domain.Tile
+	Creatures []CreatureInMap
+}
+
+func ParseUpdateTileMsg(pr *protocol.PacketReader) (*UpdateTileMsg, error) {
+	msg := &UpdateTileMsg{Pos: readPosition(pr)}
+
+	val, err := pr.PeekUint16()
+	if err != nil {
+		return nil, err
+	}
+	if val == 0xFF01 {
+		_ = pr.ReadUint16()
+		return msg, nil
+	}
+
+	msg.Tile, msg.Creatures = parseTile(pr, msg.Pos)
+	_ = pr.ReadUint16() // 0xFF00 end of tile marker
+	return msg, pr.Err()
+}
+
 func ParseMapDescriptionMsg(pr *protocol.PacketReader) (*MapDescriptionMsg, error) {
 	msg := &MapDescriptionMsg{
 		PlayerPos: readPosition(pr),
@@ -71,28 +180,40 @@ func ParseMapDescriptionMsg(pr *protocol.PacketReader) (*MapDescriptionMsg, erro
 	var y = int(msg.PlayerPos.Y) - ClientViewportY
 	var z = int(msg.PlayerPos.Z)
 
-	tiles, err := parseMapDescription(pr, x, y, z, ClientViewportX*2+2, ClientViewportY*2+2)
+	tiles, creatures, err := parseMapDescription(pr, x, y, z, ClientViewportX*2+2, ClientViewportY*2+2)
 	if err != nil {
 		return nil, err
 	}
 	msg.Tiles = tiles
+	msg.Creatures = creatures
 	return msg, err
 }
 
-func parseMapDescription(pr *protocol.PacketReader, x, y, z, width, height int) (map[domain.Position]*domain.Tile, error) {
-	tiles := make(map[domain.Position]*domain.Tile)
-
+func parseMapDescription(pr *protocol.PacketReader, x, y, z, width, height int) (map[domain.Position]*domain.Tile, []CreatureInMap, error) {
 	// 2. Determine Z-Range
 	// If on surface (z<=7), draw from 7 down to 0.
-	// If underground (z>7), draw from z-2 to z+2.
-	var startZ, endZ, zStep int
-	if z > 7 {
-		startZ = max(z-2, 0)
-		endZ = z + 2 // TODO: Clamp to Max?
-		zStep = 1
+	// If underground (z>7), draw from z-2 to z+2, the server stops at the deepest floor.
+	var startZ, endZ int
+	if z > seaFloor {
+		startZ = max(z-awareUndergroundZ, 0)
+		endZ = min(z+awareUndergroundZ, maxFloor)
 	} else {
-		startZ = 7
+		startZ = seaFloor
 		endZ = 0
+	}
+
+	return parseFloors(pr, x, y, width, height, startZ, endZ, z)
+}
+
+// parseFloors reads the floors from startZ to endZ (in either direction) as one RLE stream,
+// a skip may run over into the next floor.
+// perspectiveZ is the floor the view is centered on, each floor above it is shifted by one tile to the south-east.
+func parseFloors(pr *protocol.PacketReader, x, y, width, height, startZ, endZ, perspectiveZ int) (map[domain.Position]*domain.Tile, []CreatureInMap, error) {
+	tiles := make(map[domain.Position]*domain.Tile)
+	var creatures []CreatureInMap
+
+	zStep := 1
+	if endZ < startZ {
 		zStep = -1
 	}
 
@@ -107,7 +228,7 @@ func parseMapDescription(pr *protocol.PacketReader, x, y, z, width, height int)
 		// <  0xFF00 means TILE (and this is the Ground ID)
 		val, err := pr.PeekUint16()
 		if err != nil {
-			return nil, fmt.Errorf("EOF peeking token at Floor Z=%d, TileIndex=%d", currentZ, tilesProcessed)
+			return nil, nil, fmt.Errorf("EOF peeking token at Floor Z=%d, TileIndex=%d", currentZ, tilesProcessed)
 		}
 
 		if val >= 0xFF00 {
@@ -121,7 +242,7 @@ func parseMapDescription(pr *protocol.PacketReader, x, y, z, width, height int)
 
 			// Calculate perspective offset for this floor
 			// Tibia shifts the view when looking at lower floors
-			offsetZ := z - currentZ
+			offsetZ := perspectiveZ - currentZ
 
 			// Calculate actual X,Y based on linear index
 			// Tibia loop order: for(x) { for(y) }
@@ -134,15 +255,16 @@ func parseMapDescription(pr *protocol.PacketReader, x, y, z, width, height int)
 				Z: uint8(currentZ),
 			}
 
-			tile := parseTile(pr, tilePos)
+			tile, tileCreatures := parseTile(pr, tilePos)
 			tiles[tilePos] = tile
+			creatures = append(creatures, tileCreatures...)
 		}
 
 		// skip tiles
 		for tilesProcessed >= tilesPerFloor {
 			// 1. Check if we are done with the entire volume
 			if currentZ == endZ {
-				return tiles, nil
+				return tiles, creatures, nil
 			}
 
 			// 2. Move to next floor
@@ -153,15 +275,16 @@ func parseMapDescription(pr *protocol.PacketReader, x, y, z, width, height int)
 	}
 }
 
-func parseTile(pr *protocol.PacketReader, tilePos domain.Position) *domain.Tile {
-	// 1. Setup the Tile struct
+func parseTile(pr *protocol.PacketReader, tilePos domain.Position) (*domain.Tile, []CreatureInMap) {
+	// Things arrive in stack order, creatures included, so the stack is kept as it is sent.
 	t := &domain.Tile{
 		Position: tilePos,
-		Items:    make([]domain.Item, 0, 4), // Pre-allocate small cap for performance
+		Things:   make([]domain.Thing, 0, 4), // Pre-allocate small cap for performance
 	}
+	var creatures []CreatureInMap
 
 	groundItem := readItem(pr)
-	t.Items = append(t.Items, groundItem)
+	t.Things = append(t.Things, ItemThing(groundItem))
 
 	// 3. Loop: Read Items on top of the ground
 	// We read until we hit a "Skip" marker (>= 0xFF00) which belongs to the NEXT tile.
@@ -173,79 +296,77 @@ func parseTile(pr *protocol.PacketReader, tilePos domain.Position) *domain.Tile
 		// - Error/EOF
 		// - Value is >= 0xFF00 (This is a Skip/RLE marker for the map loop)
 		if err != nil || nextVal >= 0xFF00 {
-			// fmt.Println("End of Tile")
 			break
 		}
 
 		if nextVal == TileDataCreatureKnown || nextVal == TileDataCreatureUnknown {
 			// It is a CREATURE, not an ITEM.
-			err := readCreatureInMap(pr)
+			creature, err := readCreatureInMap(pr)
 			if err != nil {
-				// fmt.Printf("Error reading creature in map at tile %v: %v\n", pos, err)
-				return &domain.Tile{}
+				return &domain.Tile{}, creatures
 			}
+			creature.Creature.Pos = tilePos
+			creatures = append(creatures, creature)
+			t.Things = append(t.Things, domain.CreatureThing(creature.Creature.ID))
 			continue
 		}
 
 		item := readItem(pr)
-		t.Items = append(t.Items, item)
+		t.Things = append(t.Things, ItemThing(item))
 	}
 
-	return t
+	return t, creatures
 }
 
-func readCreatureInMap(pr *protocol.PacketReader) error {
+func readCreatureInMap(pr *protocol.PacketReader) (CreatureInMap, error) {
+	cim := CreatureInMap{}
+
 	// 1. Read Marker (We already peeked it, but we must consume it)
 	marker := pr.ReadUint16()
 
 	// 2. Handle ID / Name logic
 	switch marker {
 	case TileDataCreatureKnown:
-		_ = pr.ReadUint32() // ID
+		cim.Known = true
+		cim.Creature.ID = pr.ReadUint32()
 	case TileDataCreatureUnknown:
-		_ = pr.ReadUint32() // The id to remove from knowns, it is there to free some slot from known creatures list.
-		_ = pr.ReadUint32() // ID
-		_ = pr.ReadString() // Name
+		cim.RemoveID = pr.ReadUint32() // The id to remove from knowns, it is there to free some slot from known creatures list.
+		cim.Creature.ID = pr.ReadUint32()
+		cim.Creature.Name = pr.ReadString()
 	default:
-		return fmt.Errorf("unknown creature marker: 0x%X", marker)
+		return cim, fmt.Errorf("unknown creature marker: 0x%X", marker)
 	}
 
-	// Health
-	_ = pr.ReadUint8()
-
-	// Direction
-	_ = pr.ReadUint8()
+	c := &cim.Creature
+	c.Visible = true
+	c.HealthPercent = pr.ReadUint8()
+	c.Direction = domain.Direction(pr.ReadUint8())
+	c.Outfit = readOutfit(pr)
 
-	// Outfit
-	if err := readOutfit(pr); err != nil {
-		return err
-	}
+	c.LightLevel = pr.ReadUint8()
+	c.LightColor = pr.ReadUint8()
 
-	// Light
-	_ = pr.ReadUint8() // Light Level
-	_ = pr.ReadUint8() // Light Color
+	c.Speed = pr.ReadUint16()
 
-	// Speed
-	_ = pr.ReadUint16()
+	c.Skull = domain.Skull(pr.ReadUint8())
+	c.Shield = domain.PartyShield(pr.ReadUint8())
 
-	// Skull & Party
-	_ = pr.ReadUint8() // Skull
-	_ = pr.ReadUint8() // Party Shield
-
-	return nil
+	return cim, pr.Err()
 }
 
-func readOutfit(pr *protocol.PacketReader) error {
-	lookType := pr.ReadUint16()
+func readOutfit(pr *protocol.PacketReader) domain.Outfit {
+	outfit := domain.Outfit{
+		LookType: pr.ReadUint16(),
+	}
 
-	if lookType != 0 {
-		_ = pr.ReadUint8() // Head
-		_ = pr.ReadUint8() // Body
-		_ = pr.ReadUint8() // Legs
-		_ = pr.ReadUint8() // Feet
+	if outfit.LookType != 0 {
+		outfit.Head = pr.ReadUint8()
+		outfit.Body = pr.ReadUint8()
+		outfit.Legs = pr.ReadUint8()
+		outfit.Feet = pr.ReadUint8()
 	} else {
 		// Item Outfit (Chameleon Rune, etc.)
-		_ = pr.ReadUint16() // Look Item ID
+		outfit.LookItem = pr.ReadUint16()
 	}
-	return nil
+	return outfit
 }
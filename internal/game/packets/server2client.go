@@ -47,6 +47,9 @@ type RemoveTileThingMsg struct {
 type AddTileThingMsg struct {
 	Pos  domain.Position
 	Item domain.Item
+
+	// Creature is set instead of Item when a creature steps onto the tile.
+	Creature *CreatureInMap
 }
 
 type CreatureLightMsg struct {
@@ -66,7 +69,7 @@ type CreatureHealthMsg struct {
 }
 
 type PlayerIconsMsg struct {
-	Icons uint8
+	Icons domain.Icons
 }
 
 type AddInventoryItemMsg struct {
@@ -78,10 +81,6 @@ type RemoveInventoryItemMsg struct {
 	Slot domain.EquipmentSlot
 }
 
-type SayMsg struct {
-	Slot uint8
-}
-
 func ParseLoginResultMessage(pr *protocol.PacketReader) (*LoginResponse, error) {
 	lr := &LoginResponse{}
 
@@ -203,7 +202,7 @@ func (cr *CreatureLightMsg) Encode(pw *protocol.PacketWriter) {
 
 func ParsePlayerIcons(pr *protocol.PacketReader) (*PlayerIconsMsg, error) {
 	pi := &PlayerIconsMsg{}
-	pi.Icons = pr.ReadUint8()
+	pi.Icons = domain.Icons(pr.ReadUint8())
 
 	return pi, nil
 }
@@ -218,6 +217,65 @@ func ParseServerClosedMsg(pr *protocol.PacketReader) (*ServerClosedMsg, error) {
 	return scm, nil
 }
 
+// LoginAsAdminMsg follows the login of a gamemaster, one flag per rule violation reason
+// says which actions the report dialog offers for it.
+type LoginAsAdminMsg struct {
+	ViolationActions [32]uint8
+}
+
+func ParseLoginAsAdminMsg(pr *protocol.PacketReader) (*LoginAsAdminMsg, error) {
+	la := &LoginAsAdminMsg{}
+	for i := range la.ViolationActions {
+		la.ViolationActions[i] = pr.ReadUint8()
+	}
+	return la, pr.Err()
+}
+
+// FYIBoxMsg is a message the client shows in a dialog box.
+type FYIBoxMsg struct {
+	Text string
+}
+
+func ParseFYIBoxMsg(pr *protocol.PacketReader) (*FYIBoxMsg, error) {
+	return &FYIBoxMsg{Text: pr.ReadString()}, pr.Err()
+}
+
+// TextWindowMsg opens the text of a readable or writable item, e.g. a letter or a book.
+type TextWindowMsg struct {
+	WindowID  uint32 // Sent back with the edited text
+	ItemID    uint16
+	MaxLength uint16 // 0 for a text that can only be read
+	Text      string
+}
+
+func ParseTextWindowMsg(pr *protocol.PacketReader) (*TextWindowMsg, error) {
+	tw := &TextWindowMsg{}
+
+	tw.WindowID = pr.ReadUint32()
+	tw.ItemID = pr.ReadUint16()
+	tw.MaxLength = pr.ReadUint16()
+	tw.Text = pr.ReadString()
+
+	return tw, pr.Err()
+}
+
+// HouseTextWindowMsg opens a house list for editing, e.g. the guests or the people allowed through a door.
+type HouseTextWindowMsg struct {
+	ListID   uint8
+	WindowID uint32
+	Text     string
+}
+
+func ParseHouseTextWindowMsg(pr *protocol.PacketReader) (*HouseTextWindowMsg, error) {
+	ht := &HouseTextWindowMsg{}
+
+	ht.ListID = pr.ReadUint8()
+	ht.WindowID = pr.ReadUint32()
+	ht.Text = pr.ReadString()
+
+	return ht, pr.Err()
+}
+
 func ParseAddTileThingMsg(pr *protocol.PacketReader) (*AddTileThingMsg, error) {
 	ati := &AddTileThingMsg{}
 	ati.Pos.X = pr.ReadUint16()
@@ -225,9 +283,13 @@ func ParseAddTileThingMsg(pr *protocol.PacketReader) (*AddTileThingMsg, error) {
 	ati.Pos.Z = pr.ReadUint8()
 
 	itemId, _ := pr.PeekUint16()
-	if itemId == 97 || itemId == 98 {
-		// TODO do something with it
-		readCreatureInMap(pr)
+	if itemId == TileDataCreatureKnown || itemId == TileDataCreatureUnknown {
+		creature, err := readCreatureInMap(pr)
+		if err != nil {
+			return nil, err
+		}
+		creature.Creature.Pos = ati.Pos
+		ati.Creature = &creature
 	} else {
 		ati.Item = readItem(pr)
 	}
@@ -340,15 +402,35 @@ type UpdateTileItemMsg struct {
 	Item     domain.Item
 }
 
-func ParseUpdateTileItemMsg(pr *protocol.PacketReader) (*UpdateTileItemMsg, error) {
+// CreatureTurnMsg is sent through the UpdateTileItem opcode when a creature changes direction.
+type CreatureTurnMsg struct {
+	Position   domain.Position
+	Stackpos   uint8
+	CreatureID uint32
+	Direction  domain.Direction
+}
 
-	utim := &UpdateTileItemMsg{}
+func ParseUpdateTileItemMsg(pr *protocol.PacketReader) (S2CPacket, error) {
+	position := readPosition(pr)
+	stackpos := pr.ReadUint8()
 
-	utim.Position = readPosition(pr)
-	utim.Stackpos = pr.ReadUint8()
-	utim.Item = readItem(pr)
+	thingId, _ := pr.PeekUint16()
+	if thingId == TileDataTurnCreature {
+		_ = pr.ReadUint16() // Consume marker
 
-	// TODO - Item can be creature !!
+		return &CreatureTurnMsg{
+			Position:   position,
+			Stackpos:   stackpos,
+			CreatureID: pr.ReadUint32(),
+			Direction:  domain.Direction(pr.ReadUint8()),
+		}, pr.Err()
+	}
+
+	utim := &UpdateTileItemMsg{
+		Position: position,
+		Stackpos: stackpos,
+		Item:     readItem(pr),
+	}
 
 	return utim, nil
 }
@@ -370,17 +452,7 @@ func ParsePlayerSkillMsg(pr *protocol.PacketReader) (*PlayerSkillsMsg, error) {
 }
 
 type PlayerStatsMsg struct {
-	Health            uint16
-	MaxHealth         uint16
-	FreeCapacity      uint16
-	Experience        uint32
-	Level             uint16
-	LevelPercent      uint8
-	Mana              uint16
-	MaxMana           uint16
-	MagicLevel        uint8
-	MagicLevelPercent uint8
-	Soul              uint8
+	domain.Stats
 }
 
 func ParsePlayerStatsMsg(pr *protocol.PacketReader) (*PlayerStatsMsg, error) {
@@ -388,7 +460,7 @@ func ParsePlayerStatsMsg(pr *protocol.PacketReader) (*PlayerStatsMsg, error) {
 
 	psm.Health = pr.ReadUint16()
 	psm.MaxHealth = pr.ReadUint16()
-	psm.FreeCapacity = pr.ReadUint16()
+	psm.Capacity = pr.ReadUint16()
 	psm.Experience = pr.ReadUint32()
 	psm.Level = pr.ReadUint16()
 	psm.LevelPercent = pr.ReadUint8()
@@ -418,3 +490,57 @@ func ParseLoginQueueMsg(pr *protocol.PacketReader) (*LoginQueueMsg, error) {
 	lqm.RetryTimeSeconds = pr.ReadUint8()
 	return lqm, pr.Err()
 }
+
+type AnimatedTextMsg struct {
+	Pos   domain.Position
+	Color uint8
+	Text  string
+}
+
+func ParseAnimatedTextMsg(pr *protocol.PacketReader) (*AnimatedTextMsg, error) {
+	at := &AnimatedTextMsg{}
+
+	at.Pos = readPosition(pr)
+	at.Color = pr.ReadUint8()
+	at.Text = pr.ReadString()
+
+	return at, pr.Err()
+}
+
+type DistanceShootMsg struct {
+	From domain.Position
+	To   domain.Position
+	Type uint8
+}
+
+func ParseDistanceShootMsg(pr *protocol.PacketReader) (*DistanceShootMsg, error) {
+	ds := &DistanceShootMsg{}
+
+	ds.From = readPosition(pr)
+	ds.To = readPosition(pr)
+	ds.Type = pr.ReadUint8()
+
+	return ds, pr.Err()
+}
+
+// TradeItemsMsg is one side of a trade window. Own is set for the player's offer.
+type TradeItemsMsg struct {
+	Own   bool
+	Name  string
+	Items []domain.Item
+}
+
+func ParseTradeItemsMsg(pr *protocol.PacketReader, own bool) (*TradeItemsMsg, error) {
+	ti := &TradeItemsMsg{Own: own}
+
+	ti.Name = pr.ReadString()
+	count := pr.ReadUint8()
+	ti.Items = make([]domain.Item, 0, count)
+	for i := 0; i < int(count); i++ {
+		ti.Items = append(ti.Items, readItem(pr))
+	}
+
+	return ti, pr.Err()
+}
+
+type TradeCloseMsg struct{}
@@ -2,7 +2,9 @@ package packets_test
 
 import (
 	"testing"
+	"z07/internal/game/domain"
 	"z07/internal/game/packets"
+	"z07/internal/game/packets/packetstest"
 	"z07/internal/protocol"
 
 	"github.com/stretchr/testify/require"
@@ -16,3 +18,261 @@ func TestParseMagicEffect(t *testing.T) {
 	require.NoError(t, err)
 	require.IsType(t, &packets.MagicEffect{}, effect)
 }
+
+func TestParseAddTileThingMsg_UnknownCreature(t *testing.T) {
+	input := []byte{
+		0x00, 0x7E, 0x00, 0x7E, 0x07, // Position
+		0x61, 0x00, // Unknown creature marker
+		0x15, 0x00, 0x00, 0x40, // Remove ID
+		0x2A, 0x00, 0x00, 0x40, // Creature ID
+		0x03, 0x00, 'R', 'a', 't', // Name
+		0x64,       // Health
+		0x02,       // Direction
+		0x15, 0x00, // Look type
+		0x01, 0x02, 0x03, 0x04, // Head, Body, Legs, Feet
+		0x00, 0x00, // Light level and color
+		0xDC, 0x00, // Speed
+		0x00, 0x00, // Skull and party shield
+	}
+	pr := protocol.NewPacketReader(input)
+
+	msg, err := packets.ParseAddTileThingMsg(pr)
+	require.NoError(t, err)
+	require.NotNil(t, msg.Creature)
+	require.Zero(t, pr.Remaining())
+
+	require.False(t, msg.Creature.Known)
+	require.Equal(t, uint32(0x40000015), msg.Creature.RemoveID)
+
+	creature := msg.Creature.Creature
+	require.Equal(t, uint32(0x4000002A), creature.ID)
+	require.Equal(t, "Rat", creature.Name)
+	require.Equal(t, domain.Position{X: 0x7E00, Y: 0x7E00, Z: 7}, creature.Pos)
+	require.Equal(t, uint8(100), creature.HealthPercent)
+	require.Equal(t, domain.South, creature.Direction)
+	require.Equal(t, domain.Outfit{LookType: 21, Head: 1, Body: 2, Legs: 3, Feet: 4}, creature.Outfit)
+	require.Equal(t, uint16(220), creature.Speed)
+	require.True(t, creature.IsMonster())
+	require.True(t, creature.Visible)
+}
+
+func TestParsePlayerStatsMsg(t *testing.T) {
+	input := []byte{
+		0x96, 0x00, // Health
+		0xC8, 0x00, // Max Health
+		0x90, 0x01, // Capacity
+		0x10, 0x27, 0x00, 0x00, // Experience
+		0x08, 0x00, // Level
+		0x32,       // Level Percent
+		0x1E, 0x00, // Mana
+		0x3C, 0x00, // Max Mana
+		0x03, // Magic Level
+		0x0A, // Magic Level Percent
+		0x64, // Soul
+	}
+	pr := protocol.NewPacketReader(input)
+
+	msg, err := packets.ParsePlayerStatsMsg(pr)
+	require.NoError(t, err)
+	require.Zero(t, pr.Remaining())
+
+	require.Equal(t, domain.Stats{
+		Health:            150,
+		MaxHealth:         200,
+		Capacity:          400,
+		Experience:        10000,
+		Level:             8,
+		LevelPercent:      50,
+		Mana:              30,
+		MaxMana:           60,
+		MagicLevel:        3,
+		MagicLevelPercent: 10,
+		Soul:              100,
+	}, msg.Stats)
+	require.Equal(t, 75, msg.HealthPercent())
+	require.Equal(t, 50, msg.ManaPercent())
+}
+
+func TestParseSayMsg(t *testing.T) {
+	t.Run("On the map", func(t *testing.T) {
+		input := []byte{
+			0xAA,
+			0x01, 0x00, 0x00, 0x00, // Statement
+			0x03, 0x00, 'B', 'o', 'b',
+			0x01,                         // Say
+			0x10, 0x00, 0x20, 0x00, 0x07, // Position
+			0x02, 0x00, 'h', 'i',
+		}
+		packet, err := packets.ReadAndParseS2C(protocol.NewPacketReader(input), packets.ParsingContext{})
+		require.NoError(t, err)
+		require.Equal(t, &packets.SayMsg{
+			StatementID: 1,
+			Name:        "Bob",
+			Class:       domain.SpeakSay,
+			Pos:         domain.Position{X: 0x10, Y: 0x20, Z: 7},
+			Text:        "hi",
+		}, packet)
+	})
+
+	t.Run("In a channel", func(t *testing.T) {
+		input := []byte{
+			0xAA,
+			0x00, 0x00, 0x00, 0x00,
+			0x03, 0x00, 'B', 'o', 'b',
+			0x05,       // Yellow channel
+			0x05, 0x00, // Channel
+			0x02, 0x00, 'h', 'i',
+		}
+		packet, err := packets.ReadAndParseS2C(protocol.NewPacketReader(input), packets.ParsingContext{})
+		require.NoError(t, err)
+		require.Equal(t, &packets.SayMsg{Name: "Bob", Class: domain.SpeakChannelY, ChannelID: 5, Text: "hi"}, packet)
+	})
+}
+
+func TestParseFloorChange(t *testing.T) {
+	const tilesPerFloor = 18 * 14
+
+	t.Run("Up to the surface", func(t *testing.T) {
+		input := []byte{0xBE, 0x64, 0x00} // Ground on the first tile of floor 5
+		input = append(input, packetstest.SkipTiles(tilesPerFloor+5*tilesPerFloor)...)
+
+		pr := protocol.NewPacketReader(input)
+		packet, err := packets.ReadAndParseS2C(pr, packets.ParsingContext{PlayerPosition: domain.Position{X: 100, Y: 100, Z: 8}})
+		require.NoError(t, err)
+		require.Zero(t, pr.Remaining())
+
+		msg := packet.(*packets.FloorChangeMsg)
+		require.True(t, msg.Up)
+		require.Equal(t, domain.Position{X: 101, Y: 101, Z: 7}, msg.PlayerPos)
+		require.Len(t, msg.Tiles, 1)
+		require.Contains(t, msg.Tiles, domain.Position{X: 95, Y: 97, Z: 5})
+	})
+
+	t.Run("Down to the underground", func(t *testing.T) {
+		input := []byte{0xBF}
+		input = append(input, packetstest.SkipTiles(tilesPerFloor)...)
+		input = append(input, 0x64, 0x00) // Ground on the first tile of floor 9
+		input = append(input, packetstest.SkipTiles(tilesPerFloor+tilesPerFloor)...)
+
+		pr := protocol.NewPacketReader(input)
+		packet, err := packets.ReadAndParseS2C(pr, packets.ParsingContext{PlayerPosition: domain.Position{X: 100, Y: 100, Z: 7}})
+		require.NoError(t, err)
+		require.Zero(t, pr.Remaining())
+
+		msg := packet.(*packets.FloorChangeMsg)
+		require.False(t, msg.Up)
+		require.Equal(t, domain.Position{X: 99, Y: 99, Z: 8}, msg.PlayerPos)
+		require.Len(t, msg.Tiles, 1)
+		require.Contains(t, msg.Tiles, domain.Position{X: 90, Y: 92, Z: 9})
+	})
+
+	t.Run("Up between surface floors reads nothing", func(t *testing.T) {
+		pr := protocol.NewPacketReader([]byte{0xBE})
+		packet, err := packets.ReadAndParseS2C(pr, packets.ParsingContext{PlayerPosition: domain.Position{X: 100, Y: 100, Z: 7}})
+		require.NoError(t, err)
+		require.Equal(t, domain.Position{X: 101, Y: 101, Z: 6}, packet.(*packets.FloorChangeMsg).PlayerPos)
+	})
+}
+
+func TestParseMapDescription_DeepestFloors(t *testing.T) {
+	const tilesPerFloor = 18 * 14
+
+	// From floor 14 the view covers 12 to 15, there is no floor below the deepest one.
+	input := []byte{0x64, 0x64, 0x00, 0x64, 0x00, 0x0E}
+	input = append(input, packetstest.SkipTiles(3*tilesPerFloor)...)
+	input = append(input, 0x64, 0x00) // Ground on the first tile of floor 15
+	input = append(input, packetstest.SkipTiles(tilesPerFloor)...)
+
+	pr := protocol.NewPacketReader(input)
+	packet, err := packets.ReadAndParseS2C(pr, packets.ParsingContext{})
+	require.NoError(t, err)
+	require.Zero(t, pr.Remaining())
+
+	msg := packet.(*packets.MapDescriptionMsg)
+	require.Equal(t, domain.Position{X: 100, Y: 100, Z: 14}, msg.PlayerPos)
+	require.Contains(t, msg.Tiles, domain.Position{X: 91, Y: 93, Z: 15}, "Floors below are shifted to the north-west")
+}
+
+func TestParseUpdateTileMsg(t *testing.T) {
+	pos := []byte{0x10, 0x00, 0x20, 0x00, 0x07}
+
+	t.Run("Tile", func(t *testing.T) {
+		input := append([]byte{0x69}, pos...)
+		input = append(input, 0x64, 0x00, 0x00, 0xFF)
+
+		pr := protocol.NewPacketReader(input)
+		packet, err := packets.ReadAndParseS2C(pr, packets.ParsingContext{})
+		require.NoError(t, err)
+		require.Zero(t, pr.Remaining())
+		require.Equal(t, []domain.Item{{ID: 100}}, packet.(*packets.UpdateTileMsg).Tile.Items())
+	})
+
+	t.Run("Creatures keep their place in the stack", func(t *testing.T) {
+		input := append([]byte{0x69}, pos...)
+		input = append(input, 0x64, 0x00) // Ground
+		input = append(input,
+			0x62, 0x00, 0x01, 0x00, 0x00, 0x10, // Known creature 0x10000001
+			100, 2, // Health, direction
+			0x80, 0x00, 1, 2, 3, 4, // Outfit
+			0, 0, // Light
+			0xDC, 0x00, // Speed
+			0, 0, // Skull, shield
+		)
+		input = append(input, 0x65, 0x00, 0x00, 0xFF) // A common item listed after the creature, end of tile
+
+		pr := protocol.NewPacketReader(input)
+		packet, err := packets.ReadAndParseS2C(pr, packets.ParsingContext{})
+		require.NoError(t, err)
+		require.Zero(t, pr.Remaining())
+
+		msg := packet.(*packets.UpdateTileMsg)
+		require.Equal(t, []domain.Thing{
+			domain.ItemThing(domain.Item{ID: 100}, domain.StackCommon),
+			domain.CreatureThing(0x10000001),
+			domain.ItemThing(domain.Item{ID: 101}, domain.StackCommon),
+		}, msg.Tile.Things)
+		require.Len(t, msg.Creatures, 1)
+	})
+
+	t.Run("Empty tile", func(t *testing.T) {
+		input := append([]byte{0x69}, pos...)
+		input = append(input, 0x01, 0xFF)
+
+		pr := protocol.NewPacketReader(input)
+		packet, err := packets.ReadAndParseS2C(pr, packets.ParsingContext{})
+		require.NoError(t, err)
+		require.Zero(t, pr.Remaining())
+		require.Nil(t, packet.(*packets.UpdateTileMsg).Tile)
+	})
+}
+
+func TestParseTextWindows(t *testing.T) {
+	t.Run("Text window", func(t *testing.T) {
+		pr := protocol.NewPacketReader([]byte{0x96, 0x01, 0x00, 0x00, 0x00, 0x4A, 0x07, 0xE8, 0x03, 0x02, 0x00, 'h', 'i'})
+		packet, err := packets.ReadAndParseS2C(pr, packets.ParsingContext{})
+		require.NoError(t, err)
+		require.Equal(t, &packets.TextWindowMsg{WindowID: 1, ItemID: 0x074A, MaxLength: 1000, Text: "hi"}, packet)
+		require.Zero(t, pr.Remaining())
+	})
+
+	t.Run("House text window", func(t *testing.T) {
+		pr := protocol.NewPacketReader([]byte{0x97, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03, 0x00, 'B', 'o', 'b'})
+		packet, err := packets.ReadAndParseS2C(pr, packets.ParsingContext{})
+		require.NoError(t, err)
+		require.Equal(t, &packets.HouseTextWindowMsg{ListID: 0, WindowID: 2, Text: "Bob"}, packet)
+		require.Zero(t, pr.Remaining())
+	})
+}
+
+func TestParseS2CPacket_UnknownOpcode(t *testing.T) {
+	pr := protocol.NewPacketReader([]byte{0x1E, 0xFE, 0x01})
+
+	_, err := packets.ReadAndParseS2C(pr, packets.ParsingContext{})
+	require.NoError(t, err)
+
+	_, err = packets.ReadAndParseS2C(pr, packets.ParsingContext{})
+	var unknown *packets.UnknownOpcodeError
+	require.ErrorAs(t, err, &unknown)
+	require.Equal(t, uint8(0xFE), unknown.Opcode)
+	require.Equal(t, 1, unknown.Offset)
+}
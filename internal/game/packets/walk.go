@@ -0,0 +1,59 @@
+package packets
+
+import "z07/internal/protocol"
+
+// WalkS2C parses the packets of a server message in order and calls fn with the bounds of each one.
+// Map packets move the player, so the packets after them are parsed relative to the new position.
+// It stops at the first packet that fails to parse and returns its offset with the error,
+// or without an error at the first packet fn returns false for.
+func WalkS2C(data []byte, ctx ParsingContext, fn func(start, end int, packet S2CPacket) bool) (int, error) {
+	pr := protocol.NewPacketReader(data)
+	for pr.Remaining() > 0 {
+		start := pr.Offset()
+		packet, err := ReadAndParseS2C(pr, ctx)
+		if err == nil {
+			err = pr.Err()
+		}
+		if err != nil {
+			return start, err
+		}
+
+		switch p := packet.(type) {
+		case *MapDescriptionMsg:
+			ctx.PlayerPosition = p.PlayerPos
+		case *FloorChangeMsg:
+			ctx.PlayerPosition = p.PlayerPos
+		}
+		if !fn(start, pr.Offset(), packet) {
+			return start, nil
+		}
+	}
+	return len(data), nil
+}
+
+// IsCameraRelative reports whether a server packet is read relative to the player position of the
+// ParsingContext, i.e. the map slices and floor changes. A map description carries its own position.
+func IsCameraRelative(opcode S2COpcode) bool {
+	switch opcode {
+	case S2CMapSliceNorth, S2CMapSliceEast, S2CMapSliceSouth, S2CMapSliceWest, S2CFloorChangeUp, S2CFloorChangeDown:
+		return true
+	}
+	return false
+}
+
+// WalkC2S is WalkS2C for client messages, which usually hold a single packet.
+func WalkC2S(data []byte, fn func(start, end int, packet C2SPacket)) (int, error) {
+	pr := protocol.NewPacketReader(data)
+	for pr.Remaining() > 0 {
+		start := pr.Offset()
+		packet, err := ReadAndParseC2S(pr)
+		if err == nil {
+			err = pr.Err()
+		}
+		if err != nil {
+			return start, err
+		}
+		fn(start, pr.Offset(), packet)
+	}
+	return len(data), nil
+}
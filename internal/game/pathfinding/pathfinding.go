@@ -0,0 +1,214 @@
+package pathfinding
+
+import (
+	"container/heap"
+	"errors"
+	"time"
+	"z07/internal/assets"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/state"
+)
+
+const (
+	// defaultGroundSpeed is used when the ground item is unknown or has no speed set.
+	defaultGroundSpeed = 150
+	// minGroundSpeed is the cheapest step the heuristic assumes, faster grounds cost as much to keep it admissible.
+	minGroundSpeed = 100
+	// diagonalCostFactor matches the server, which makes a diagonal step three times slower.
+	diagonalCostFactor = 3
+	// maxAutoWalkSteps is the most steps a single auto-walk packet can carry.
+	maxAutoWalkSteps = 255
+	// searchMargin is how far a route may stray outside the rectangle spanned by start and target.
+	searchMargin = 8
+	// maxSearchNodes bounds the tiles a single search expands, it runs on every bot tick.
+	maxSearchNodes = 4000
+	// defaultPlayerSpeed is the speed of a fresh character, used until the server sent the player's.
+	defaultPlayerSpeed = 220
+)
+
+var ErrNoPath = errors.New("no path found")
+
+var directions = []domain.Direction{
+	domain.North, domain.East, domain.South, domain.West,
+	domain.NorthEast, domain.SouthEast, domain.SouthWest, domain.NorthWest,
+}
+
+// Options tweak a search. The zero value walks onto the target tile.
+type Options struct {
+	// Distance stops the route as soon as the target is this many steps away,
+	// e.g. 1 to stand next to a monster or a corpse.
+	Distance int
+	// IgnoreCreatures walks through tiles occupied by creatures.
+	IgnoreCreatures bool
+}
+
+// Path is a route as single steps, starting from the current position.
+type Path []domain.Direction
+
+// AutoWalk encodes the path the same way the client does after a map click.
+// Longer routes are cut, the rest has to be sent once the first part is walked.
+func (p Path) AutoWalk() *packets.AutoWalkRequest {
+	steps := p
+	if len(steps) > maxAutoWalkSteps {
+		steps = steps[:maxAutoWalkSteps]
+	}
+	return &packets.AutoWalkRequest{Steps: append([]domain.Direction(nil), steps...)}
+}
+
+// FindPath runs A* over the tracked map from `from` to `to`, which have to be on the same floor.
+// Steps are weighted by the ground speed of the tile they enter. The search stays within searchMargin
+// of both ends and gives up after maxSearchNodes tiles.
+func FindPath(frame state.WorldSnapshot, from, to domain.Position, opts Options) (Path, error) {
+	if from.Z != to.Z {
+		return nil, ErrNoPath
+	}
+	if from.DistanceTo(to) <= opts.Distance {
+		return Path{}, nil
+	}
+
+	occupied := make(map[domain.Position]bool)
+	if !opts.IgnoreCreatures {
+		for _, c := range frame.Creatures {
+			if c.Visible && c.ID != frame.Player.ID {
+				occupied[c.Pos] = true
+			}
+		}
+	}
+
+	minX, maxX := int(min(from.X, to.X))-searchMargin, int(max(from.X, to.X))+searchMargin
+	minY, maxY := int(min(from.Y, to.Y))-searchMargin, int(max(from.Y, to.Y))+searchMargin
+	inBounds := func(pos domain.Position) bool {
+		return int(pos.X) >= minX && int(pos.X) <= maxX && int(pos.Y) >= minY && int(pos.Y) <= maxY
+	}
+
+	type visit struct {
+		cost int
+		from domain.Position
+		dir  domain.Direction
+	}
+	visited := map[domain.Position]visit{from: {}}
+
+	open := &nodeQueue{}
+	heap.Push(open, &node{pos: from, priority: from.DistanceTo(to) * minGroundSpeed})
+
+	for expanded := 0; open.Len() > 0 && expanded < maxSearchNodes; expanded++ {
+		current := heap.Pop(open).(*node)
+		if current.cost > visited[current.pos].cost {
+			continue // A cheaper way to this tile was found after it was queued.
+		}
+		if current.pos.DistanceTo(to) <= opts.Distance {
+			path := Path{}
+			for pos := current.pos; pos != from; pos = visited[pos].from {
+				path = append(path, visited[pos].dir)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path, nil
+		}
+
+		for _, d := range directions {
+			next := current.pos.Translate(d)
+			if !inBounds(next) {
+				continue
+			}
+			tile, ok := frame.WorldMap[next]
+			if !ok || !walkableTile(*tile) || occupied[next] {
+				continue
+			}
+
+			cost := groundSpeed(*tile)
+			if d.IsDiagonal() {
+				cost *= diagonalCostFactor
+			}
+			cost += current.cost
+
+			if v, seen := visited[next]; seen && v.cost <= cost {
+				continue
+			}
+			visited[next] = visit{cost: cost, from: current.pos, dir: d}
+			heap.Push(open, &node{pos: next, cost: cost, priority: cost + next.DistanceTo(to)*minGroundSpeed})
+		}
+	}
+
+	return nil, ErrNoPath
+}
+
+// IsWalkable reports whether the tile is known, has no blocking items and no creature standing on it.
+func IsWalkable(frame state.WorldSnapshot, pos domain.Position) bool {
+	tile, ok := frame.WorldMap[pos]
+	if !ok || !walkableTile(*tile) {
+		return false
+	}
+	for _, c := range frame.Creatures {
+		if c.Visible && c.Pos == pos && c.ID != frame.Player.ID {
+			return false
+		}
+	}
+	return true
+}
+
+func walkableTile(tile domain.Tile) bool {
+	items := tile.Items()
+	if len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		thing := assets.Get(item.ID)
+		if thing.IsBlocking || thing.IsPathBlock {
+			return false
+		}
+	}
+	return true
+}
+
+// StepDuration is how long the server takes for a step of the player in the direction,
+// at the player's speed over the ground it stands on.
+func StepDuration(frame state.WorldSnapshot, d domain.Direction) time.Duration {
+	ground := defaultGroundSpeed
+	if tile, ok := frame.WorldMap[frame.Player.Pos]; ok {
+		ground = groundSpeed(*tile)
+	}
+	if d.IsDiagonal() {
+		ground *= diagonalCostFactor
+	}
+	speed := int(frame.Creatures[frame.Player.ID].Speed)
+	if speed == 0 {
+		speed = defaultPlayerSpeed
+	}
+	return time.Duration(ground) * time.Second / time.Duration(speed)
+}
+
+// groundSpeed is the cost of entering the tile. The ground is always the bottom item.
+func groundSpeed(tile domain.Tile) int {
+	item, ok := tile.Ground()
+	if !ok {
+		return defaultGroundSpeed
+	}
+	ground := assets.Get(item.ID)
+	if ground.Speed == 0 {
+		return defaultGroundSpeed
+	}
+	return max(int(ground.Speed), minGroundSpeed)
+}
+
+type node struct {
+	pos      domain.Position
+	cost     int
+	priority int
+}
+
+// nodeQueue is a min-heap on the A* priority.
+type nodeQueue []*node
+
+func (q nodeQueue) Len() int           { return len(q) }
+func (q nodeQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q nodeQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x any)        { *q = append(*q, x.(*node)) }
+func (q *nodeQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
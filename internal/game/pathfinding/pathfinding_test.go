@@ -0,0 +1,162 @@
+package pathfinding
+
+import (
+	"testing"
+	"time"
+	"z07/internal/assets"
+	"z07/internal/game/domain"
+	"z07/internal/game/state"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testGrass = 60001
+	testMud   = 60002
+	testWall  = 60003
+)
+
+func init() {
+	assets.Register(
+		assets.ItemType{ID: testGrass, IsGround: true, Speed: 150},
+		assets.ItemType{ID: testMud, IsGround: true, Speed: 600},
+		assets.ItemType{ID: testWall, IsBlocking: true},
+	)
+}
+
+// buildFrame turns rows of g (grass), m (mud) and # (wall) into a map on floor 7 starting at 100,100.
+func buildFrame(rows ...string) state.WorldSnapshot {
+	frame := state.WorldSnapshot{
+		WorldMap:  make(map[domain.Position]*domain.Tile),
+		Creatures: make(map[uint32]domain.Creature),
+	}
+	for y, row := range rows {
+		for x, c := range row {
+			pos := domain.Position{X: uint16(100 + x), Y: uint16(100 + y), Z: 7}
+			items := []domain.Item{{ID: testGrass}}
+			switch c {
+			case 'm':
+				items = []domain.Item{{ID: testMud}}
+			case '#':
+				items = append(items, domain.Item{ID: testWall})
+			}
+			frame.WorldMap[pos] = state.NewTile(pos, items...)
+		}
+	}
+	return frame
+}
+
+func walk(from domain.Position, path Path) domain.Position {
+	for _, d := range path {
+		from = from.Translate(d)
+	}
+	return from
+}
+
+func TestFindPath(t *testing.T) {
+	from := domain.Position{X: 100, Y: 100, Z: 7}
+
+	t.Run("Straight line", func(t *testing.T) {
+		frame := buildFrame("gggg")
+		path, err := FindPath(frame, from, domain.Position{X: 103, Y: 100, Z: 7}, Options{})
+		require.NoError(t, err)
+		require.Equal(t, Path{domain.East, domain.East, domain.East}, path)
+	})
+
+	t.Run("Around a wall", func(t *testing.T) {
+		frame := buildFrame(
+			"g#g",
+			"g#g",
+			"ggg",
+		)
+		to := domain.Position{X: 102, Y: 100, Z: 7}
+		path, err := FindPath(frame, from, to, Options{})
+		require.NoError(t, err)
+		require.Equal(t, to, walk(from, path))
+		require.Len(t, path, 6) // Two diagonals would be slower than going around
+	})
+
+	t.Run("Slow ground is avoided", func(t *testing.T) {
+		frame := buildFrame(
+			"gmg",
+			"ggg",
+		)
+		to := domain.Position{X: 102, Y: 100, Z: 7}
+		path, err := FindPath(frame, from, to, Options{})
+		require.NoError(t, err)
+		require.Equal(t, Path{domain.South, domain.East, domain.East, domain.North}, path)
+	})
+
+	t.Run("Diagonal when it is the only way", func(t *testing.T) {
+		frame := buildFrame(
+			"g#",
+			"#g",
+		)
+		path, err := FindPath(frame, from, domain.Position{X: 101, Y: 101, Z: 7}, Options{})
+		require.NoError(t, err)
+		require.Equal(t, Path{domain.SouthEast}, path)
+	})
+
+	t.Run("Creatures block", func(t *testing.T) {
+		frame := buildFrame("ggg")
+		frame.Creatures[0x40000001] = domain.Creature{ID: 0x40000001, Pos: domain.Position{X: 101, Y: 100, Z: 7}, Visible: true}
+
+		_, err := FindPath(frame, from, domain.Position{X: 102, Y: 100, Z: 7}, Options{})
+		require.ErrorIs(t, err, ErrNoPath)
+
+		path, err := FindPath(frame, from, domain.Position{X: 102, Y: 100, Z: 7}, Options{IgnoreCreatures: true})
+		require.NoError(t, err)
+		require.Len(t, path, 2)
+	})
+
+	t.Run("Stop next to the target", func(t *testing.T) {
+		frame := buildFrame("ggg#")
+		path, err := FindPath(frame, from, domain.Position{X: 103, Y: 100, Z: 7}, Options{Distance: 1})
+		require.NoError(t, err)
+		require.Equal(t, Path{domain.East, domain.East}, path)
+	})
+
+	t.Run("Detours stay near the route", func(t *testing.T) {
+		// A wall between both ends with a gap in the given row.
+		wall := func(gap int) state.WorldSnapshot {
+			rows := make([]string, gap+1)
+			for y := range rows {
+				rows[y] = "gg#gg"
+			}
+			rows[gap] = "ggggg"
+			return buildFrame(rows...)
+		}
+		to := domain.Position{X: 104, Y: 100, Z: 7}
+
+		path, err := FindPath(wall(searchMargin), from, to, Options{})
+		require.NoError(t, err)
+		require.Equal(t, to, walk(from, path))
+
+		_, err = FindPath(wall(searchMargin+1), from, to, Options{})
+		require.ErrorIs(t, err, ErrNoPath)
+	})
+
+	t.Run("Different floor", func(t *testing.T) {
+		frame := buildFrame("gg")
+		_, err := FindPath(frame, from, domain.Position{X: 101, Y: 100, Z: 6}, Options{})
+		require.ErrorIs(t, err, ErrNoPath)
+	})
+}
+
+func TestStepDuration(t *testing.T) {
+	frame := buildFrame("gm")
+	frame.Player = domain.Player{ID: 0x10000001, Pos: domain.Position{X: 100, Y: 100, Z: 7}}
+	require.Equal(t, 150*time.Second/defaultPlayerSpeed, StepDuration(frame, domain.East), "Speed not known yet")
+
+	frame.Creatures[0x10000001] = domain.Creature{ID: 0x10000001, Speed: 300}
+	require.Equal(t, 500*time.Millisecond, StepDuration(frame, domain.East))
+	require.Equal(t, 1500*time.Millisecond, StepDuration(frame, domain.SouthEast))
+
+	frame.Player.Pos.X++
+	require.Equal(t, 2*time.Second, StepDuration(frame, domain.West), "Mud is slow to leave")
+}
+
+func TestPath_AutoWalk(t *testing.T) {
+	path := make(Path, 300)
+	require.Len(t, path.AutoWalk().Steps, maxAutoWalkSteps)
+}
@@ -0,0 +1,86 @@
+// Package replay feeds recorded sessions back into a GameState, so whole sessions can serve as regression tests.
+//
+// Only server messages change the state. Client messages stay in the capture to tell the story when a replay fails.
+package replay
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"z07/internal/capture"
+	"z07/internal/game"
+	"z07/internal/game/state"
+)
+
+// Error reports the first server message that could not be applied.
+type Error struct {
+	Record  int // Index of the record in the capture, client messages included
+	Offset  int // Position of the failing packet in the payload
+	Payload []byte
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("replay: record %d, offset %d: %v\n%s", e.Record, e.Offset, e.Err, hex.Dump(e.Payload))
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Run applies every server message of a capture to a fresh state. It stops at the first parse failure.
+func Run(r io.Reader) (*state.GameState, error) {
+	gs := state.New()
+	cr := capture.NewReader(r)
+
+	for i := 0; ; i++ {
+		rec, err := cr.Next()
+		if err == io.EOF {
+			return gs, nil
+		}
+		if err != nil {
+			return gs, fmt.Errorf("replay: record %d: %w", i, err)
+		}
+		if rec.Direction != capture.ServerToClient {
+			continue
+		}
+
+		if err := game.ApplyServerMessage(gs, rec.Payload); err != nil {
+			replayErr := &Error{Record: i, Payload: rec.Payload, Err: err}
+			var pe *game.PacketError
+			if errors.As(err, &pe) {
+				replayErr.Offset = pe.Offset
+				replayErr.Err = pe.Err
+			}
+			return gs, replayErr
+		}
+	}
+}
+
+func RunFile(path string) (*state.GameState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Run(f)
+}
+
+// TB is the part of testing.TB that Golden uses, the package stays free of the testing package and its flags.
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
+// Golden replays a capture file and returns the final world, failing the test on any error.
+func Golden(t TB, path string) state.WorldSnapshot {
+	t.Helper()
+	gs, err := RunFile(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+		return state.WorldSnapshot{} // Only testing.TB stops here
+	}
+	return gs.CaptureFrame()
+}
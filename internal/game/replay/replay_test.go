@@ -0,0 +1,151 @@
+package replay_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"z07/internal/capture"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+	"z07/internal/game/packets/packetstest"
+	"z07/internal/game/replay"
+
+	"github.com/stretchr/testify/require"
+)
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func writeCapture(t *testing.T, records ...capture.Record) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := capture.NewWriter(&buf)
+	for _, r := range records {
+		r.Time = time.Unix(1700000000, 0)
+		require.NoError(t, w.Write(r))
+	}
+	return buf.Bytes()
+}
+
+func s2c(payload ...[]byte) capture.Record {
+	return capture.Record{Direction: capture.ServerToClient, Payload: join(payload...)}
+}
+
+func c2s(payload ...[]byte) capture.Record {
+	return capture.Record{Direction: capture.ClientToServer, Payload: join(payload...)}
+}
+
+var (
+	loginResponse = []byte{0x0A, 0x39, 0x30, 0x00, 0x00, 0x32, 0x00, 0x00}
+	// Player at 100,100,7 with a single ground tile in the top left corner of the view.
+	mapDescription = join(
+		[]byte{0x64, 0x64, 0x00, 0x64, 0x00, 0x07},
+		[]byte{0xAE, 0x11},
+		packetstest.SkipTiles(8*18*14),
+	)
+	equipHelmet = []byte{0x78, byte(domain.SlotHead), 0x16, 0x0D}
+	openBag     = []byte{
+		0x6E, 0x00,
+		0xC3, 0x07, // Bag
+		0x03, 0x00, 'b', 'a', 'g',
+		0x08, 0x00,
+		0x01, 0xC4, 0x07,
+	}
+	// Replaces the ground of the corner tile.
+	updateCorner = []byte{0x69, 0x5C, 0x00, 0x5E, 0x00, 0x07, 0xAF, 0x11, 0x00, 0xFF}
+	playerStats  = []byte{0xA0, 0x96, 0x00, 0xC8, 0x00, 0x2C, 0x01, 0x00, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x1E, 0x00, 0x3C, 0x00, 0x00, 0x00, 0x64}
+)
+
+func TestRun(t *testing.T) {
+	session := writeCapture(t,
+		c2s([]byte{0x1E}), // Ignored, only kept for context
+		s2c(loginResponse, mapDescription),
+		s2c(equipHelmet, openBag),
+		c2s([]byte{0x65}),
+		s2c(updateCorner),
+	)
+
+	gs, err := replay.Run(bytes.NewReader(session))
+	require.NoError(t, err)
+	frame := gs.CaptureFrame()
+
+	require.Equal(t, uint32(12345), frame.Player.ID)
+	require.Equal(t, domain.Position{X: 100, Y: 100, Z: 7}, frame.Player.Pos)
+
+	corner := frame.WorldMap[domain.Position{X: 92, Y: 94, Z: 7}]
+	require.NotNil(t, corner)
+	require.Equal(t, uint16(0x11AF), corner.Things[0].Item.ID)
+
+	require.Equal(t, uint16(0x0D16), frame.Equipment[domain.SlotHead].ID)
+	require.NotNil(t, frame.Containers[0])
+	require.Equal(t, "bag", frame.Containers[0].Name)
+	require.Len(t, frame.Containers[0].Items, 1)
+}
+
+func TestRun_ReportsFirstFailure(t *testing.T) {
+	t.Run("Unknown opcode", func(t *testing.T) {
+		session := writeCapture(t,
+			s2c(loginResponse),
+			s2c(playerStats, []byte{0xFE, 0x01}),
+			s2c(equipHelmet),
+		)
+
+		gs, err := replay.Run(bytes.NewReader(session))
+		var replayErr *replay.Error
+		require.ErrorAs(t, err, &replayErr)
+		require.Equal(t, 1, replayErr.Record)
+		require.Equal(t, len(playerStats), replayErr.Offset)
+
+		var unknown *packets.UnknownOpcodeError
+		require.ErrorAs(t, err, &unknown)
+		require.Contains(t, err.Error(), "00000000  a0 96 00")
+
+		// Everything before the failure is applied, nothing after it.
+		frame := gs.CaptureFrame()
+		require.Equal(t, uint16(150), frame.Player.Stats.Health)
+		require.Zero(t, frame.Equipment[domain.SlotHead].ID)
+	})
+
+	t.Run("Truncated packet", func(t *testing.T) {
+		session := writeCapture(t, s2c(playerStats[:6]))
+
+		_, err := replay.Run(bytes.NewReader(session))
+		var replayErr *replay.Error
+		require.ErrorAs(t, err, &replayErr)
+		require.Equal(t, 0, replayErr.Record)
+		require.Equal(t, 0, replayErr.Offset)
+	})
+
+	t.Run("Not a capture", func(t *testing.T) {
+		_, err := replay.Run(bytes.NewReader([]byte{0x01, 0x02}))
+		require.ErrorIs(t, err, capture.ErrBadHeader)
+	})
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.bin")
+	require.NoError(t, os.WriteFile(path, writeCapture(t, s2c(loginResponse, mapDescription)), 0o644))
+
+	frame := replay.Golden(t, path)
+	require.Equal(t, domain.Position{X: 100, Y: 100, Z: 7}, frame.Player.Pos)
+}
+
+// failures records what Golden reports, in place of a test.
+type failures []string
+
+func (f *failures) Helper() {}
+func (f *failures) Fatalf(format string, args ...any) {
+	*f = append(*f, fmt.Sprintf(format, args...))
+}
+
+func TestGolden_OutsideTests(t *testing.T) {
+	var f failures
+	frame := replay.Golden(&f, filepath.Join(t.TempDir(), "missing.bin"))
+	require.Len(t, f, 1)
+	require.Contains(t, f[0], "missing.bin")
+	require.Zero(t, frame.Player)
+}
@@ -0,0 +1,125 @@
+package state
+
+import (
+	"strings"
+	"time"
+	"z07/internal/game/domain"
+)
+
+// chatHistorySize is how many messages are kept, older ones are dropped.
+const chatHistorySize = 2000
+
+// chatLog is a fixed size ring of the latest chat messages.
+type chatLog struct {
+	buf     []domain.ChatMessage
+	start   int // Index of the oldest message
+	size    int
+	lastSeq uint64
+}
+
+func newChatLog(capacity int) *chatLog {
+	return &chatLog{buf: make([]domain.ChatMessage, capacity)}
+}
+
+func (l *chatLog) add(msg domain.ChatMessage) domain.ChatMessage {
+	l.lastSeq++
+	msg.Seq = l.lastSeq
+
+	if l.size < len(l.buf) {
+		l.buf[(l.start+l.size)%len(l.buf)] = msg
+		l.size++
+	} else {
+		l.buf[l.start] = msg
+		l.start = (l.start + 1) % len(l.buf)
+	}
+	return msg
+}
+
+// each visits the messages from the oldest to the newest.
+func (l *chatLog) each(fn func(domain.ChatMessage)) {
+	for i := 0; i < l.size; i++ {
+		fn(l.buf[(l.start+i)%len(l.buf)])
+	}
+}
+
+// ChatQuery filters the chat history. Zero fields match everything.
+type ChatQuery struct {
+	AfterSeq  uint64 // Only messages newer than this, used to stream the history
+	Since     time.Time
+	Author    string // Case-insensitive exact match
+	Text      string // Case-insensitive substring
+	Classes   []domain.SpeakClass
+	ChannelID *uint16
+	Server    bool // Only server text messages
+	LootOnly  bool
+	Limit     int // Keep only the newest N matches
+}
+
+func (q ChatQuery) matches(m domain.ChatMessage) bool {
+	if m.Seq <= q.AfterSeq {
+		return false
+	}
+	if !q.Since.IsZero() && m.Time.Before(q.Since) {
+		return false
+	}
+	if q.Author != "" && !strings.EqualFold(m.Author, q.Author) {
+		return false
+	}
+	if q.Text != "" && !strings.Contains(strings.ToLower(m.Text), strings.ToLower(q.Text)) {
+		return false
+	}
+	if len(q.Classes) > 0 && !containsClass(q.Classes, m.Class) {
+		return false
+	}
+	if q.ChannelID != nil && (!m.Class.IsChannel() || m.ChannelID != *q.ChannelID) {
+		return false
+	}
+	if q.Server && !m.IsServerMessage() {
+		return false
+	}
+	if q.LootOnly && !m.IsLoot() {
+		return false
+	}
+	return true
+}
+
+func containsClass(classes []domain.SpeakClass, class domain.SpeakClass) bool {
+	for _, c := range classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// AddChatMessage records a message. Seq is assigned here, and the level is filled in for the player's own messages.
+func (gs *GameState) AddChatMessage(msg domain.ChatMessage) domain.ChatMessage {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	if msg.Author != "" && msg.Author == gs.player.Name {
+		msg.Level = gs.player.Stats.Level
+	}
+	return gs.chat.add(msg)
+}
+
+// ChatHistory returns the recorded messages matching the query, oldest first.
+func (gs *GameState) ChatHistory(q ChatQuery) []domain.ChatMessage {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	var result []domain.ChatMessage
+	gs.chat.each(func(m domain.ChatMessage) {
+		if q.matches(m) {
+			result = append(result, m)
+		}
+	})
+
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result
+}
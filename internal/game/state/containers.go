@@ -0,0 +1,60 @@
+package state
+
+import "z07/internal/game/domain"
+
+// containerRequest is a request that opens a container in a window, the server answers with the container.
+type containerRequest struct {
+	itemId uint16 // 0 when nothing was asked for
+	from   domain.Position
+	up     bool // The parent of the window's container, see packets.UpContainerRequest
+}
+
+// ExpectContainer records that the item at from was used to open a container in the window.
+func (gs *GameState) ExpectContainer(window uint8, itemId uint16, from domain.Position) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if int(window) < len(gs.opening) {
+		gs.opening[window] = containerRequest{itemId: itemId, from: from}
+	}
+}
+
+// ExpectParentContainer records that the window is going up one level.
+func (gs *GameState) ExpectParentContainer(window uint8) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if int(window) < len(gs.opening) {
+		gs.opening[window] = containerRequest{up: true}
+	}
+}
+
+// resolveParent fills the source and parent of a container that was just opened, from the request that opened it.
+// It is called with the lock held, before the container replaces the one open in its window.
+func (gs *GameState) resolveParent(c *domain.Container) {
+	req := gs.opening[c.ID]
+	gs.opening[c.ID] = containerRequest{}
+
+	switch {
+	case req.up:
+		// The parent comes back into the same window, it takes the place it had in the tree.
+		if old := gs.containers[c.ID]; old != nil && old.Parent != nil && old.Parent.ItemID == c.ItemID {
+			c.Source = old.Parent.Source
+			c.Parent = old.Parent.Parent
+		}
+	case req.itemId != 0 && req.itemId == c.ItemID:
+		c.Source = req.from
+		if req.from.IsInContainer() && int(req.from.GetContainerIndex()) < len(gs.containers) {
+			if parent := gs.containers[req.from.GetContainerIndex()]; parent != nil {
+				copied := *parent
+				copied.Items = nil
+				c.Parent = &copied
+			}
+		}
+	}
+
+	// The server knows best, a bag on the floor has no parent whatever the client opened it from.
+	if !c.HasParent {
+		c.Parent = nil
+	}
+}
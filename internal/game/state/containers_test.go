@@ -0,0 +1,40 @@
+package state_test
+
+import (
+	"sync"
+	"testing"
+	"z07/internal/game/domain"
+	"z07/internal/game/state"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameState_SnapshotKeepsItsContainers(t *testing.T) {
+	gs := state.New()
+	gs.OpenContainer(domain.Container{ID: 0, ItemID: backpack, Capacity: 20, Items: []domain.Item{
+		{ID: gold, Count: 100},
+		{ID: sword},
+		{ID: bag},
+	}})
+	frame := gs.CaptureFrame()
+
+	// The server edits the backpack while the bot reads its snapshot, run with -race.
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for range 50 {
+			gs.UpdateContainerItem(0, 0, domain.Item{ID: gold, Count: 1})
+			gs.AddContainerItem(0, domain.Item{ID: gold, Count: 2})
+			gs.RemoveContainerItem(0, 1)
+		}
+		gs.RemoveContainerItem(0, 0)
+	})
+	for range 100 {
+		frame.FindItems(func(domain.Item) bool { return true })
+		frame.TotalWeight()
+	}
+	wg.Wait()
+
+	require.Equal(t, []domain.Item{{ID: gold, Count: 100}, {ID: sword}, {ID: bag}}, frame.Containers[0].Items,
+		"Containers of a snapshot never change")
+	require.Len(t, gs.CaptureFrame().Containers[0].Items, 2)
+}
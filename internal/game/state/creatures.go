@@ -0,0 +1,171 @@
+package state
+
+import (
+	"time"
+	"z07/internal/game/domain"
+)
+
+// AddUnknownCreature registers a creature the client sees for the first time.
+// The server picks removeId to free a slot in the client's known creatures list,
+// so that creature is forgotten before the new one is stored.
+func (gs *GameState) AddUnknownCreature(c domain.Creature, removeId uint32) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if removeId != 0 {
+		delete(gs.creatures, removeId)
+	}
+	gs.creatures[c.ID] = &c
+}
+
+// UpdateKnownCreature refreshes a creature the client already knows.
+// Known creatures are sent without a name, so the name is kept from the registry.
+func (gs *GameState) UpdateKnownCreature(c domain.Creature) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if existing, ok := gs.creatures[c.ID]; ok && c.Name == "" {
+		c.Name = existing.Name
+	}
+	gs.creatures[c.ID] = &c
+}
+
+// Creature looks up a single creature without copying the whole registry like CaptureFrame does.
+func (gs *GameState) Creature(creatureId uint32) (domain.Creature, bool) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	c, ok := gs.creatures[creatureId]
+	if !ok {
+		return domain.Creature{}, false
+	}
+	return *c, true
+}
+
+// MoveCreature moves a creature the server identified by its ID, the source tile was not on screen.
+func (gs *GameState) MoveCreature(creatureId uint32, to domain.Position) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if c, ok := gs.creatures[creatureId]; ok {
+		gs.placeCreature(creatureId, to)
+		c.Pos = to
+		c.Visible = true
+	}
+}
+
+// MoveCreatureFrom moves the creature at the stack position of a tile.
+// When the stack does not hold a creature there, the first visible creature on the tile is moved.
+func (gs *GameState) MoveCreatureFrom(from domain.Position, stackpos uint8, to domain.Position) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	var c *domain.Creature
+	if tile, ok := gs.worldMap[from]; ok && int(stackpos) < len(tile.Things) && tile.Things[stackpos].IsCreature() {
+		c = gs.creatures[tile.Things[stackpos].CreatureID]
+	}
+	if c == nil {
+		c = gs.findCreatureAt(from)
+	}
+	if c == nil {
+		return
+	}
+	gs.removeCreatureFromTile(from, c.ID)
+	gs.placeCreature(c.ID, to)
+	c.Pos = to
+}
+
+func (gs *GameState) SetCreatureHealth(creatureId uint32, healthPercent uint8) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if c, ok := gs.creatures[creatureId]; ok {
+		c.HealthPercent = healthPercent
+	}
+}
+
+func (gs *GameState) SetCreatureDirection(creatureId uint32, direction domain.Direction) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if c, ok := gs.creatures[creatureId]; ok {
+		c.Direction = direction
+	}
+}
+
+func (gs *GameState) SetCreatureLight(creatureId uint32, level uint8, color uint8) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if c, ok := gs.creatures[creatureId]; ok {
+		c.LightLevel = level
+		c.LightColor = color
+	}
+}
+
+func (gs *GameState) SetCreatureOutfit(creatureId uint32, outfit domain.Outfit) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if c, ok := gs.creatures[creatureId]; ok {
+		c.Outfit = outfit
+	}
+}
+
+func (gs *GameState) SetCreatureSpeed(creatureId uint32, speed uint16) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if c, ok := gs.creatures[creatureId]; ok {
+		c.Speed = speed
+	}
+}
+
+func (gs *GameState) SetCreatureSkull(creatureId uint32, skull domain.Skull) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if c, ok := gs.creatures[creatureId]; ok {
+		c.Skull = skull
+	}
+}
+
+func (gs *GameState) SetCreatureShield(creatureId uint32, shield domain.PartyShield) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if c, ok := gs.creatures[creatureId]; ok {
+		c.Shield = shield
+	}
+}
+
+// SetCreatureAttacking records that the creature just attacked the player.
+func (gs *GameState) SetCreatureAttacking(creatureId uint32, at time.Time) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if c, ok := gs.creatures[creatureId]; ok {
+		c.LastAttack = at
+	}
+}
+
+// HideCreature marks a creature as removed from the map.
+// It stays in the registry because the client keeps it in its known creatures list.
+func (gs *GameState) HideCreature(creatureId uint32) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if c, ok := gs.creatures[creatureId]; ok {
+		gs.removeCreatureFromTile(c.Pos, creatureId)
+		c.Visible = false
+	}
+}
+
+func (gs *GameState) findCreatureAt(pos domain.Position) *domain.Creature {
+	for _, c := range gs.creatures {
+		if c.Visible && c.Pos == pos {
+			return c
+		}
+	}
+	return nil
+}
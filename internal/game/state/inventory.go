@@ -0,0 +1,200 @@
+package state
+
+import (
+	"slices"
+	"z07/internal/assets"
+	"z07/internal/game/domain"
+)
+
+// StackPos is what the client sends as the stack position of an inventory item, the slot for container items.
+func (i ItemInInventory) StackPos() uint8 {
+	if i.Position.IsInContainer() {
+		return i.Position.Z
+	}
+	return 0
+}
+
+// Amount is how many items the stack holds, 1 for an item that does not stack.
+func (i ItemInInventory) Amount() int {
+	return amount(i.Item)
+}
+
+func amount(item domain.Item) int {
+	return item.Amount(assets.Get(item.ID).IsStackable)
+}
+
+// FindItems returns every item matching the criteria, the equipment first and then every open container.
+func (s WorldSnapshot) FindItems(criteria func(domain.Item) bool) []ItemInInventory {
+	var result []ItemInInventory
+	for slot, item := range s.Equipment {
+		if item.ID != 0 && criteria(item) {
+			result = append(result, ItemInInventory{Item: item, Position: domain.NewInventoryPosition(domain.EquipmentSlot(slot))})
+		}
+	}
+	for cid, container := range s.Containers {
+		if container == nil {
+			continue
+		}
+		for slot, item := range container.Items {
+			if criteria(item) {
+				result = append(result, ItemInInventory{Item: item, Position: domain.NewContainerPosition(cid, slot)})
+			}
+		}
+	}
+	return result
+}
+
+// CountItem adds up the item across the equipment and the open containers, stacks count with their size.
+// Items in closed containers are not known and corpses are not ours, neither is counted.
+func (s WorldSnapshot) CountItem(itemId uint16) int {
+	n := 0
+	for _, item := range s.FindItems(func(item domain.Item) bool { return item.ID == itemId }) {
+		if item.Position.IsInContainer() && !s.carried(int(item.Position.GetContainerIndex())) {
+			continue
+		}
+		n += item.Amount()
+	}
+	return n
+}
+
+// FindStack returns a stack of the same item with room for all of item, the item itself is skipped.
+// Only carried containers accepted by into are searched, a nil into accepts every one.
+func (s WorldSnapshot) FindStack(item ItemInInventory, into func(*domain.Container) bool) *ItemInInventory {
+	if !assets.Get(item.Item.ID).IsStackable {
+		return nil
+	}
+	for cid, container := range s.Containers {
+		if !s.carried(cid) || (into != nil && !into(container)) {
+			continue
+		}
+		for slot, other := range container.Items {
+			pos := domain.NewContainerPosition(cid, slot)
+			if pos == item.Position || other.ID != item.Item.ID {
+				continue
+			}
+			if amount(other)+item.Amount() <= domain.MaxStackCount {
+				return &ItemInInventory{Item: other, Position: pos}
+			}
+		}
+	}
+	return nil
+}
+
+// FindFreeSlot returns where an item dropped into the first carried container with room lands.
+// Only containers accepted by into are searched, a nil into accepts every one.
+func (s WorldSnapshot) FindFreeSlot(into func(*domain.Container) bool) (domain.Position, bool) {
+	for cid, container := range s.Containers {
+		if !s.carried(cid) || (into != nil && !into(container)) {
+			continue
+		}
+		if len(container.Items) < int(container.Capacity) {
+			// Dropping behind the last item puts it into the container itself, not into a bag inside it.
+			return domain.NewContainerPosition(cid, len(container.Items)), true
+		}
+	}
+	return domain.Position{}, false
+}
+
+// FreeContainerWindow returns the first window no container is open in, or -1 when all are in use.
+func (s WorldSnapshot) FreeContainerWindow() int {
+	for cid, container := range s.Containers {
+		if container == nil {
+			return cid
+		}
+	}
+	return -1
+}
+
+// ChildContainers lists the containers lying inside an open container, in slot order.
+func (s WorldSnapshot) ChildContainers(cid int) []ItemInInventory {
+	if cid < 0 || cid >= len(s.Containers) || s.Containers[cid] == nil {
+		return nil
+	}
+	var result []ItemInInventory
+	for slot, item := range s.Containers[cid].Items {
+		if assets.Get(item.ID).IsContainer {
+			result = append(result, ItemInInventory{Item: item, Position: domain.NewContainerPosition(cid, slot)})
+		}
+	}
+	return result
+}
+
+// TotalWeight adds up the weight of the equipment and the carried containers, in hundredths of an oz.
+// Only what is in sight is counted, the contents of closed containers are missing.
+func (s WorldSnapshot) TotalWeight() uint32 {
+	var total uint32
+	for _, item := range s.Equipment {
+		if item.ID != 0 {
+			total += assets.Get(item.ID).Weight * uint32(amount(item))
+		}
+	}
+	for cid, container := range s.Containers {
+		if !s.carried(cid) {
+			continue
+		}
+		for _, item := range container.Items {
+			total += assets.Get(item.ID).Weight * uint32(amount(item))
+		}
+	}
+	return total
+}
+
+// carried reports whether the container window holds items we carry, a corpse is looted but not carried.
+func (s WorldSnapshot) carried(cid int) bool {
+	container := s.Containers[cid]
+	return container != nil && !assets.Get(container.ItemID).IsCorpse
+}
+
+// ContainerChain lists the containers from the outermost known one down to the container of the window.
+// Parents are as they were when their child was opened, without items.
+func (s WorldSnapshot) ContainerChain(cid int) []domain.Container {
+	if cid < 0 || cid >= len(s.Containers) || s.Containers[cid] == nil {
+		return nil
+	}
+	var chain []domain.Container
+	for c := s.Containers[cid]; c != nil; c = c.Parent {
+		chain = append(chain, *c)
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// ParentWindow returns the window the parent of a container is still open in, or -1.
+func (s WorldSnapshot) ParentWindow(cid int) int {
+	if cid < 0 || cid >= len(s.Containers) || s.Containers[cid] == nil {
+		return -1
+	}
+	c := s.Containers[cid]
+	if c.Parent == nil || !c.Source.IsInContainer() {
+		return -1
+	}
+	parent := int(c.Source.GetContainerIndex())
+	if parent == cid || parent >= len(s.Containers) || s.Containers[parent] == nil || s.Containers[parent].ItemID != c.Parent.ItemID {
+		return -1
+	}
+	return parent
+}
+
+// FindContainerByPath returns the window the container at the end of path is open in, or -1.
+// The path starts at the backpack in the equipment and names a slot for every level below it,
+// so an empty path is the backpack itself and {1} is the container in its second slot.
+func (s WorldSnapshot) FindContainerByPath(path []int) int {
+	for cid := range s.Containers {
+		chain := s.ContainerChain(cid)
+		if len(chain) != len(path)+1 || chain[0].Source != domain.NewInventoryPosition(domain.SlotBackpack) {
+			continue
+		}
+		matches := true
+		for i, slot := range path {
+			source := chain[i+1].Source
+			if !source.IsInContainer() || int(source.Z) != slot {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return cid
+		}
+	}
+	return -1
+}
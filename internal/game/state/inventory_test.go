@@ -0,0 +1,106 @@
+package state_test
+
+import (
+	"testing"
+	"z07/internal/assets"
+	"z07/internal/game/domain"
+	"z07/internal/game/state"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	backpack = 62001
+	bag      = 62002
+	gold     = 62003
+	sword    = 62004
+	corpse   = 62005
+)
+
+func init() {
+	assets.Register(
+		assets.ItemType{ID: backpack, IsContainer: true, Weight: 1800},
+		assets.ItemType{ID: bag, IsContainer: true, Weight: 800},
+		assets.ItemType{ID: gold, IsStackable: true, Weight: 10},
+		assets.ItemType{ID: sword, Weight: 3500},
+		assets.ItemType{ID: corpse, IsContainer: true, IsCorpse: true},
+	)
+}
+
+func inventory() state.WorldSnapshot {
+	var s state.WorldSnapshot
+	s.Equipment[domain.SlotBackpack] = domain.Item{ID: backpack}
+	s.Equipment[domain.SlotLeft] = domain.Item{ID: sword}
+	s.Containers[0] = &domain.Container{ID: 0, ItemID: backpack, Capacity: 4, Items: []domain.Item{
+		{ID: bag},
+		{ID: gold, Count: 100},
+		{ID: gold, Count: 40},
+	}}
+	s.Containers[1] = &domain.Container{ID: 1, ItemID: bag, Capacity: 1, Items: []domain.Item{{ID: gold, Count: 5}}}
+	s.Containers[2] = &domain.Container{ID: 2, ItemID: corpse, Capacity: 8, Items: []domain.Item{{ID: gold, Count: 7}}}
+	return s
+}
+
+func TestWorldSnapshot_FindItems(t *testing.T) {
+	s := inventory()
+
+	found := s.FindItems(func(item domain.Item) bool { return item.ID == gold })
+	require.Equal(t, []state.ItemInInventory{
+		{Item: domain.Item{ID: gold, Count: 100}, Position: domain.NewContainerPosition(0, 1)},
+		{Item: domain.Item{ID: gold, Count: 40}, Position: domain.NewContainerPosition(0, 2)},
+		{Item: domain.Item{ID: gold, Count: 5}, Position: domain.NewContainerPosition(1, 0)},
+		{Item: domain.Item{ID: gold, Count: 7}, Position: domain.NewContainerPosition(2, 0)},
+	}, found)
+
+	require.Equal(t, 145, s.CountItem(gold), "The gold in the corpse is not ours")
+	require.Equal(t, 1, s.CountItem(sword))
+	require.Zero(t, s.CountItem(3031))
+}
+
+func TestWorldSnapshot_FindStack(t *testing.T) {
+	s := inventory()
+
+	stack := s.FindStack(state.ItemInInventory{Item: domain.Item{ID: gold, Count: 50}, Position: domain.NewContainerPosition(2, 0)}, nil)
+	require.NotNil(t, stack)
+	require.Equal(t, domain.NewContainerPosition(0, 2), stack.Position, "The full stack is skipped")
+
+	self := state.ItemInInventory{Item: domain.Item{ID: gold, Count: 40}, Position: domain.NewContainerPosition(0, 2)}
+	stack = s.FindStack(self, nil)
+	require.NotNil(t, stack)
+	require.Equal(t, domain.NewContainerPosition(1, 0), stack.Position, "A stack is never moved onto itself")
+
+	stack = s.FindStack(self, func(c *domain.Container) bool { return c.ItemID == backpack })
+	require.Nil(t, stack)
+
+	require.Nil(t, s.FindStack(state.ItemInInventory{Item: domain.Item{ID: sword}}, nil), "Swords do not stack")
+}
+
+func TestWorldSnapshot_FindFreeSlot(t *testing.T) {
+	s := inventory()
+
+	pos, ok := s.FindFreeSlot(nil)
+	require.True(t, ok)
+	require.Equal(t, domain.NewContainerPosition(0, 3), pos)
+
+	_, ok = s.FindFreeSlot(func(c *domain.Container) bool { return c.ItemID != backpack })
+	require.False(t, ok, "The bag is full and the corpse is not carried")
+
+	require.Equal(t, 3, s.FreeContainerWindow())
+}
+
+func TestWorldSnapshot_ChildContainers(t *testing.T) {
+	s := inventory()
+
+	require.Equal(t, []state.ItemInInventory{
+		{Item: domain.Item{ID: bag}, Position: domain.NewContainerPosition(0, 0)},
+	}, s.ChildContainers(0))
+	require.Empty(t, s.ChildContainers(1))
+	require.Empty(t, s.ChildContainers(5))
+}
+
+func TestWorldSnapshot_TotalWeight(t *testing.T) {
+	s := inventory()
+
+	// backpack + sword + bag + 140 gold + 5 gold
+	require.Equal(t, uint32(1800+3500+800+1400+50), s.TotalWeight())
+}
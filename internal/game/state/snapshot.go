@@ -1,12 +1,17 @@
 package state
 
-import "z07/internal/game/domain"
+import (
+	"strings"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+)
 
 type WorldSnapshot struct {
 	Player     domain.Player
 	Equipment  [11]domain.Item
 	Containers [16]*domain.Container
 	WorldMap   map[domain.Position]*domain.Tile
+	Creatures  map[uint32]domain.Creature
 }
 
 type ItemInInventory struct {
@@ -15,17 +20,25 @@ type ItemInInventory struct {
 }
 
 func (s WorldSnapshot) FindItemInEqAndOpenWindows(itemId uint16) *ItemInInventory {
-	itemInEq := s.findItemInEq(itemId)
+	return s.FindItem(func(item domain.Item) bool {
+		return item.ID == itemId
+	})
+}
+
+// FindItem returns the first item matching the criteria, looking at the equipment first
+// and then at every open container.
+func (s WorldSnapshot) FindItem(criteria func(domain.Item) bool) *ItemInInventory {
+	itemInEq := s.findItemInEq(criteria)
 	if itemInEq != nil {
 		return itemInEq
 	}
 
-	return s.findItemInContainers(itemId)
+	return s.findItemInContainers(criteria)
 }
 
-func (s WorldSnapshot) findItemInEq(itemId uint16) *ItemInInventory {
+func (s WorldSnapshot) findItemInEq(criteria func(domain.Item) bool) *ItemInInventory {
 	for slot, item := range s.Equipment {
-		if item.ID == itemId {
+		if item.ID != 0 && criteria(item) {
 			equipmentSlot := domain.EquipmentSlot(slot)
 			pos := domain.NewInventoryPosition(equipmentSlot)
 			return &ItemInInventory{
@@ -37,14 +50,14 @@ func (s WorldSnapshot) findItemInEq(itemId uint16) *ItemInInventory {
 	return nil
 }
 
-func (s WorldSnapshot) findItemInContainers(itemId uint16) *ItemInInventory {
+func (s WorldSnapshot) findItemInContainers(criteria func(domain.Item) bool) *ItemInInventory {
 	for cid, container := range s.Containers {
 		if container == nil {
 			// the container is not open
 			continue
 		}
 		for slot, item := range container.Items {
-			if item.ID == itemId {
+			if criteria(item) {
 				pos := domain.NewContainerPosition(cid, slot)
 				return &ItemInInventory{
 					Item:     item,
@@ -72,3 +85,41 @@ func (s WorldSnapshot) FindTileNearby(radiusX, radiusY int, criteria func(*domai
 	}
 	return nil, nil
 }
+
+// CreaturesOnScreen returns the visible creatures on the player's floor within the 15x11 game window.
+// The server describes one extra row and column around it, which the client does not draw.
+// The player itself is not included.
+func (s WorldSnapshot) CreaturesOnScreen() []domain.Creature {
+	pos := s.Player.Pos
+
+	var result []domain.Creature
+	for _, c := range s.Creatures {
+		if !c.Visible || c.ID == s.Player.ID || c.Pos.Z != pos.Z {
+			continue
+		}
+
+		dx := int(c.Pos.X) - int(pos.X)
+		dy := int(c.Pos.Y) - int(pos.Y)
+		if abs(dx) >= packets.ClientViewportX || abs(dy) >= packets.ClientViewportY {
+			continue
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+func (s WorldSnapshot) FindCreatureByName(name string) *domain.Creature {
+	for _, c := range s.Creatures {
+		if strings.EqualFold(c.Name, name) {
+			return &c
+		}
+	}
+	return nil
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -1,7 +1,8 @@
 package state
 
 import (
-	"fmt"
+	"maps"
+	"slices"
 	"sync"
 	"z07/internal/game/domain"
 )
@@ -13,14 +14,19 @@ type GameState struct {
 	player     domain.Player
 	equipment  [11]domain.Item
 	containers [16]*domain.Container // nil means closed
+	opening    [16]containerRequest  // What the client asked to open in each window
 	worldMap   map[domain.Position]*domain.Tile
+	creatures  map[uint32]*domain.Creature
+	chat       *chatLog
 
 	mu sync.RWMutex
 }
 
 func New() *GameState {
 	return &GameState{
-		worldMap: make(map[domain.Position]*domain.Tile),
+		worldMap:  make(map[domain.Position]*domain.Tile),
+		creatures: make(map[uint32]*domain.Creature),
+		chat:      newChatLog(chatHistorySize),
 	}
 }
 
@@ -31,11 +37,18 @@ func (gs *GameState) CaptureFrame() WorldSnapshot {
 	gs.mu.RLock()
 	defer gs.mu.RUnlock()
 
+	// Tiles and containers are never changed in place, the snapshot shares them but needs its own map.
 	snap := WorldSnapshot{
 		Player:     gs.player,
 		Equipment:  gs.equipment,
 		Containers: gs.containers,
-		WorldMap:   gs.worldMap,
+		WorldMap:   maps.Clone(gs.worldMap),
+		Creatures:  make(map[uint32]domain.Creature, len(gs.creatures)),
+	}
+
+	// Creatures change on almost every packet, so the snapshot gets its own copy.
+	for id, c := range gs.creatures {
+		snap.Creatures[id] = *c
 	}
 
 	return snap
@@ -55,6 +68,13 @@ func (gs *GameState) SetPlayerPos(pos domain.Position) {
 	gs.player.Pos = pos
 }
 
+func (gs *GameState) PlayerPos() domain.Position {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	return gs.player.Pos
+}
+
 func (gs *GameState) SetPlayerName(Name string) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
@@ -62,6 +82,35 @@ func (gs *GameState) SetPlayerName(Name string) {
 	gs.player.Name = Name
 }
 
+func (gs *GameState) SetPlayerStats(stats domain.Stats) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.player.Stats = stats
+}
+
+func (gs *GameState) SetPlayerSkills(skills [domain.SkillLast + 1]domain.Skill) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.player.Skills = skills
+}
+
+func (gs *GameState) SetPlayerIcons(icons domain.Icons) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.player.Icons = icons
+}
+
+// CancelTarget notes that the server stopped the attack.
+func (gs *GameState) CancelTarget() {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.player.TargetCancels++
+}
+
 func (gs *GameState) SetEquipment(slot domain.EquipmentSlot, item domain.Item) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
@@ -89,7 +138,10 @@ func (gs *GameState) OpenContainer(c domain.Container) {
 		return
 	}
 
-	// 2. Store the Container
+	// 2. Place it in the backpack tree before the window forgets what was open there.
+	gs.resolveParent(&c)
+
+	// 3. Store the Container
 	// Since 'c' is passed by value, we have a copy of the struct headers.
 	// However, c.Items is a slice (pointer to array).
 	// Because the Handler creates this slice fresh from the packet and then discards it,
@@ -124,10 +176,7 @@ func (gs *GameState) RemoveContainerItem(cId uint8, slot uint8) {
 		return
 	}
 
-	// 4. Perform the Removal (Shift Logic)
-	// We take everything BEFORE the slot, and append everything AFTER the slot.
-	// Go automatically handles the shifting of memory.
-	container.Items = append(container.Items[:slot], container.Items[slot+1:]...)
+	gs.setContainerItems(container, slices.Delete(slices.Clone(container.Items), int(slot), int(slot)+1))
 }
 
 func (gs *GameState) AddContainerItem(cId uint8, item domain.Item) {
@@ -143,7 +192,7 @@ func (gs *GameState) AddContainerItem(cId uint8, item domain.Item) {
 		return
 	}
 
-	container.Items = append([]domain.Item{item}, container.Items...)
+	gs.setContainerItems(container, append([]domain.Item{item}, container.Items...))
 }
 
 func (gs *GameState) UpdateContainerItem(cId uint8, slot uint8, item domain.Item) {
@@ -163,47 +212,15 @@ func (gs *GameState) UpdateContainerItem(cId uint8, slot uint8, item domain.Item
 		return
 	}
 
-	container.Items[slot] = item
+	items := slices.Clone(container.Items)
+	items[slot] = item
+	gs.setContainerItems(container, items)
 }
 
-func (gs *GameState) SetTiles(tiles map[domain.Position]*domain.Tile) {
-	gs.mu.Lock()
-	defer gs.mu.Unlock()
-
-	for pos, tile := range tiles {
-		gs.worldMap[pos] = tile
-	}
-}
-
-func (gs *GameState) UpdateTileItem(position domain.Position, stackpos uint8, item domain.Item) {
-	gs.mu.Lock()
-	defer gs.mu.Unlock()
-
-	if _, ok := gs.worldMap[position]; !ok {
-		fmt.Printf("UpdateTileItem: position %v not found in worldMap\n", position)
-		return
-	}
-
-	if int(stackpos) >= len(gs.worldMap[position].Items) {
-		fmt.Printf("UpdateTileItem: stackpos %d out of range for tile at position %v\n", stackpos, position)
-		return
-	}
-
-	gs.worldMap[position].Items[stackpos] = item
-}
-
-func (gs *GameState) AddTileItem(position domain.Position, item domain.Item) {
-	gs.mu.Lock()
-	defer gs.mu.Unlock()
-
-	if _, ok := gs.worldMap[position]; !ok {
-		fmt.Printf("AddTileItem: position %v not found in worldMap\n", position)
-		return
-	}
-	// 1. Get the copy
-	tile := gs.worldMap[position]
-	// 2. Update the copy
-	tile.Items = append(tile.Items, item)
-	// 3. Put the updated copy back into the map
-	gs.worldMap[position] = tile
+// setContainerItems stores a copy of the container with the new items. Like tiles, containers are never
+// changed in place, snapshots share them with the state. It is called with the lock held.
+func (gs *GameState) setContainerItems(container *domain.Container, items []domain.Item) {
+	copied := *container
+	copied.Items = items
+	gs.containers[container.ID] = &copied
 }
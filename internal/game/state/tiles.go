@@ -0,0 +1,153 @@
+package state
+
+import (
+	"log"
+	"z07/internal/game/domain"
+	"z07/internal/game/packets"
+)
+
+// NewTile builds a tile from items that are already in stack order.
+func NewTile(pos domain.Position, items ...domain.Item) *domain.Tile {
+	things := make([]domain.Thing, 0, len(items))
+	for _, item := range items {
+		things = append(things, packets.ItemThing(item))
+	}
+	return domain.NewTile(pos, things...)
+}
+
+// SetTiles stores the tiles of a map description. A creature described on one of them is gone
+// from the tile it stood on before, unless that tile is described as well.
+func (gs *GameState) SetTiles(tiles map[domain.Position]*domain.Tile) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	for pos, tile := range tiles {
+		gs.worldMap[pos] = tile
+	}
+	for pos, tile := range tiles {
+		for _, id := range tile.Creatures() {
+			if c, ok := gs.creatures[id]; ok && c.Pos != pos {
+				if _, described := tiles[c.Pos]; !described {
+					gs.removeCreatureFromTile(c.Pos, id)
+				}
+			}
+		}
+	}
+}
+
+// SetTile replaces a single tile, a nil tile removes it.
+func (gs *GameState) SetTile(position domain.Position, tile *domain.Tile) {
+	if tile == nil {
+		gs.mu.Lock()
+		defer gs.mu.Unlock()
+
+		delete(gs.worldMap, position)
+		return
+	}
+	gs.SetTiles(map[domain.Position]*domain.Tile{position: tile})
+}
+
+// Tiles copies every tile seen so far, e.g. to keep the explored map after the session.
+func (gs *GameState) Tiles() map[domain.Position]*domain.Tile {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	tiles := make(map[domain.Position]*domain.Tile, len(gs.worldMap))
+	for pos, tile := range gs.worldMap {
+		tiles[pos] = tile.Clone()
+	}
+	return tiles
+}
+
+// AddTileItem puts an item on a tile where the client would, see domain.Tile.Add.
+func (gs *GameState) AddTileItem(position domain.Position, item domain.Item) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.changeTile(position, func(t *domain.Tile) bool {
+		t.Add(packets.ItemThing(item))
+		return true
+	})
+}
+
+// AddTileCreature puts a creature that stepped into view on a tile.
+func (gs *GameState) AddTileCreature(position domain.Position, creatureId uint32) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.placeCreature(creatureId, position)
+}
+
+// RemoveTileThing takes whatever is at the stack position off the tile. A creature removed this way
+// left the screen, e.g. by logging out.
+func (gs *GameState) RemoveTileThing(position domain.Position, stackpos uint8) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	var removed domain.Thing
+	gs.changeTile(position, func(t *domain.Tile) bool {
+		var ok bool
+		removed, ok = t.Remove(stackpos)
+		if !ok {
+			log.Printf("[State] RemoveTileThing: stackpos %d out of range for tile at %v", stackpos, position)
+		}
+		return ok
+	})
+	if c, ok := gs.creatures[removed.CreatureID]; ok && removed.IsCreature() {
+		c.Visible = false
+	}
+}
+
+// UpdateTileItem transforms the item at the stack position, it keeps its place in the stack.
+func (gs *GameState) UpdateTileItem(position domain.Position, stackpos uint8, item domain.Item) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.changeTile(position, func(t *domain.Tile) bool {
+		if int(stackpos) >= len(t.Things) || t.Things[stackpos].IsCreature() {
+			log.Printf("[State] UpdateTileItem: no item at stackpos %d of tile at %v", stackpos, position)
+			return false
+		}
+		return t.Replace(stackpos, packets.ItemThing(item))
+	})
+}
+
+// changeTile applies fn to a copy of the tile and stores the copy when fn reports a change.
+func (gs *GameState) changeTile(position domain.Position, fn func(t *domain.Tile) bool) {
+	tile, ok := gs.worldMap[position]
+	if !ok {
+		log.Printf("[State] Tile %v is not known", position)
+		return
+	}
+	changed := tile.Clone()
+	if fn(changed) {
+		gs.worldMap[position] = changed
+	}
+}
+
+// placeCreature moves a creature to a tile, taking it off the one it stood on.
+// A destination outside the known map only takes it off the old tile.
+func (gs *GameState) placeCreature(creatureId uint32, to domain.Position) {
+	if c, ok := gs.creatures[creatureId]; ok && c.Pos != to {
+		gs.removeCreatureFromTile(c.Pos, creatureId)
+	}
+	if tile, ok := gs.worldMap[to]; ok {
+		changed := tile.Clone()
+		changed.RemoveCreature(creatureId) // Already there after a map description
+		changed.Add(domain.CreatureThing(creatureId))
+		gs.worldMap[to] = changed
+	}
+}
+
+func (gs *GameState) removeCreatureFromTile(position domain.Position, creatureId uint32) {
+	tile, ok := gs.worldMap[position]
+	if !ok {
+		return
+	}
+	if _, ok := tile.StackPosOf(creatureId); !ok {
+		return
+	}
+	changed := tile.Clone()
+	changed.RemoveCreature(creatureId)
+	gs.worldMap[position] = changed
+}
@@ -0,0 +1,36 @@
+package state_test
+
+import (
+	"sync"
+	"testing"
+	"z07/internal/game/domain"
+	"z07/internal/game/state"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameState_SnapshotKeepsItsMap(t *testing.T) {
+	gs := state.New()
+	here := domain.Position{X: 100, Y: 100, Z: 7}
+	gs.SetTile(here, state.NewTile(here, domain.Item{ID: sword}))
+	frame := gs.CaptureFrame()
+
+	// The state goes on writing the map while the bot reads its snapshot, run with -race.
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for i := range 100 {
+			pos := domain.Position{X: uint16(200 + i), Y: 100, Z: 7}
+			gs.SetTile(pos, state.NewTile(pos))
+		}
+		gs.AddTileItem(here, domain.Item{ID: gold, Count: 5})
+	})
+	for range 100 {
+		for range frame.WorldMap {
+		}
+	}
+	wg.Wait()
+
+	require.Len(t, frame.WorldMap, 1)
+	require.Len(t, frame.WorldMap[here].Things, 1, "Tiles of a snapshot never change")
+	require.Len(t, gs.CaptureFrame().WorldMap[here].Things, 2)
+}
@@ -2,8 +2,10 @@ package login
 
 import (
 	"log"
+	"net"
 	"strconv"
 	"time"
+	"z07/internal/capture"
 	"z07/internal/login/packets"
 	"z07/internal/protocol"
 	"z07/internal/proxy"
@@ -12,22 +14,30 @@ import (
 type LoginHandler struct {
 	TargetAddr string
 	ProxyMOTD  string
+	// Where the character list sends the client, the game proxy itself
+	GameworldIP   uint32
+	GameworldPort uint16
+	WorldName     string // Empty keeps the name of the real world
+	// Routes receives the real world of every character, so the game proxy can forward there
+	Routes *proxy.WorldRoutes
+	// Recorder captures the server response only, the credentials packet is never recorded.
+	Recorder *capture.Recorder
 }
 
 func (h *LoginHandler) Handle(protoClientConn protocol.Connection) {
 	log.Printf("[Login] New Connection: %s", protoClientConn.RemoteAddr())
 
-	_, protoServerConn, err := proxy.InitSession(
+	credentials, protoServerConn, err := proxy.InitSession(
 		"Login",
 		protoClientConn,
 		h.TargetAddr,
 		packets.ParseCredentialsPacket,
 	)
-	defer protoServerConn.Close()
 	if err != nil {
 		log.Printf("[Login]: Failed to initialize session for %s: %v", protoClientConn.RemoteAddr(), err)
 		return
 	}
+	defer protoServerConn.Close()
 
 	rawMsg, err := protoServerConn.ReadMessage()
 	if err != nil {
@@ -35,6 +45,10 @@ func (h *LoginHandler) Handle(protoClientConn protocol.Connection) {
 		return
 	}
 
+	rec := h.Recorder.Start("login-" + protoClientConn.RemoteAddr().String())
+	rec.Record(capture.ServerToClient, rawMsg)
+	rec.Close()
+
 	packetReader := protocol.NewPacketReader(rawMsg)
 	loginResultMessage, err := packets.ParseLoginResultMessage(packetReader)
 	if err != nil {
@@ -44,7 +58,7 @@ func (h *LoginHandler) Handle(protoClientConn protocol.Connection) {
 
 	if !loginResultMessage.ClientDisconnected {
 		injectMotd(loginResultMessage, h.ProxyMOTD)
-		injectProxyGameworldIP(loginResultMessage)
+		h.injectProxyGameworld(loginResultMessage, credentials.AccountNumber)
 	}
 
 	err = protoClientConn.SendPacket(loginResultMessage)
@@ -63,17 +77,20 @@ func injectMotd(message *packets.LoginResultMessage, motd string) {
 	}
 }
 
-func injectProxyGameworldIP(message *packets.LoginResultMessage) {
+func (h *LoginHandler) injectProxyGameworld(message *packets.LoginResultMessage, account uint32) {
+	if message.CharacterList == nil {
+		return
+	}
 	for _, c := range message.CharacterList.Characters {
-
-		// TODO: Extract configuration - do not hardcode
-		ip, err := protocol.StringToIP("192.168.1.142")
-		if err != nil {
-			panic("StringToIP failed")
+		if h.Routes != nil {
+			world := net.JoinHostPort(protocol.IPToString(c.WorldIp), strconv.Itoa(int(c.WorldPort)))
+			h.Routes.Remember(account, c.Name, world)
 		}
 
-		c.WorldIp = ip
-		c.WorldPort = 7172
-		c.WorldName = "Proxy"
+		c.WorldIp = h.GameworldIP
+		c.WorldPort = h.GameworldPort
+		if h.WorldName != "" {
+			c.WorldName = h.WorldName
+		}
 	}
 }
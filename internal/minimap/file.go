@@ -0,0 +1,190 @@
+package minimap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"z07/internal/game/domain"
+)
+
+// Files are gzip compressed: the magic, a version byte, the tile count and then one record per tile
+// sorted by floor, row and column, so neighbouring records compress well.
+const (
+	fileMagic   = "Z07M"
+	fileVersion = 1
+	FileExt     = ".z07map"
+)
+
+const flagWalkable = 1 << 0
+
+// record is the on-disk layout of one tile, little endian.
+type record struct {
+	X, Y  uint16
+	Z     uint8
+	Color uint8
+	Speed uint16
+	Flags uint8
+}
+
+// Write encodes the map in the file format.
+func (m *Map) Write(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+
+	bw.WriteString(fileMagic)
+	bw.WriteByte(fileVersion)
+	binary.Write(bw, binary.LittleEndian, uint32(len(m.tiles)))
+	for _, pos := range m.positions() {
+		t := m.tiles[pos]
+		r := record{X: pos.X, Y: pos.Y, Z: pos.Z, Color: t.Color, Speed: t.Speed}
+		if t.Walkable {
+			r.Flags |= flagWalkable
+		}
+		if err := binary.Write(bw, binary.LittleEndian, r); err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Read decodes a map written by Write.
+func Read(r io.Reader) (*Map, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a minimap file: %w", err)
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+
+	header := make([]byte, len(fileMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if string(header[:len(fileMagic)]) != fileMagic {
+		return nil, errors.New("not a minimap file")
+	}
+	if header[len(fileMagic)] != fileVersion {
+		return nil, fmt.Errorf("unsupported minimap version %d", header[len(fileMagic)])
+	}
+
+	var count uint32
+	if err := binary.Read(br, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("reading tile count: %w", err)
+	}
+	m := New()
+	for i := range count {
+		var rec record
+		if err := binary.Read(br, binary.LittleEndian, &rec); err != nil {
+			return nil, fmt.Errorf("reading tile %d of %d: %w", i, count, err)
+		}
+		m.tiles[domain.Position{X: rec.X, Y: rec.Y, Z: rec.Z}] = Tile{
+			Color:    rec.Color,
+			Speed:    rec.Speed,
+			Walkable: rec.Flags&flagWalkable != 0,
+		}
+	}
+	return m, nil
+}
+
+// ReadFile loads a map file, a missing file is an empty map.
+func ReadFile(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// WriteFile replaces the file at once, a crash never leaves half a map behind.
+func WriteFile(path string, m *Map) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+
+	if err := m.Write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Store keeps one map file per server in Dir. Sessions on the same server can end at the same time,
+// so saving is serialized.
+type Store struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// Path is the map file of a server address such as "world.example:7172".
+func (s *Store) Path(server string) string {
+	return filepath.Join(s.Dir, sanitize(server)+FileExt)
+}
+
+// Load reads the known map of a server.
+func (s *Store) Load(server string) (*Map, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return ReadFile(s.Path(server))
+}
+
+// Save merges explored into the known map of the server, the explored tiles win. A nil store saves nothing.
+func (s *Store) Save(server string, explored *Map) error {
+	if s == nil || explored.Len() == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.Path(server)
+	known, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+	known.Merge(explored)
+	return WriteFile(path, known)
+}
+
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
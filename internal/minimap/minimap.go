@@ -0,0 +1,101 @@
+package minimap
+
+import (
+	"maps"
+	"slices"
+	"z07/internal/assets"
+	"z07/internal/game/domain"
+)
+
+// Tile is what the minimap remembers of one position.
+type Tile struct {
+	Color    uint8  // Palette index of the topmost item with a minimap colour
+	Speed    uint16 // Ground speed, 0 when the ground has none
+	Walkable bool   // No item on it blocks the way
+}
+
+// Map is the explored map of one server, merged across sessions.
+type Map struct {
+	tiles map[domain.Position]Tile
+}
+
+func New() *Map {
+	return &Map{tiles: make(map[domain.Position]Tile)}
+}
+
+// FromTiles remembers the tiles of a game state, the colours come from the loaded items.
+func FromTiles(tiles map[domain.Position]*domain.Tile) *Map {
+	m := New()
+	for pos, tile := range tiles {
+		if tile == nil || len(tile.Items()) == 0 {
+			continue
+		}
+		m.tiles[pos] = remember(*tile)
+	}
+	return m
+}
+
+func remember(tile domain.Tile) Tile {
+	t := Tile{Walkable: true}
+	if ground, ok := tile.Ground(); ok {
+		t.Speed = assets.Get(ground.ID).Speed
+	}
+	for _, item := range tile.Items() {
+		thing := assets.Get(item.ID)
+		if thing.IsBlocking || thing.IsPathBlock {
+			t.Walkable = false
+		}
+		// The client draws the colour of the topmost item that has one.
+		if thing.MinimapColor != 0 {
+			t.Color = uint8(thing.MinimapColor)
+		}
+	}
+	return t
+}
+
+func (m *Map) Len() int {
+	return len(m.tiles)
+}
+
+func (m *Map) Tile(pos domain.Position) (Tile, bool) {
+	t, ok := m.tiles[pos]
+	return t, ok
+}
+
+func (m *Map) Set(pos domain.Position, t Tile) {
+	m.tiles[pos] = t
+}
+
+// Merge adds the tiles of other, a position known to both takes the tile of other.
+func (m *Map) Merge(other *Map) {
+	maps.Copy(m.tiles, other.tiles)
+}
+
+// Floors lists the floors with at least one known tile, from the highest (0) down.
+func (m *Map) Floors() []uint8 {
+	var seen [256]bool
+	var floors []uint8
+	for pos := range m.tiles {
+		if !seen[pos.Z] {
+			seen[pos.Z] = true
+			floors = append(floors, pos.Z)
+		}
+	}
+	slices.Sort(floors)
+	return floors
+}
+
+// positions returns the known positions by floor, then row, then column.
+func (m *Map) positions() []domain.Position {
+	positions := slices.Collect(maps.Keys(m.tiles))
+	slices.SortFunc(positions, func(a, b domain.Position) int {
+		if a.Z != b.Z {
+			return int(a.Z) - int(b.Z)
+		}
+		if a.Y != b.Y {
+			return int(a.Y) - int(b.Y)
+		}
+		return int(a.X) - int(b.X)
+	})
+	return positions
+}
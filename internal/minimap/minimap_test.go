@@ -0,0 +1,142 @@
+package minimap_test
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"z07/internal/assets"
+	"z07/internal/game/domain"
+	"z07/internal/minimap"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testGrass = 61001 // Green ground
+	testWall  = 61002 // Grey wall
+	testTorch = 61003 // No colour of its own
+)
+
+func init() {
+	assets.Register(
+		assets.ItemType{ID: testGrass, IsGround: true, Speed: 150, MinimapColor: 24},
+		assets.ItemType{ID: testWall, IsBlocking: true, MinimapColor: 86},
+		assets.ItemType{ID: testTorch},
+	)
+}
+
+func tile(ids ...uint16) *domain.Tile {
+	t := &domain.Tile{}
+	for _, id := range ids {
+		thing := assets.Get(id)
+		t.Things = append(t.Things, domain.ItemThing(domain.Item{ID: id}, domain.ItemPriority(thing.IsGround, thing.TopOrder)))
+	}
+	return t
+}
+
+func TestFromTiles(t *testing.T) {
+	grass := domain.Position{X: 100, Y: 100, Z: 7}
+	wall := domain.Position{X: 101, Y: 100, Z: 7}
+	empty := domain.Position{X: 102, Y: 100, Z: 7}
+
+	m := minimap.FromTiles(map[domain.Position]*domain.Tile{
+		grass: tile(testGrass, testTorch),
+		wall:  tile(testGrass, testWall, testTorch),
+		empty: tile(),
+	})
+	require.Equal(t, 2, m.Len(), "Tiles without items are not explored")
+
+	got, ok := m.Tile(grass)
+	require.True(t, ok)
+	require.Equal(t, minimap.Tile{Color: 24, Speed: 150, Walkable: true}, got, "Items without a colour keep the one below")
+
+	got, _ = m.Tile(wall)
+	require.Equal(t, minimap.Tile{Color: 86, Speed: 150, Walkable: false}, got, "Topmost colour wins")
+}
+
+func TestMap_WriteRead(t *testing.T) {
+	m := minimap.New()
+	m.Set(domain.Position{X: 32000, Y: 31000, Z: 7}, minimap.Tile{Color: 24, Speed: 150, Walkable: true})
+	m.Set(domain.Position{X: 32001, Y: 31000, Z: 7}, minimap.Tile{Color: 86})
+	m.Set(domain.Position{X: 32000, Y: 31000, Z: 8}, minimap.Tile{Color: 114, Speed: 200, Walkable: true})
+
+	var buf bytes.Buffer
+	require.NoError(t, m.Write(&buf))
+
+	read, err := minimap.Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, m, read)
+
+	_, err = minimap.Read(bytes.NewReader([]byte("not a map")))
+	require.Error(t, err)
+}
+
+func TestStore_Save(t *testing.T) {
+	store := minimap.NewStore(t.TempDir())
+	const server = "world.example:7172"
+	a := domain.Position{X: 100, Y: 100, Z: 7}
+	b := domain.Position{X: 101, Y: 100, Z: 7}
+
+	first := minimap.New()
+	first.Set(a, minimap.Tile{Color: 24, Walkable: true})
+	first.Set(b, minimap.Tile{Color: 24, Walkable: true})
+	require.NoError(t, store.Save(server, first))
+	require.Equal(t, filepath.Join(store.Dir, "world.example_7172.z07map"), store.Path(server))
+
+	// A door was closed since, the later session wins where both saw the tile.
+	second := minimap.New()
+	second.Set(b, minimap.Tile{Color: 86})
+	require.NoError(t, store.Save(server, second))
+
+	known, err := store.Load(server)
+	require.NoError(t, err)
+	require.Equal(t, 2, known.Len())
+	got, _ := known.Tile(a)
+	require.Equal(t, minimap.Tile{Color: 24, Walkable: true}, got)
+	got, _ = known.Tile(b)
+	require.Equal(t, minimap.Tile{Color: 86}, got)
+
+	other, err := store.Load("other.example:7172")
+	require.NoError(t, err)
+	require.Zero(t, other.Len(), "Every server has a map of its own")
+
+	var none *minimap.Store
+	require.NoError(t, none.Save(server, first))
+}
+
+func TestColor(t *testing.T) {
+	require.Equal(t, color.RGBA{A: 0xFF}, minimap.Color(0))
+	require.Equal(t, color.RGBA{R: 0, G: 204, B: 0, A: 0xFF}, minimap.Color(24))
+	require.Equal(t, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, minimap.Color(215))
+	require.Equal(t, color.RGBA{A: 0xFF}, minimap.Color(216))
+}
+
+func TestMap_ExportPNG(t *testing.T) {
+	m := minimap.New()
+	m.Set(domain.Position{X: 100, Y: 200, Z: 7}, minimap.Tile{Color: 24})
+	m.Set(domain.Position{X: 102, Y: 201, Z: 7}, minimap.Tile{Color: 86})
+	m.Set(domain.Position{X: 50, Y: 50, Z: 8}, minimap.Tile{Color: 215})
+
+	dir := t.TempDir()
+	floors, err := m.ExportPNG(dir)
+	require.NoError(t, err)
+	require.Len(t, floors, 2)
+	require.Equal(t, domain.Position{X: 100, Y: 200, Z: 7}, floors[0].Origin)
+
+	f, err := os.Open(filepath.Join(dir, "floor-07.png"))
+	require.NoError(t, err)
+	defer f.Close()
+	img, err := png.Decode(f)
+	require.NoError(t, err)
+
+	require.Equal(t, 3, img.Bounds().Dx())
+	require.Equal(t, 2, img.Bounds().Dy())
+	require.Equal(t, minimap.Color(24), color.RGBAModel.Convert(img.At(0, 0)))
+	require.Equal(t, minimap.Color(86), color.RGBAModel.Convert(img.At(2, 1)))
+	require.Equal(t, color.RGBA{}, color.RGBAModel.Convert(img.At(1, 0)), "Unexplored tiles are transparent")
+
+	require.FileExists(t, filepath.Join(dir, "floor-08.png"))
+}
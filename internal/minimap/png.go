@@ -0,0 +1,97 @@
+package minimap
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"z07/internal/game/domain"
+)
+
+// Color is the RGB value of a minimap palette index. The palette is a 6x6x6 colour cube, indices past
+// it have no colour of their own and are drawn black like the client does.
+func Color(index uint8) color.RGBA {
+	if index >= 216 {
+		return color.RGBA{A: 0xFF}
+	}
+	return color.RGBA{
+		R: index / 36 * 51,
+		G: index / 6 % 6 * 51,
+		B: index % 6 * 51,
+		A: 0xFF,
+	}
+}
+
+// Floor is one floor drawn as an image, one pixel per tile. Unexplored tiles are transparent.
+type Floor struct {
+	Z      uint8
+	Origin domain.Position // The tile of the top left pixel
+	Image  *image.RGBA
+}
+
+// Render draws a floor, false when nothing of it is known.
+func (m *Map) Render(z uint8) (Floor, bool) {
+	var bounds image.Rectangle
+	found := false
+	for pos := range m.tiles {
+		if pos.Z != z {
+			continue
+		}
+		tile := image.Rect(int(pos.X), int(pos.Y), int(pos.X)+1, int(pos.Y)+1)
+		if !found {
+			bounds, found = tile, true
+		} else {
+			bounds = bounds.Union(tile)
+		}
+	}
+	if !found {
+		return Floor{}, false
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for pos, t := range m.tiles {
+		if pos.Z == z {
+			img.SetRGBA(int(pos.X)-bounds.Min.X, int(pos.Y)-bounds.Min.Y, Color(t.Color))
+		}
+	}
+	return Floor{
+		Z:      z,
+		Origin: domain.Position{X: uint16(bounds.Min.X), Y: uint16(bounds.Min.Y), Z: z},
+		Image:  img,
+	}, true
+}
+
+// ExportPNG writes floor-NN.png into dir for every known floor.
+func (m *Map) ExportPNG(dir string) ([]Floor, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var floors []Floor
+	for _, z := range m.Floors() {
+		floor, _ := m.Render(z)
+		if err := writePNG(filepath.Join(dir, FloorFile(z)), floor.Image); err != nil {
+			return floors, err
+		}
+		floors = append(floors, floor)
+	}
+	return floors, nil
+}
+
+// FloorFile is the name ExportPNG gives the image of a floor.
+func FloorFile(z uint8) string {
+	return fmt.Sprintf("floor-%02d.png", z)
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
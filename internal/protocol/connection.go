@@ -79,9 +79,9 @@ func (c *connection) ReadMessage() ([]byte, error) {
 	return payload, nil
 }
 
+// WriteMessage is not safe for concurrent use, an interleaved write corrupts the XTEA stream.
+// Connections with more than one writer go through an Outbox.
 func (c *connection) WriteMessage(payload []byte) error {
-	// TODO: This need mutex
-
 	var dataToSend []byte
 	var err error
 
@@ -13,25 +13,34 @@ type RSAKeySet struct {
 
 var RSA RSAKeySet
 
-// TODO: The following should be loaded from config
+// Built-in keys, used unless the configuration provides others.
+// OT keys are the ones patched into the client, the target server key is what the real client ships with.
 const (
 	OTPublicRSA     = "109120132967399429278860960508995541528237502902798129123468757937266291492576446330739696001110603907230888610072655818825358503429057592827629436413108566029093628212635953836686562675849720620786279431090218017681061521755056710823876476444260558147179707119674283982419152118103759076030616683978566631413"
 	OTPrivateRSA    = "46730330223584118622160180015036832148732986808519344675210555262940258739805766860224610646919605860206328024326703361630109888417839241959507572247284807035235569619173792292786907845791904955103601652822519121908367187885509270025388641700821735345222087940578381210879116823013776808975766851829020659073"
-	targetServerRSA = "138358917549655551601135922545920258651079249320630202917602000570926337770168654400102862016157293631277888588897291561865439132767832236947553872456033140205555218536070792283327632773558457562430692973109061064849319454982125688743198270276394129121891795353179249782548271479625552587457164097090236827371"
+	TargetServerRSA = "138358917549655551601135922545920258651079249320630202917602000570926337770168654400102862016157293631277888588897291561865439132767832236947553872456033140205555218536070792283327632773558457562430692973109061064849319454982125688743198270276394129121891795353179249782548271479625552587457164097090236827371"
 )
 
 func init() {
-	privateKey, err := buildPrivateKeyFromComponents(OTPublicRSA, OTPrivateRSA)
+	keys, err := NewKeySet(OTPublicRSA, OTPrivateRSA, TargetServerRSA)
 	if err != nil {
-		panic(fmt.Sprintf("FATAL: Could not build RSA private key: %v", err))
+		panic(fmt.Sprintf("FATAL: Could not build RSA keys: %v", err))
+	}
+	RSA = keys
+}
+
+// NewKeySet builds the proxy private key and the target server public key from decimal numbers.
+func NewKeySet(proxyModulus, proxyPrivateExponent, serverModulus string) (RSAKeySet, error) {
+	privateKey, err := buildPrivateKeyFromComponents(proxyModulus, proxyPrivateExponent)
+	if err != nil {
+		return RSAKeySet{}, fmt.Errorf("proxy private key: %w", err)
 	}
-	RSA.ClientPrivateKey = privateKey
 
-	publicKey, err := buildPublicKeyFromComponents(targetServerRSA)
+	publicKey, err := buildPublicKeyFromComponents(serverModulus)
 	if err != nil {
-		panic(fmt.Sprintf("FATAL: Could not build RSA public key: %v", err))
+		return RSAKeySet{}, fmt.Errorf("server public key: %w", err)
 	}
-	RSA.GameServerPublicKey = publicKey
+	return RSAKeySet{ClientPrivateKey: privateKey, GameServerPublicKey: publicKey}, nil
 }
 
 func buildPublicKeyFromComponents(nStr string) (*rsa.PublicKey, error) {
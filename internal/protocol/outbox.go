@@ -0,0 +1,212 @@
+package protocol
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Priority orders queued messages, higher ones are written first. Messages of the same priority keep their order.
+type Priority uint8
+
+const (
+	PriorityLow    Priority = iota // Cosmetic, e.g. light updates
+	PriorityNormal                 // Forwarded traffic and regular bot actions
+	PriorityHigh                   // Anything that keeps the character alive
+	priorityCount
+)
+
+const defaultOutboxCapacity = 256
+
+var ErrOutboxClosed = errors.New("outbox closed")
+
+// Injector accepts packets that did not come from the other end of the proxy.
+type Injector interface {
+	Inject(packet Encodable, prio Priority) error
+}
+
+type OutboxOptions struct {
+	Capacity int // Queued messages before writers block, 0 uses the default
+	// MinInjectInterval spaces out injected packets, 0 disables the limit. Forwarded traffic is never delayed,
+	// neither are high priority packets, the ones injected after them wait for the slot they took.
+	MinInjectInterval time.Duration
+}
+
+// Outbox serializes every write to a connection through a single goroutine, so the forwarding loop and the
+// bot modules can never interleave frames. Reads and everything else pass through to the wrapped connection.
+type Outbox struct {
+	Connection
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	queues   [priorityCount][][]byte
+	size     int
+	capacity int
+	closed   bool
+	err      error // First write failure, returned to every writer afterwards
+
+	injectMu   sync.Mutex
+	interval   time.Duration
+	nextInject time.Time
+
+	done chan struct{}
+}
+
+func NewOutbox(conn Connection, opts OutboxOptions) *Outbox {
+	o := &Outbox{
+		Connection: conn,
+		capacity:   opts.Capacity,
+		interval:   opts.MinInjectInterval,
+		done:       make(chan struct{}),
+	}
+	if o.capacity <= 0 {
+		o.capacity = defaultOutboxCapacity
+	}
+	o.notEmpty = sync.NewCond(&o.mu)
+	o.notFull = sync.NewCond(&o.mu)
+
+	go o.run()
+	return o
+}
+
+// WriteMessage queues a payload at normal priority. It blocks while the queue is full.
+// The payload must not be modified afterwards. A failed write is reported by the following calls.
+func (o *Outbox) WriteMessage(payload []byte) error {
+	return o.enqueue(payload, PriorityNormal)
+}
+
+func (o *Outbox) SendPacket(packet Encodable) error {
+	return o.send(packet, PriorityNormal)
+}
+
+// Inject queues a packet created by the proxy itself, waiting for the rate limit first.
+func (o *Outbox) Inject(packet Encodable, prio Priority) error {
+	o.waitInjectSlot(prio)
+	return o.send(packet, prio)
+}
+
+func (o *Outbox) send(packet Encodable, prio Priority) error {
+	pw := NewPacketWriter()
+	packet.Encode(pw)
+	payload, err := pw.GetBytes()
+	if err != nil {
+		return err
+	}
+	return o.enqueue(payload, prio)
+}
+
+// waitInjectSlot hands out the injection slots first come, first served. A high priority packet does not
+// queue behind the others, it takes the slot after the last one handed out and goes right away.
+func (o *Outbox) waitInjectSlot(prio Priority) {
+	if o.interval <= 0 {
+		return
+	}
+	o.injectMu.Lock()
+	now := time.Now()
+	wait := max(o.nextInject.Sub(now), 0)
+	o.nextInject = now.Add(wait + o.interval)
+	if prio >= PriorityHigh {
+		wait = 0
+	}
+	o.injectMu.Unlock()
+
+	if wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-o.done:
+		}
+	}
+}
+
+func (o *Outbox) enqueue(payload []byte, prio Priority) error {
+	if prio >= priorityCount {
+		prio = PriorityHigh
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for o.size >= o.capacity && !o.closed {
+		o.notFull.Wait()
+	}
+	if o.err != nil {
+		return o.err
+	}
+	if o.closed {
+		return ErrOutboxClosed
+	}
+
+	o.queues[prio] = append(o.queues[prio], payload)
+	o.size++
+	o.notEmpty.Signal()
+	return nil
+}
+
+// next waits for the highest priority message, it returns false once the outbox is closed.
+func (o *Outbox) next() ([]byte, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for o.size == 0 && !o.closed {
+		o.notEmpty.Wait()
+	}
+	if o.closed {
+		return nil, false
+	}
+
+	for prio := priorityCount - 1; ; prio-- {
+		if q := o.queues[prio]; len(q) > 0 {
+			payload := q[0]
+			q[0] = nil
+			o.queues[prio] = q[1:]
+			o.size--
+			o.notFull.Signal()
+			return payload, true
+		}
+	}
+}
+
+func (o *Outbox) run() {
+	defer close(o.done)
+	for {
+		payload, ok := o.next()
+		if !ok {
+			return
+		}
+		if err := o.Connection.WriteMessage(payload); err != nil {
+			o.shutdown(err)
+			return
+		}
+	}
+}
+
+func (o *Outbox) shutdown(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return // A write failing because of Close is not an error of its own
+	}
+	o.err = err
+	o.closed = true
+	o.queues = [priorityCount][][]byte{}
+	o.size = 0
+	o.notEmpty.Broadcast()
+	o.notFull.Broadcast()
+}
+
+// Err is the write failure that stopped the outbox, if any.
+func (o *Outbox) Err() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
+
+// Close drops whatever is still queued and closes the connection.
+func (o *Outbox) Close() error {
+	o.shutdown(nil)
+	err := o.Connection.Close() // Unblocks a write stuck on the socket
+	<-o.done
+	return err
+}
@@ -0,0 +1,225 @@
+package protocol_test
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+	"z07/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+// gatedConn records written payloads. While the gate is closed every write blocks, which lets a test fill the queue.
+type gatedConn struct {
+	mu        sync.Mutex
+	written   [][]byte
+	writeErr  error
+	gate      chan struct{}
+	writing   chan struct{} // Signalled when a write starts waiting on the gate
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newGatedConn() *gatedConn {
+	return &gatedConn{
+		gate:    make(chan struct{}),
+		writing: make(chan struct{}, 16),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (c *gatedConn) open() { close(c.gate) }
+
+func (c *gatedConn) WriteMessage(payload []byte) error {
+	select {
+	case c.writing <- struct{}{}:
+	default:
+	}
+	select {
+	case <-c.gate:
+	case <-c.closed:
+		return net.ErrClosed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.writeErr != nil {
+		return c.writeErr
+	}
+	c.written = append(c.written, payload)
+	return nil
+}
+
+func (c *gatedConn) Written() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]string, len(c.written))
+	for i, p := range c.written {
+		out[i] = string(p)
+	}
+	return out
+}
+
+func (c *gatedConn) ReadMessage() ([]byte, error)        { return nil, errors.New("not implemented") }
+func (c *gatedConn) SendPacket(protocol.Encodable) error { return errors.New("not implemented") }
+func (c *gatedConn) RemoteAddr() net.Addr                { return &net.TCPAddr{} }
+func (c *gatedConn) EnableXTEA([4]uint32)                {}
+func (c *gatedConn) Close() error                        { c.closeOnce.Do(func() { close(c.closed) }); return nil }
+
+type rawPacket string
+
+func (p rawPacket) Encode(w *protocol.PacketWriter) {
+	w.WriteBytes([]byte(p))
+}
+
+func TestOutbox_Order(t *testing.T) {
+	conn := newGatedConn()
+	out := protocol.NewOutbox(conn, protocol.OutboxOptions{})
+	defer out.Close()
+
+	// The first message is taken by the writer and stuck on the gate, the rest queues up behind it.
+	require.NoError(t, out.WriteMessage([]byte("first")))
+	<-conn.writing
+	require.NoError(t, out.Inject(rawPacket("light"), protocol.PriorityLow))
+	require.NoError(t, out.WriteMessage([]byte("walk1")))
+	require.NoError(t, out.Inject(rawPacket("heal"), protocol.PriorityHigh))
+	require.NoError(t, out.SendPacket(rawPacket("walk2")))
+	conn.open()
+
+	require.Eventually(t, func() bool { return len(conn.Written()) == 5 }, time.Second, time.Millisecond)
+	require.Equal(t, []string{"first", "heal", "walk1", "walk2", "light"}, conn.Written())
+}
+
+func TestOutbox_Backpressure(t *testing.T) {
+	conn := newGatedConn()
+	out := protocol.NewOutbox(conn, protocol.OutboxOptions{Capacity: 2})
+	defer out.Close()
+
+	require.NoError(t, out.WriteMessage([]byte("1")))
+	<-conn.writing
+	require.NoError(t, out.WriteMessage([]byte("2")))
+	require.NoError(t, out.WriteMessage([]byte("3")))
+
+	queued := make(chan error)
+	go func() { queued <- out.WriteMessage([]byte("4")) }()
+	select {
+	case <-queued:
+		t.Fatal("write did not block on a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	conn.open()
+	require.NoError(t, <-queued)
+	require.Eventually(t, func() bool { return len(conn.Written()) == 4 }, time.Second, time.Millisecond)
+	require.Equal(t, []string{"1", "2", "3", "4"}, conn.Written())
+}
+
+func TestOutbox_InjectRateLimit(t *testing.T) {
+	conn := newGatedConn()
+	conn.open()
+	out := protocol.NewOutbox(conn, protocol.OutboxOptions{MinInjectInterval: 20 * time.Millisecond})
+	defer out.Close()
+
+	start := time.Now()
+	for range 3 {
+		require.NoError(t, out.Inject(rawPacket("x"), protocol.PriorityNormal))
+	}
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	// Forwarded traffic is never throttled.
+	start = time.Now()
+	require.NoError(t, out.WriteMessage([]byte("y")))
+	require.Less(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestOutbox_InjectRateLimitPriority(t *testing.T) {
+	conn := newGatedConn()
+	conn.open()
+	out := protocol.NewOutbox(conn, protocol.OutboxOptions{MinInjectInterval: 50 * time.Millisecond})
+	defer out.Close()
+
+	// Two light updates take the next slots, the second one waits for its turn.
+	require.NoError(t, out.Inject(rawPacket("light1"), protocol.PriorityLow))
+	light := make(chan error)
+	go func() { light <- out.Inject(rawPacket("light2"), protocol.PriorityLow) }()
+	require.Eventually(t, func() bool { return len(conn.Written()) == 1 }, time.Second, time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, out.Inject(rawPacket("heal"), protocol.PriorityHigh))
+	require.Less(t, time.Since(start), 25*time.Millisecond, "A heal does not wait behind the lights")
+	require.NoError(t, <-light)
+
+	// Injections after the heal wait for the slot it took.
+	start = time.Now()
+	require.NoError(t, out.Inject(rawPacket("light3"), protocol.PriorityLow))
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	require.Eventually(t, func() bool { return len(conn.Written()) == 4 }, time.Second, time.Millisecond)
+	require.Equal(t, []string{"light1", "heal", "light2", "light3"}, conn.Written())
+}
+
+func TestOutbox_WriteError(t *testing.T) {
+	conn := newGatedConn()
+	conn.writeErr = errors.New("broken pipe")
+	conn.open()
+	out := protocol.NewOutbox(conn, protocol.OutboxOptions{})
+	defer out.Close()
+
+	require.NoError(t, out.WriteMessage([]byte("lost")))
+	require.Eventually(t, func() bool { return out.Err() != nil }, time.Second, time.Millisecond)
+
+	require.ErrorContains(t, out.WriteMessage([]byte("next")), "broken pipe")
+	require.ErrorContains(t, out.Inject(rawPacket("x"), protocol.PriorityHigh), "broken pipe")
+}
+
+func TestOutbox_Close(t *testing.T) {
+	conn := newGatedConn()
+	out := protocol.NewOutbox(conn, protocol.OutboxOptions{Capacity: 1})
+
+	// A writer stuck on the socket and another one waiting for room in the queue.
+	require.NoError(t, out.WriteMessage([]byte("stuck")))
+	<-conn.writing
+	require.NoError(t, out.WriteMessage([]byte("queued")))
+	blocked := make(chan error)
+	go func() { blocked <- out.WriteMessage([]byte("waiting")) }()
+
+	require.NoError(t, out.Close())
+	require.ErrorIs(t, <-blocked, protocol.ErrOutboxClosed)
+	require.ErrorIs(t, out.SendPacket(rawPacket("late")), protocol.ErrOutboxClosed)
+	require.Empty(t, conn.Written())
+}
+
+func TestOutbox_ConcurrentWriters(t *testing.T) {
+	conn := newGatedConn()
+	conn.open()
+	out := protocol.NewOutbox(conn, protocol.OutboxOptions{Capacity: 4})
+	defer out.Close()
+
+	const writers, perWriter = 8, 50
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWriter {
+				if w%2 == 0 {
+					require.NoError(t, out.WriteMessage([]byte{byte(w)}))
+				} else {
+					require.NoError(t, out.Inject(rawPacket([]byte{byte(w)}), protocol.PriorityHigh))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Eventually(t, func() bool { return len(conn.Written()) == writers*perWriter }, time.Second, time.Millisecond)
+	counts := map[string]int{}
+	for _, p := range conn.Written() {
+		counts[p]++
+	}
+	for w := range writers {
+		require.Equal(t, perWriter, counts[string([]byte{byte(w)})])
+	}
+}
@@ -215,3 +215,8 @@ func (pr *PacketReader) PeekBytes(n int) ([]byte, error) {
 func (pr *PacketReader) Remaining() int {
 	return pr.reader.Len()
 }
+
+// Offset is the number of bytes consumed so far.
+func (pr *PacketReader) Offset() int {
+	return int(pr.reader.Size()) - pr.reader.Len()
+}
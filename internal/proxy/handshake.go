@@ -18,6 +18,16 @@ func InitSession[T XTEAPacket](
 	targetAddr string,
 	parser func(*protocol.PacketReader) (T, error),
 ) (T, protocol.Connection, error) {
+	return InitRoutedSession(logPrefix, client, func(T) string { return targetAddr }, parser)
+}
+
+// InitRoutedSession is InitSession with the backend picked from the parsed initial packet.
+func InitRoutedSession[T XTEAPacket](
+	logPrefix string,
+	client protocol.Connection,
+	route func(T) string,
+	parser func(*protocol.PacketReader) (T, error),
+) (T, protocol.Connection, error) {
 
 	var empty T // Zero value for error returns
 
@@ -35,6 +45,7 @@ func InitSession[T XTEAPacket](
 	}
 
 	// 3. Connect to Backend
+	targetAddr := route(packet)
 	server, err := ConnectToBackend(targetAddr)
 	if err != nil {
 		return empty, nil, fmt.Errorf("connect backend: %w", err)
@@ -46,7 +57,7 @@ func InitSession[T XTEAPacket](
 		return empty, nil, fmt.Errorf("forward packet: %w", err)
 	}
 
-	log.Printf("[%s] Session established, forwarding to backend.", logPrefix)
+	log.Printf("[%s] Session established, forwarding to %s.", logPrefix, targetAddr)
 
 	// 5. Enable Encryption
 	key := packet.GetXTEAKey()
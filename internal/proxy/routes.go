@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"strings"
+	"sync"
+)
+
+// WorldRoutes remembers the real game world of every character seen in a character list.
+// The login proxy fills it, the game proxy uses it to pick the backend of each login.
+type WorldRoutes struct {
+	mu     sync.RWMutex
+	routes map[routeKey]string
+}
+
+// routeKey with an empty character is the last world seen for the account,
+// with a zero account the last world seen for the character name.
+type routeKey struct {
+	account   uint32
+	character string
+}
+
+func NewWorldRoutes() *WorldRoutes {
+	return &WorldRoutes{routes: make(map[routeKey]string)}
+}
+
+// Remember records the address of the game world a character lives on.
+func (r *WorldRoutes) Remember(account uint32, character string, addr string) {
+	name := strings.ToLower(character)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routes[routeKey{account, name}] = addr
+	r.routes[routeKey{0, name}] = addr
+	r.routes[routeKey{account, ""}] = addr
+}
+
+// Lookup finds the world of a login, by account and character first, then by character name, then by account.
+func (r *WorldRoutes) Lookup(account uint32, character string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	name := strings.ToLower(character)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range []routeKey{{account, name}, {0, name}, {account, ""}} {
+		if addr, ok := r.routes[key]; ok {
+			return addr, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorldRoutes(t *testing.T) {
+	routes := NewWorldRoutes()
+	routes.Remember(1001, "Knight", "10.0.0.1:7172")
+	routes.Remember(1001, "Druid", "10.0.0.2:7172")
+	routes.Remember(2002, "Knight", "10.0.0.3:7172") // Same name on another server
+
+	t.Run("Account and character", func(t *testing.T) {
+		addr, ok := routes.Lookup(1001, "knight")
+		require.True(t, ok)
+		require.Equal(t, "10.0.0.1:7172", addr)
+	})
+
+	t.Run("Character name alone", func(t *testing.T) {
+		addr, ok := routes.Lookup(0, "Druid")
+		require.True(t, ok)
+		require.Equal(t, "10.0.0.2:7172", addr)
+	})
+
+	t.Run("Unknown character of a known account", func(t *testing.T) {
+		addr, ok := routes.Lookup(2002, "Sorcerer")
+		require.True(t, ok)
+		require.Equal(t, "10.0.0.3:7172", addr)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, ok := routes.Lookup(3003, "Paladin")
+		require.False(t, ok)
+
+		var none *WorldRoutes
+		_, ok = none.Lookup(1001, "Knight")
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,198 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"z07/internal/game/packets"
+	"z07/internal/protocol"
+)
+
+// Engine holds the active rule set. It is shared by every session and can be replaced at any time.
+type Engine struct {
+	set atomic.Pointer[ruleSet]
+
+	mu      sync.Mutex // Serializes Set
+	lastErr error
+}
+
+type ruleSet struct {
+	rules    []*compiled
+	byOpcode [2][256]ruleList // Enabled rules by direction and opcode, in declaration order
+	active   [2]bool
+}
+
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// Set validates and activates a new rule set. An invalid set is rejected as a whole and the old one stays active.
+func (e *Engine) Set(rules []Rule) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	set := &ruleSet{}
+	var errs []error
+	for i, r := range rules {
+		c, err := compile(r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rules[%d] %q: %w", i, r.ID, err))
+			continue
+		}
+		set.rules = append(set.rules, c)
+		if c.Enabled {
+			d, _ := c.Direction.index()
+			set.byOpcode[d][c.Opcode] = append(set.byOpcode[d][c.Opcode], c)
+			set.active[d] = true
+		}
+	}
+
+	e.lastErr = errors.Join(errs...)
+	if e.lastErr != nil {
+		return e.lastErr
+	}
+	e.set.Store(set)
+	return nil
+}
+
+// Err is why the latest Set was rejected, nil when it went through.
+func (e *Engine) Err() error {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastErr
+}
+
+func (e *Engine) Rules() []Status {
+	if e == nil {
+		return nil
+	}
+	set := e.set.Load()
+	if set == nil {
+		return nil
+	}
+	out := make([]Status, 0, len(set.rules))
+	for _, c := range set.rules {
+		out = append(out, c.status())
+	}
+	return out
+}
+
+// Apply runs the rules of a direction over a message. Without any rule for that direction the message is
+// returned as it is, otherwise every packet is parsed to find its boundaries. A packet that cannot be parsed
+// ends the walk and the rest of the message passes unchanged.
+//
+// Map slices and floor changes are read relative to the player position. The state follows it off the
+// forwarding path and may lag behind, so they end the walk as well unless a map description earlier in
+// the same message placed the player.
+func (e *Engine) Apply(dir Direction, data []byte, env Env) []byte {
+	if e == nil {
+		return data
+	}
+	set := e.set.Load()
+	d, ok := dir.index()
+	if set == nil || !ok || !set.active[d] {
+		return data
+	}
+
+	var out []byte // Stays nil until a packet changes
+	visit := func(start, end int, packet any) {
+		replacement, changed := set.byOpcode[d][data[start]].apply(packet, env)
+		if changed && out == nil {
+			out = append(make([]byte, 0, len(data)), data[:start]...)
+		}
+		if out != nil {
+			if changed {
+				out = append(out, replacement...)
+			} else {
+				out = append(out, data[start:end]...)
+			}
+		}
+	}
+
+	var stop int
+	if dir == ServerToClient {
+		placed := false
+		stop, _ = packets.WalkS2C(data, packets.ParsingContext{}, func(start, end int, p packets.S2CPacket) bool {
+			opcode := packets.S2COpcode(data[start])
+			if packets.IsCameraRelative(opcode) && !placed {
+				return false
+			}
+			placed = placed || opcode == packets.S2CMapDescription
+			visit(start, end, p)
+			return true
+		})
+	} else {
+		stop, _ = packets.WalkC2S(data, func(start, end int, p packets.C2SPacket) { visit(start, end, p) })
+	}
+
+	if out == nil {
+		return data
+	}
+	return append(out, data[stop:]...)
+}
+
+type ruleList []*compiled
+
+// apply returns the bytes that take the place of the packet, nil for a drop.
+func (rules ruleList) apply(packet any, env Env) ([]byte, bool) {
+	for _, r := range rules {
+		if r.broken.Load() != nil {
+			continue
+		}
+		matched, err := r.matches(packet, env)
+		if err != nil {
+			r.fail(err)
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		switch r.Action {
+		case ActionDrop:
+			r.hits.Add(1)
+			return nil, true
+		case ActionReplace:
+			r.hits.Add(1)
+			return r.replace, true
+		case ActionModify:
+			payload, err := r.modify(packet)
+			if err != nil {
+				r.fail(err)
+				continue
+			}
+			r.hits.Add(1)
+			return payload, true
+		}
+	}
+	return nil, false
+}
+
+func (r *compiled) matches(packet any, env Env) (bool, error) {
+	for _, cond := range r.When {
+		ok, err := cond.holds(packet, env)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (r *compiled) modify(packet any) ([]byte, error) {
+	encodable, ok := packet.(protocol.Encodable)
+	if !ok {
+		return nil, fmt.Errorf("%T cannot be encoded, only drop and replace work on it", packet)
+	}
+	for name, value := range r.Set {
+		if err := setField(packet, name, value); err != nil {
+			return nil, err
+		}
+	}
+	pw := protocol.NewPacketWriter()
+	encodable.Encode(pw)
+	return pw.GetBytes()
+}
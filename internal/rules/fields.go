@@ -0,0 +1,128 @@
+package rules
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"z07/internal/game/domain"
+)
+
+// Env is what conditions may look up besides the packet itself.
+type Env interface {
+	PlayerPos() domain.Position
+	Creature(creatureId uint32) (domain.Creature, bool)
+}
+
+func field(packet any, path string) (reflect.Value, error) {
+	v := reflect.ValueOf(packet)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("field %q: %s has no fields", path, v.Type())
+		}
+		v = v.FieldByNameFunc(func(n string) bool { return strings.EqualFold(n, name) })
+		if !v.IsValid() {
+			return reflect.Value{}, fmt.Errorf("%T has no field %q", packet, path)
+		}
+	}
+	return v, nil
+}
+
+func (c Condition) holds(packet any, env Env) (bool, error) {
+	v, err := field(packet, c.Field)
+	if err != nil {
+		return false, err
+	}
+
+	if c.Op == OpParty {
+		if !v.CanUint() {
+			return false, fmt.Errorf("party: field %q is not a creature ID", c.Field)
+		}
+		creature, ok := env.Creature(uint32(v.Uint()))
+		return ok && (creature.Shield == domain.ShieldBlue || creature.Shield == domain.ShieldYellow), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		want := fmt.Sprint(c.Value)
+		switch c.Op {
+		case OpEq:
+			return v.String() == want, nil
+		case OpNe:
+			return v.String() != want, nil
+		case OpContains:
+			return strings.Contains(strings.ToLower(v.String()), strings.ToLower(want)), nil
+		}
+	case reflect.Bool:
+		want, ok := c.Value.(bool)
+		if !ok {
+			return false, fmt.Errorf("field %q is a bool, got %v", c.Field, c.Value)
+		}
+		switch c.Op {
+		case OpEq:
+			return v.Bool() == want, nil
+		case OpNe:
+			return v.Bool() != want, nil
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		want, ok := toFloat(c.Value)
+		if !ok {
+			return false, fmt.Errorf("field %q is a number, got %v", c.Field, c.Value)
+		}
+		var got float64
+		if v.CanInt() {
+			got = float64(v.Int())
+		} else {
+			got = float64(v.Uint())
+		}
+		switch c.Op {
+		case OpEq:
+			return got == want, nil
+		case OpNe:
+			return got != want, nil
+		case OpLt:
+			return got < want, nil
+		case OpGt:
+			return got > want, nil
+		}
+	}
+	return false, fmt.Errorf("%s does not apply to field %q of type %s", c.Op, c.Field, v.Type())
+}
+
+func setField(packet any, path string, value any) error {
+	v, err := field(packet, path)
+	if err != nil {
+		return err
+	}
+	if !v.CanSet() {
+		return fmt.Errorf("field %q cannot be set", path)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(fmt.Sprint(value))
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("field %q is a bool, got %v", path, value)
+		}
+		v.SetBool(b)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := toFloat(value)
+		if !ok || n < 0 || v.OverflowUint(uint64(n)) {
+			return fmt.Errorf("field %q does not fit %v", path, value)
+		}
+		v.SetUint(uint64(n))
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		n, ok := toFloat(value)
+		if !ok || v.OverflowInt(int64(n)) {
+			return fmt.Errorf("field %q does not fit %v", path, value)
+		}
+		v.SetInt(int64(n))
+	default:
+		return fmt.Errorf("field %q of type %s cannot be set", path, v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package rules drops, rewrites or replaces single packets on their way through the proxy.
+//
+// A rule matches one opcode in one direction, optionally narrowed by predicates on the fields of the parsed packet.
+// The first enabled rule that matches a packet decides what happens to it, packets without a match pass unchanged.
+package rules
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+type Direction string
+
+const (
+	ServerToClient Direction = "s2c"
+	ClientToServer Direction = "c2s"
+)
+
+func (d Direction) index() (int, bool) {
+	switch d {
+	case ServerToClient:
+		return 0, true
+	case ClientToServer:
+		return 1, true
+	default:
+		return 0, false
+	}
+}
+
+const (
+	ActionDrop    = "drop"
+	ActionModify  = "modify"  // Sets fields and encodes the packet again, only for packets with an encoder
+	ActionReplace = "replace" // Sends the given bytes instead, opcode included
+)
+
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpLt       = "lt"
+	OpGt       = "gt"
+	OpContains = "contains" // Case insensitive substring of a text field
+	OpParty    = "party"    // The field is a creature ID that shows a party member or leader shield
+)
+
+type Rule struct {
+	ID        string         `json:"id" yaml:"id"`
+	Enabled   bool           `json:"enabled" yaml:"enabled"`
+	Direction Direction      `json:"direction" yaml:"direction"`
+	Opcode    uint8          `json:"opcode" yaml:"opcode"`
+	When      []Condition    `json:"when,omitempty" yaml:"when"` // All of them have to hold
+	Action    string         `json:"action" yaml:"action"`
+	Set       map[string]any `json:"set,omitempty" yaml:"set"`         // Field values for modify
+	Replace   string         `json:"replace,omitempty" yaml:"replace"` // Hex payload for replace
+}
+
+// Condition compares a field of the parsed packet. Fields use the Go names of the packet struct,
+// matched case insensitively and nested with dots, e.g. "Pos.Z".
+type Condition struct {
+	Field string `json:"field" yaml:"field"`
+	Op    string `json:"op" yaml:"op"`
+	Value any    `json:"value,omitempty" yaml:"value"`
+}
+
+// Status is a rule as the dashboard shows it.
+type Status struct {
+	Rule
+	Hits  uint64 `json:"hits"`
+	Error string `json:"error,omitempty"` // Why the rule could not be applied to a matching packet
+}
+
+type compiled struct {
+	Rule
+	replace []byte
+
+	hits   atomic.Uint64
+	broken atomic.Pointer[string]
+}
+
+func compile(r Rule) (*compiled, error) {
+	c := &compiled{Rule: r}
+
+	if _, ok := r.Direction.index(); !ok {
+		return nil, fmt.Errorf("direction %q is neither %s nor %s", r.Direction, ServerToClient, ClientToServer)
+	}
+	for i, cond := range r.When {
+		if err := cond.validate(); err != nil {
+			return nil, fmt.Errorf("when[%d]: %w", i, err)
+		}
+	}
+
+	switch r.Action {
+	case ActionDrop:
+	case ActionModify:
+		if len(r.Set) == 0 {
+			return nil, errors.New("modify needs at least one field to set")
+		}
+	case ActionReplace:
+		payload, err := hex.DecodeString(strings.ReplaceAll(r.Replace, " ", ""))
+		if err != nil {
+			return nil, fmt.Errorf("replace: %w", err)
+		}
+		if len(payload) == 0 {
+			return nil, errors.New("replace needs a payload, use drop to remove the packet")
+		}
+		c.replace = payload
+	default:
+		return nil, fmt.Errorf("unknown action %q", r.Action)
+	}
+	return c, nil
+}
+
+func (c Condition) validate() error {
+	if c.Field == "" {
+		return errors.New("field must be set")
+	}
+	switch c.Op {
+	case OpEq, OpNe, OpContains:
+		if c.Value == nil {
+			return fmt.Errorf("%s needs a value", c.Op)
+		}
+	case OpLt, OpGt:
+		if _, ok := toFloat(c.Value); !ok {
+			return fmt.Errorf("%s needs a number, got %v", c.Op, c.Value)
+		}
+	case OpParty:
+	default:
+		return fmt.Errorf("unknown op %q", c.Op)
+	}
+	return nil
+}
+
+// fail disables the rule for the packets it cannot handle, the first reason is kept for the dashboard.
+func (c *compiled) fail(err error) {
+	msg := err.Error()
+	c.broken.CompareAndSwap(nil, &msg)
+}
+
+func (c *compiled) status() Status {
+	s := Status{Rule: c.Rule, Hits: c.hits.Load()}
+	if msg := c.broken.Load(); msg != nil {
+		s.Error = *msg
+	}
+	return s
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		// YAML and JSON users both get to write opcodes and IDs in hex.
+		if i, err := strconv.ParseInt(n, 0, 64); err == nil {
+			return float64(i), true
+		}
+	}
+	return 0, false
+}
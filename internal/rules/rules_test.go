@@ -0,0 +1,192 @@
+package rules_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"z07/internal/game/domain"
+	"z07/internal/rules"
+
+	"github.com/stretchr/testify/require"
+)
+
+type env struct {
+	creatures map[uint32]domain.Creature
+}
+
+func (e env) PlayerPos() domain.Position { return domain.Position{X: 100, Y: 100, Z: 7} }
+func (e env) Creature(id uint32) (domain.Creature, bool) {
+	c, ok := e.creatures[id]
+	return c, ok
+}
+
+var (
+	ping          = []byte{0x1E}
+	magicEffect13 = []byte{0x83, 0x64, 0x00, 0x64, 0x00, 0x07, 0x0D}
+	magicEffect2  = []byte{0x83, 0x64, 0x00, 0x64, 0x00, 0x07, 0x02}
+	creatureHP    = []byte{0x8C, 0x01, 0x00, 0x00, 0x10, 0x32}
+	sayHello      = []byte{0x96, 0x01, 0x05, 0x00, 'h', 'e', 'l', 'l', 'o'}
+	attackFriend  = []byte{0xA1, 0x01, 0x00, 0x00, 0x10}
+	attackMonster = []byte{0xA1, 0x02, 0x00, 0x00, 0x40}
+)
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func newEngine(t *testing.T, list ...rules.Rule) *rules.Engine {
+	t.Helper()
+	e := rules.NewEngine()
+	require.NoError(t, e.Set(list))
+	return e
+}
+
+func TestEngine_Apply(t *testing.T) {
+	party := env{creatures: map[uint32]domain.Creature{
+		0x10000001: {ID: 0x10000001, Shield: domain.ShieldBlue},
+		0x40000002: {ID: 0x40000002},
+	}}
+
+	t.Run("No rules", func(t *testing.T) {
+		var e *rules.Engine
+		msg := join(magicEffect13, ping)
+		require.Same(t, &msg[0], &e.Apply(rules.ServerToClient, msg, party)[0])
+		require.Same(t, &msg[0], &rules.NewEngine().Apply(rules.ServerToClient, msg, party)[0])
+	})
+
+	t.Run("Drop a packet in the middle of a message", func(t *testing.T) {
+		e := newEngine(t, rules.Rule{
+			ID: "hide-effect", Enabled: true, Direction: rules.ServerToClient, Opcode: 0x83,
+			When:   []rules.Condition{{Field: "Type", Op: rules.OpEq, Value: 13}},
+			Action: rules.ActionDrop,
+		})
+
+		got := e.Apply(rules.ServerToClient, join(creatureHP, magicEffect13, magicEffect2, ping), party)
+		require.Equal(t, join(creatureHP, magicEffect2, ping), got)
+		require.Equal(t, uint64(1), e.Rules()[0].Hits)
+
+		// Rules of one direction leave the other alone.
+		require.Equal(t, ping, e.Apply(rules.ClientToServer, ping, party))
+	})
+
+	t.Run("Nested field", func(t *testing.T) {
+		e := newEngine(t, rules.Rule{
+			ID: "floor", Enabled: true, Direction: rules.ServerToClient, Opcode: 0x83,
+			When:   []rules.Condition{{Field: "pos.z", Op: rules.OpLt, Value: "0x08"}},
+			Action: rules.ActionDrop,
+		})
+		require.Empty(t, e.Apply(rules.ServerToClient, magicEffect2, party))
+	})
+
+	t.Run("Rewrite say text", func(t *testing.T) {
+		e := newEngine(t, rules.Rule{
+			ID: "polite", Enabled: true, Direction: rules.ClientToServer, Opcode: 0x96,
+			When:   []rules.Condition{{Field: "Text", Op: rules.OpContains, Value: "HELL"}},
+			Action: rules.ActionModify,
+			Set:    map[string]any{"Text": "hi"},
+		})
+		require.Equal(t, []byte{0x96, 0x01, 0x02, 0x00, 'h', 'i'}, e.Apply(rules.ClientToServer, sayHello, party))
+	})
+
+	t.Run("Block attacks on party members", func(t *testing.T) {
+		e := newEngine(t, rules.Rule{
+			ID: "no-friendly-fire", Enabled: true, Direction: rules.ClientToServer, Opcode: 0xA1,
+			When:   []rules.Condition{{Field: "CreatureID", Op: rules.OpParty}},
+			Action: rules.ActionDrop,
+		})
+		require.Empty(t, e.Apply(rules.ClientToServer, attackFriend, party))
+		require.Equal(t, attackMonster, e.Apply(rules.ClientToServer, attackMonster, party))
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		e := newEngine(t, rules.Rule{
+			ID: "pong", Enabled: true, Direction: rules.ServerToClient, Opcode: 0x1E,
+			Action: rules.ActionReplace, Replace: "1E 1E",
+		})
+		require.Equal(t, join(creatureHP, ping, ping), e.Apply(rules.ServerToClient, join(creatureHP, ping), party))
+	})
+
+	t.Run("First matching rule wins, disabled rules are skipped", func(t *testing.T) {
+		e := newEngine(t,
+			rules.Rule{ID: "off", Direction: rules.ServerToClient, Opcode: 0x1E, Action: rules.ActionDrop},
+			rules.Rule{ID: "double", Enabled: true, Direction: rules.ServerToClient, Opcode: 0x1E, Action: rules.ActionReplace, Replace: "1E1E"},
+			rules.Rule{ID: "drop", Enabled: true, Direction: rules.ServerToClient, Opcode: 0x1E, Action: rules.ActionDrop},
+		)
+		require.Equal(t, join(ping, ping), e.Apply(rules.ServerToClient, ping, party))
+	})
+
+	t.Run("Unparsable tail passes unchanged", func(t *testing.T) {
+		e := newEngine(t, rules.Rule{ID: "drop", Enabled: true, Direction: rules.ServerToClient, Opcode: 0x1E, Action: rules.ActionDrop})
+		tail := []byte{0xFE, 0x1E, 0x1E}
+		require.Equal(t, join(creatureHP, tail), e.Apply(rules.ServerToClient, join(creatureHP, ping, tail), party))
+	})
+
+	t.Run("Map slices without a map description pass unchanged", func(t *testing.T) {
+		e := newEngine(t, rules.Rule{ID: "drop", Enabled: true, Direction: rules.ServerToClient, Opcode: 0x1E, Action: rules.ActionDrop})
+		for _, opcode := range []byte{0x65, 0x66, 0x67, 0x68, 0xBE, 0xBF} {
+			slice := []byte{opcode, 0xFF, 0xFF}
+			require.Equal(t, join(slice, ping), e.Apply(rules.ServerToClient, join(ping, slice, ping), party))
+		}
+	})
+
+	t.Run("A rule that cannot apply is reported and skipped", func(t *testing.T) {
+		e := newEngine(t, rules.Rule{
+			ID: "bad", Enabled: true, Direction: rules.ServerToClient, Opcode: 0x83,
+			Action: rules.ActionModify, Set: map[string]any{"Type": 1},
+		})
+		msg := join(magicEffect13, magicEffect2)
+		require.Equal(t, msg, e.Apply(rules.ServerToClient, msg, party))
+		require.Contains(t, e.Rules()[0].Error, "cannot be encoded")
+	})
+}
+
+func TestEngine_Set(t *testing.T) {
+	e := newEngine(t, rules.Rule{ID: "keep", Enabled: true, Direction: rules.ServerToClient, Opcode: 0x1E, Action: rules.ActionDrop})
+
+	err := e.Set([]rules.Rule{
+		{ID: "dir", Direction: "up", Action: rules.ActionDrop},
+		{ID: "op", Direction: rules.ClientToServer, Action: rules.ActionDrop, When: []rules.Condition{{Field: "Text", Op: "like"}}},
+		{ID: "hex", Direction: rules.ClientToServer, Action: rules.ActionReplace, Replace: "zz"},
+		{ID: "set", Direction: rules.ClientToServer, Action: rules.ActionModify},
+	})
+	for _, id := range []string{`"dir"`, `"op"`, `"hex"`, `"set"`} {
+		require.ErrorContains(t, err, id)
+	}
+	require.Equal(t, err, e.Err())
+
+	// The previous set stays active.
+	require.Len(t, e.Rules(), 1)
+	require.Empty(t, e.Apply(rules.ServerToClient, ping, env{}))
+
+	require.NoError(t, e.Set(nil))
+	require.NoError(t, e.Err())
+	require.Equal(t, ping, e.Apply(rules.ServerToClient, ping, env{}))
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+- id: hide-effect
+  enabled: true
+  direction: s2c
+  opcode: 0x83
+  when:
+    - {field: Type, op: eq, value: 13}
+  action: drop
+`), 0o644))
+
+	list, err := rules.LoadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, rules.NewEngine().Set(list))
+	require.Equal(t, uint8(0x83), list[0].Opcode)
+
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+	list, err = rules.LoadFile(path)
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	require.NoError(t, os.WriteFile(path, []byte("- id: x\n  actoin: drop\n"), 0o644))
+	_, err = rules.LoadFile(path)
+	require.ErrorContains(t, err, "actoin")
+}
@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile reads a YAML list of rules.
+func LoadFile(path string) ([]Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []Rule
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil && !errors.Is(err, io.EOF) { // An empty file has no rules
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Watch reloads the rules file into the engine whenever it changes, the base rules come first.
+// A broken file is logged and the rules stay as they were until it is fixed.
+func Watch(ctx context.Context, e *Engine, path string, base []Rule, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var loaded time.Time
+	missing := false
+	for {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			if !missing {
+				log.Printf("[Rules] %v", err)
+			}
+			missing = true
+		case !info.ModTime().Equal(loaded):
+			missing = false
+			loaded = info.ModTime()
+			if err := reload(e, path, base); err != nil {
+				log.Printf("[Rules] Keeping the previous rules, %s: %v", path, err)
+			} else {
+				log.Printf("[Rules] Loaded %s", path)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func reload(e *Engine, path string, base []Rule) error {
+	fromFile, err := LoadFile(path)
+	if err != nil {
+		return err
+	}
+	return e.Set(append(append([]Rule(nil), base...), fromFile...))
+}